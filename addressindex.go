@@ -0,0 +1,385 @@
+package open_scanner
+
+import (
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/cache/redis"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_base/major"
+	"github.com/nbit99/open_base/model"
+	tradeutil "github.com/nbit99/open_scanner/uitl"
+	"github.com/nbit99/openwallet/v2/log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AddressIndexRedis   = "redis"   // 每次查询Redis
+	AddressIndexMemory  = "memory"  // 进程内Map,从OwAddress预加载
+	AddressIndexLayered = "layered" // 先查本地布隆过滤器,命中后再查Redis
+
+	bloomFalsePositive = 0.001
+	indexRefreshSecond = 300
+)
+
+// AddressIndex 扫块地址索引,通过地址/别名查找所属账户ID,不存在时返回空
+type AddressIndex interface {
+	AccountIDByAddress(address, symbol string) (string, error)
+	AccountIDByAlias(alias, symbol string) (string, error)
+}
+
+// ReloadableIndex 支持从数据库重新加载的地址索引
+type ReloadableIndex interface {
+	AddressIndex
+	Reload() error
+}
+
+// 接收tradeutil地址变更通知的本地索引
+type notifiedIndex interface {
+	invalidate(kind, id string)
+}
+
+// NewAddressIndex 按类型创建地址索引,未知类型使用Redis
+func NewAddressIndex(kind, symbol string, repo Repository) (AddressIndex, error) {
+	switch kind {
+	case AddressIndexMemory:
+		index := NewMemoryAddressIndex(symbol, repo, new(RedisAddressIndex))
+		if err := index.Reload(); err != nil {
+			return nil, err
+		}
+		return index, nil
+	case AddressIndexLayered:
+		index := NewLayeredAddressIndex(symbol, repo, new(RedisAddressIndex))
+		if err := index.Reload(); err != nil {
+			return nil, err
+		}
+		return index, nil
+	default:
+		return new(RedisAddressIndex), nil
+	}
+}
+
+// 按币种配置初始化地址索引: addressIndex = redis|memory|layered, addressIndexRefresh = 重新加载间隔(秒)
+// 本地索引通过tradeutil.NotifyAddress接收新导入的地址,lookupCacheSync开启时同步其他进程的通知
+func (o *OpenWScanner) initAddressIndex(c config.Configer) error {
+	if o.AddressIndex == nil {
+		index, err := NewAddressIndex(c.DefaultString("addressIndex", AddressIndexRedis), o.Symbol, o.Repository)
+		if err != nil {
+			return err
+		}
+		o.AddressIndex = index
+		if v, ok := index.(notifiedIndex); ok {
+			tradeutil.OnInvalidate(v.invalidate)
+			if c.DefaultBool("lookupCacheSync", true) {
+				tradeutil.SubscribeInvalidate()
+			}
+		}
+	}
+	if o.indexRefresh == nil {
+		refresh := c.DefaultInt64("addressIndexRefresh", indexRefreshSecond)
+		o.indexRefresh = RefreshAddressIndex(o.AddressIndex, time.Duration(refresh)*time.Second)
+	}
+	return nil
+}
+
+// IndexRefresher 定时重新加载地址索引
+type IndexRefresher struct {
+	index    ReloadableIndex
+	interval time.Duration
+	done     chan struct{}
+	once     sync.Once
+}
+
+// RefreshAddressIndex 定时重新加载地址索引,用于清理已删除的地址及重建布隆过滤器,不支持重新加载时返回nil
+func RefreshAddressIndex(index AddressIndex, interval time.Duration) *IndexRefresher {
+	reloadable, ok := index.(ReloadableIndex)
+	if !ok || interval <= 0 {
+		return nil
+	}
+	r := &IndexRefresher{index: reloadable, interval: interval, done: make(chan struct{})}
+	go func() {
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-time.After(r.interval):
+				reloadIndex(r.index)
+			}
+		}
+	}()
+	return r
+}
+
+func reloadIndex(index ReloadableIndex) {
+	if err := index.Reload(); err != nil {
+		log.Error("重新加载地址索引失败: ", err.Error())
+	}
+}
+
+func (r *IndexRefresher) Stop() {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		close(r.done)
+	})
+}
+
+// 需要加载的主链,ETH链共用TRUE链地址
+func indexSymbols(symbol string) []string {
+	symbol = strings.ToUpper(symbol)
+	if symbol == model.ETH {
+		return []string{symbol, model.TRUE}
+	}
+	return []string{symbol}
+}
+
+// 从数据库读取地址及账户别名
+func loadIndexEntries(symbol string, repo Repository, call func(key, accountID string)) error {
+	for _, s := range indexSymbols(symbol) {
+		addresses, err := repo.FindAddressList(NewFilter().Eq("symbol", s).Eq("state", 1), 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, v := range addresses {
+			call(util.AddStr(s, v.Address), v.AccountID)
+		}
+		accounts, err := repo.FindAccountList(NewFilter().Eq("symbol", s).Eq("state", 1), 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, v := range accounts {
+			if len(v.Alias) > 0 {
+				call(util.AddStr(s, v.Alias), v.AccountID)
+			}
+		}
+	}
+	return nil
+}
+
+// RedisAddressIndex 基于Redis缓存的地址索引
+type RedisAddressIndex struct {
+}
+
+func (r *RedisAddressIndex) AccountIDByAddress(address, symbol string) (string, error) {
+	client, err := new(cache.RedisManager).Client()
+	if err != nil {
+		log2.Error(util.AddStr("[", symbol, "]通过地址[", address, "]获取redis失败: ", err), 0)
+		return "", nil
+	}
+	obj := major.CacheValue{}
+	if _, err := client.Get(util.AddStr(symbol, address), &obj); err != nil {
+		log2.Error(util.AddStr("[", symbol, "]通过地址[", address, "]读取账号ID失败: ", err), 0)
+		return "", nil
+	}
+	if len(obj.V) == 0 {
+		if symbol == model.ETH {
+			if _, err := client.Get(util.AddStr(model.TRUE, address), &obj); err != nil {
+				log2.Error(util.AddStr("[", model.TRUE, "]通过地址[", address, "]读取账号ID失败: ", err), 0)
+				return "", nil
+			}
+		}
+		if len(obj.V) == 0 {
+			return "", nil
+		}
+	}
+	return obj.V, nil
+}
+
+func (r *RedisAddressIndex) AccountIDByAlias(alias, symbol string) (string, error) {
+	client, err := new(cache.RedisManager).Client()
+	if err != nil {
+		log2.Error(util.AddStr("[", symbol, "]通过别名[", alias, "]获取redis失败: ", err), 0)
+		return "", nil
+	}
+	obj := major.CacheValue{}
+	if _, err := client.Get(util.AddStr(symbol, alias), &obj); err != nil {
+		log2.Error(util.AddStr("[", symbol, "]通过别名[", alias, "]读取账号ID失败: ", err), 0)
+		return "", nil
+	}
+	if len(obj.V) == 0 {
+		return "", nil
+	}
+	return obj.V, nil
+}
+
+// MemoryAddressIndex 进程内地址索引,从OwAddress/OwAccount预加载,未命中时直接返回空
+// 新导入的地址通过Add或tradeutil.NotifyAddress通知(回查下一层索引)加入,定时重新加载时清理已删除的地址
+type MemoryAddressIndex struct {
+	mu      sync.RWMutex
+	symbol  string
+	repo    Repository
+	entries map[string]string
+	next    AddressIndex
+}
+
+func NewMemoryAddressIndex(symbol string, repo Repository, next AddressIndex) *MemoryAddressIndex {
+	if repo == nil {
+		repo = defaultRepository
+	}
+	return &MemoryAddressIndex{symbol: symbol, repo: repo, entries: make(map[string]string), next: next}
+}
+
+func (m *MemoryAddressIndex) Reload() error {
+	entries := make(map[string]string)
+	if err := loadIndexEntries(m.symbol, m.repo, func(key, accountID string) {
+		entries[key] = accountID
+	}); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.entries = entries
+	m.mu.Unlock()
+	log.Info(m.symbol, " 地址索引加载完成: ", len(entries))
+	return nil
+}
+
+// Add 添加地址或别名
+func (m *MemoryAddressIndex) Add(key, symbol, accountID string) {
+	m.mu.Lock()
+	m.entries[util.AddStr(symbol, key)] = accountID
+	m.mu.Unlock()
+}
+
+// Remove 删除地址或别名
+func (m *MemoryAddressIndex) Remove(key, symbol string) {
+	m.mu.Lock()
+	delete(m.entries, util.AddStr(symbol, key))
+	m.mu.Unlock()
+}
+
+// 地址变更时回查下一层索引更新该地址,订阅中断后重新加载
+func (m *MemoryAddressIndex) invalidate(kind, id string) {
+	switch kind {
+	case tradeutil.CacheAddress:
+		symbol, key, ok := tradeutil.SplitAddressKey(id)
+		if !ok || m.next == nil {
+			return
+		}
+		accountID, err := m.next.AccountIDByAddress(key, symbol)
+		if err == nil && len(accountID) == 0 {
+			accountID, err = m.next.AccountIDByAlias(key, symbol)
+		}
+		if err != nil {
+			log.Error(m.symbol, " 地址索引更新[", id, "]失败: ", err.Error())
+			return
+		}
+		if len(accountID) == 0 {
+			m.Remove(key, symbol)
+		} else {
+			m.Add(key, symbol, accountID)
+		}
+	case tradeutil.CacheAll:
+		go reloadIndex(m)
+	}
+}
+
+func (m *MemoryAddressIndex) get(keys ...string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, k := range keys {
+		if v, ok := m.entries[k]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (m *MemoryAddressIndex) AccountIDByAddress(address, symbol string) (string, error) {
+	keys := []string{util.AddStr(symbol, address)}
+	if symbol == model.ETH {
+		keys = append(keys, util.AddStr(model.TRUE, address))
+	}
+	v, _ := m.get(keys...)
+	return v, nil
+}
+
+func (m *MemoryAddressIndex) AccountIDByAlias(alias, symbol string) (string, error) {
+	v, _ := m.get(util.AddStr(symbol, alias))
+	return v, nil
+}
+
+// LayeredAddressIndex 本地布隆过滤器加下一层索引,过滤器未命中时直接返回空,命中后以下一层索引的结果为准
+// 新导入的地址通过Add或tradeutil.NotifyAddress通知加入过滤器,定时重新加载时清理已删除的地址
+type LayeredAddressIndex struct {
+	mu     sync.RWMutex
+	symbol string
+	repo   Repository
+	filter *bloomFilter
+	next   AddressIndex
+}
+
+func NewLayeredAddressIndex(symbol string, repo Repository, next AddressIndex) *LayeredAddressIndex {
+	if repo == nil {
+		repo = defaultRepository
+	}
+	return &LayeredAddressIndex{symbol: symbol, repo: repo, filter: newBloomFilter(1, bloomFalsePositive), next: next}
+}
+
+func (l *LayeredAddressIndex) Reload() error {
+	keys := make([]string, 0)
+	if err := loadIndexEntries(l.symbol, l.repo, func(key, accountID string) {
+		keys = append(keys, key)
+	}); err != nil {
+		return err
+	}
+	// 预留新增地址空间
+	filter := newBloomFilter(len(keys)*2, bloomFalsePositive)
+	for _, k := range keys {
+		filter.Add(k)
+	}
+	l.mu.Lock()
+	l.filter = filter
+	l.mu.Unlock()
+	log.Info(l.symbol, " 地址布隆过滤器加载完成: ", len(keys))
+	return nil
+}
+
+// Add 添加地址或别名
+func (l *LayeredAddressIndex) Add(key, symbol string) {
+	l.mu.Lock()
+	l.filter.Add(util.AddStr(symbol, key))
+	l.mu.Unlock()
+}
+
+// 新增地址加入过滤器,订阅中断后重新加载
+func (l *LayeredAddressIndex) invalidate(kind, id string) {
+	switch kind {
+	case tradeutil.CacheAddress:
+		if symbol, key, ok := tradeutil.SplitAddressKey(id); ok {
+			l.Add(key, symbol)
+		}
+	case tradeutil.CacheAll:
+		go reloadIndex(l)
+	}
+}
+
+func (l *LayeredAddressIndex) mayContain(keys ...string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, k := range keys {
+		if l.filter.Test(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LayeredAddressIndex) AccountIDByAddress(address, symbol string) (string, error) {
+	keys := []string{util.AddStr(symbol, address)}
+	if symbol == model.ETH {
+		keys = append(keys, util.AddStr(model.TRUE, address))
+	}
+	if !l.mayContain(keys...) {
+		return "", nil
+	}
+	return l.next.AccountIDByAddress(address, symbol)
+}
+
+func (l *LayeredAddressIndex) AccountIDByAlias(alias, symbol string) (string, error) {
+	if !l.mayContain(util.AddStr(symbol, alias)) {
+		return "", nil
+	}
+	return l.next.AccountIDByAlias(alias, symbol)
+}
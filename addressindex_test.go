@@ -0,0 +1,122 @@
+package open_scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_base/model"
+	tradeutil "github.com/nbit99/open_scanner/uitl"
+)
+
+// 模拟Redis索引,记录查询次数
+type stubAddressIndex struct {
+	entries map[string]string
+	calls   int
+}
+
+func (s *stubAddressIndex) AccountIDByAddress(address, symbol string) (string, error) {
+	s.calls++
+	return s.entries[util.AddStr(symbol, address)], nil
+}
+
+func (s *stubAddressIndex) AccountIDByAlias(alias, symbol string) (string, error) {
+	s.calls++
+	return s.entries[util.AddStr(symbol, alias)], nil
+}
+
+func newIndexRepository(t *testing.T) *MemoryRepository {
+	repo := NewMemoryRepository()
+	if err := repo.Save(&model.OwAddress{AccountID: "acc1", Symbol: "BTC", Address: "addr1", State: 1}); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestMemoryAddressIndexNotify(t *testing.T) {
+	next := &stubAddressIndex{entries: map[string]string{"BTCaddr2": "acc2", "BTCalias2": "acc2"}}
+	index := NewMemoryAddressIndex("BTC", newIndexRepository(t), next)
+	if err := index.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := index.AccountIDByAddress("addr1", "BTC"); v != "acc1" {
+		t.Fatalf("expected preloaded acc1, got %s", v)
+	}
+	// 未命中时不查询下一层索引
+	if v, _ := index.AccountIDByAddress("addr2", "BTC"); v != "" || next.calls != 0 {
+		t.Fatalf("expected empty without fallback, got %s (%d calls)", v, next.calls)
+	}
+	// 新导入的地址及别名通过通知加入
+	index.invalidate(tradeutil.CacheAddress, tradeutil.AddressKey("BTC", "addr2"))
+	index.invalidate(tradeutil.CacheAddress, tradeutil.AddressKey("BTC", "alias2"))
+	if v, _ := index.AccountIDByAddress("addr2", "BTC"); v != "acc2" {
+		t.Fatalf("expected notified acc2, got %s", v)
+	}
+	if v, _ := index.AccountIDByAlias("alias2", "BTC"); v != "acc2" {
+		t.Fatalf("expected notified alias acc2, got %s", v)
+	}
+	// 删除的地址通过通知移除
+	delete(next.entries, "BTCaddr2")
+	index.invalidate(tradeutil.CacheAddress, tradeutil.AddressKey("BTC", "addr2"))
+	if v, _ := index.AccountIDByAddress("addr2", "BTC"); v != "" {
+		t.Fatalf("expected removed addr2, got %s", v)
+	}
+}
+
+func TestLayeredAddressIndexBloomMiss(t *testing.T) {
+	next := &stubAddressIndex{entries: map[string]string{"BTCaddr1": "acc1", "BTCaddr2": "acc2"}}
+	index := NewLayeredAddressIndex("BTC", newIndexRepository(t), next)
+	if err := index.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := index.AccountIDByAddress("addr1", "BTC"); v != "acc1" || next.calls != 1 {
+		t.Fatalf("expected acc1 from next, got %s (%d calls)", v, next.calls)
+	}
+	// 过滤器未命中时不查询下一层索引
+	if v, _ := index.AccountIDByAddress("addr2", "BTC"); v != "" || next.calls != 1 {
+		t.Fatalf("expected empty without next lookup, got %s (%d calls)", v, next.calls)
+	}
+	if v, _ := index.AccountIDByAlias("alias2", "BTC"); v != "" || next.calls != 1 {
+		t.Fatalf("expected empty alias without next lookup, got %s (%d calls)", v, next.calls)
+	}
+	// 新导入的地址通过通知加入过滤器
+	index.invalidate(tradeutil.CacheAddress, tradeutil.AddressKey("BTC", "addr2"))
+	if v, _ := index.AccountIDByAddress("addr2", "BTC"); v != "acc2" || next.calls != 2 {
+		t.Fatalf("expected acc2 after notify, got %s (%d calls)", v, next.calls)
+	}
+}
+
+type countingIndex struct {
+	stubAddressIndex
+	reloads chan struct{}
+}
+
+func (c *countingIndex) Reload() error {
+	c.reloads <- struct{}{}
+	return nil
+}
+
+func TestRefreshAddressIndexStop(t *testing.T) {
+	if r := RefreshAddressIndex(new(RedisAddressIndex), time.Millisecond); r != nil {
+		t.Fatal("expected nil refresher for non-reloadable index")
+	}
+	index := &countingIndex{reloads: make(chan struct{}, 1)}
+	r := RefreshAddressIndex(index, 10*time.Millisecond)
+	select {
+	case <-index.reloads:
+	case <-time.After(time.Second):
+		t.Fatal("index not reloaded")
+	}
+	r.Stop()
+	r.Stop()
+	// 停止前可能已开始的一次加载
+	select {
+	case <-index.reloads:
+	case <-time.After(50 * time.Millisecond):
+	}
+	select {
+	case <-index.reloads:
+		t.Fatal("index reloaded after stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,175 @@
+package open_scanner
+
+import (
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	"sync"
+	"time"
+)
+
+const (
+	AlertStatusAlert    = "alert"
+	AlertStatusRecovery = "recovery"
+
+	defaultAlertLag      = 50
+	defaultAlertStall    = 600
+	defaultAlertInterval = 60
+)
+
+// LagAlert 扫块延迟告警/恢复消息
+type LagAlert struct {
+	Symbol        string `json:"symbol"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason"`
+	Height        uint64 `json:"height"`        // 最后通知的区块高度
+	NodeHeight    uint64 `json:"nodeHeight"`    // 节点最新高度
+	Lag           uint64 `json:"lag"`           // 落后区块数
+	LastBlockTime int64  `json:"lastBlockTime"` // 最后通知区块的时间(毫秒)
+	Time          int64  `json:"time"`
+}
+
+// LagMonitor 比较最后通知的区块高度与节点高度,超过阈值时发送告警,恢复后发送恢复消息
+// [币种ini]
+// alertLag = 落后区块数阈值,0不检查
+// alertStall = 未收到新区块的秒数阈值,0不检查
+// alertInterval = 检查间隔(秒)
+// 以上阈值支持配置热加载
+// alertWebhook = 告警同时发送的webhook地址,作为名称为alert的webhook发送通道(只订阅告警/恢复消息),经发件箱签名及重试
+// alertSecret = webhook签名密钥
+type LagMonitor struct {
+	mu       sync.Mutex
+	o        *OpenWScanner
+	lag      uint64
+	stall    time.Duration
+	interval time.Duration
+	height   uint64
+	last     time.Time
+	alerting bool
+	done     chan struct{}
+	once     sync.Once
+}
+
+func NewLagMonitor(o *OpenWScanner, c config.Configer) *LagMonitor {
+	m := &LagMonitor{o: o, last: time.Now(), done: make(chan struct{})}
+	m.Apply(c)
+	return m
+}
+
+// Apply 更新告警阈值及检查间隔,新的检查间隔在下次检查后生效
+func (m *LagMonitor) Apply(c config.Configer) {
+	interval := time.Duration(c.DefaultInt64("alertInterval", defaultAlertInterval)) * time.Second
+	if interval <= 0 {
+		interval = defaultAlertInterval * time.Second
+	}
+	m.mu.Lock()
+	m.lag = uint64(c.DefaultInt64("alertLag", defaultAlertLag))
+	m.stall = time.Duration(c.DefaultInt64("alertStall", defaultAlertStall)) * time.Second
+	m.interval = interval
+	m.mu.Unlock()
+}
+
+// 启动延迟告警检查
+func (o *OpenWScanner) startLagMonitor(c config.Configer) {
+	if o.lagMonitor != nil {
+		return
+	}
+	o.lagMonitor = NewLagMonitor(o, c)
+	if o.BlockScanner != nil {
+		o.lagMonitor.Notify(o.BlockScanner.GetScannedBlockHeight())
+	}
+	o.lagMonitor.Start()
+}
+
+// Notify 记录最后通知的区块
+func (m *LagMonitor) Notify(height uint64) {
+	m.mu.Lock()
+	m.height = height
+	m.last = time.Now()
+	m.mu.Unlock()
+}
+
+func (m *LagMonitor) Start() {
+	go func() {
+		for {
+			m.mu.Lock()
+			interval := m.interval
+			m.mu.Unlock()
+			select {
+			case <-m.done:
+				return
+			case <-time.After(interval):
+				m.check()
+			}
+		}
+	}()
+}
+
+func (m *LagMonitor) Stop() {
+	m.once.Do(func() {
+		close(m.done)
+	})
+}
+
+func (m *LagMonitor) check() {
+	scanner := m.o.BlockScanner
+	if scanner == nil {
+		return
+	}
+	tip := scanner.GetGlobalMaxBlockHeight()
+	scanned := scanner.GetScannedBlockHeight()
+	m.mu.Lock()
+	// 启动后未收到区块通知时按扫块器已扫描高度计算,仍为0时不检查落后区块数
+	if m.height == 0 {
+		m.height = scanned
+	}
+	alert := LagAlert{Symbol: m.o.Symbol, Height: m.height, NodeHeight: tip, LastBlockTime: util.Time(m.last), Time: util.Time()}
+	if tip > m.height {
+		alert.Lag = tip - m.height
+	}
+	stalled := m.stall > 0 && time.Since(m.last) > m.stall
+	lagged := m.lag > 0 && m.height > 0 && alert.Lag > m.lag
+	alerting := m.alerting
+	m.mu.Unlock()
+	if lagged || stalled {
+		// 暂停中的扫块器不产生新告警
+		if running, _ := scannerRunning(scanner); alerting || !running {
+			return
+		}
+		if lagged {
+			alert.Reason = "扫描高度落后节点超过阈值"
+		} else {
+			alert.Reason = "超过阈值时间未扫描到新区块"
+		}
+		alert.Status = AlertStatusAlert
+		m.setAlerting(true)
+		m.send(EventAlert, alert)
+	} else if alerting {
+		alert.Status = AlertStatusRecovery
+		alert.Reason = "扫描高度已恢复"
+		m.setAlerting(false)
+		m.send(EventRecovery, alert)
+	}
+}
+
+func (m *LagMonitor) setAlerting(alerting bool) {
+	m.mu.Lock()
+	m.alerting = alerting
+	m.mu.Unlock()
+}
+
+// 发送告警到订阅告警消息的发送通道(经发件箱),包括alertWebhook
+func (m *LagMonitor) send(kind int64, alert LagAlert) {
+	o := m.o
+	log2.Warn("扫块延迟"+alert.Status, 0, log2.String("symbol", o.Symbol), log2.String("reason", alert.Reason), log2.Uint64("height", alert.Height), log2.Uint64("nodeHeight", alert.NodeHeight))
+	ret, sig, err := o.Signer.Sign(alert)
+	if err != nil {
+		log2.Warn(err.Error(), 0, log2.Any("content", alert))
+		return
+	}
+	data := rabbitmq.MsgData{Exchange: exchange, Queue: queue + o.Symbol, Type: kind, Content: ret, Signature: sig}
+	if err := o.publish(data); err != nil {
+		log2.Error("扫块延迟告警发送MQ异常", 0, log2.String("symbol", o.Symbol), log2.Any("content", alert), log2.AddError(err))
+	}
+}
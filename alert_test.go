@@ -0,0 +1,112 @@
+package open_scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+func TestLagMonitorApply(t *testing.T) {
+	m := NewLagMonitor(&OpenWScanner{Symbol: "BTC"}, newTestConfig(t, "alertLag = 10\n"))
+	if m.lag != 10 || m.stall != defaultAlertStall*time.Second || m.interval != defaultAlertInterval*time.Second {
+		t.Fatalf("unexpected settings: %d %s %s", m.lag, m.stall, m.interval)
+	}
+	m.Apply(newTestConfig(t, "alertLag = 20\nalertStall = 30\nalertInterval = -1\n"))
+	if m.lag != 20 || m.stall != 30*time.Second || m.interval != defaultAlertInterval*time.Second {
+		t.Fatalf("unexpected settings after apply: %d %s %s", m.lag, m.stall, m.interval)
+	}
+}
+
+// 模拟扫块器,Scanning字段供scannerRunning读取
+type testBlockScanner struct {
+	openwallet.BlockScanner
+	Scanning bool
+	tip      uint64
+	scanned  uint64
+	calls    []string
+	rescan   uint64
+}
+
+func (s *testBlockScanner) GetGlobalMaxBlockHeight() uint64 {
+	return s.tip
+}
+
+func (s *testBlockScanner) GetScannedBlockHeight() uint64 {
+	return s.scanned
+}
+
+// 暂停/启动只切换Scanning并记录调用
+func (s *testBlockScanner) Pause() error {
+	s.Scanning = false
+	s.calls = append(s.calls, "pause")
+	return nil
+}
+
+func (s *testBlockScanner) Run() error {
+	s.Scanning = true
+	s.calls = append(s.calls, "run")
+	return nil
+}
+
+func (s *testBlockScanner) Restart() error {
+	s.Scanning = true
+	s.calls = append(s.calls, "restart")
+	return nil
+}
+
+func (s *testBlockScanner) SetRescanBlockHeight(height uint64) error {
+	s.rescan = height
+	return nil
+}
+
+func newTestLagMonitor(t *testing.T, scanner *testBlockScanner, ini string) (*LagMonitor, *memorySink) {
+	sink := &memorySink{name: "alert"}
+	o := &OpenWScanner{Symbol: "BTC", Signer: &Signer{legacy: "secret"}, BlockScanner: scanner,
+		routes: []*SinkRoute{{Sink: sink, Events: map[int64]bool{EventAlert: true, EventRecovery: true}}}}
+	return NewLagMonitor(o, newTestConfig(t, ini)), sink
+}
+
+func TestLagMonitorCheck(t *testing.T) {
+	scanner := &testBlockScanner{Scanning: true, tip: 1000, scanned: 995}
+	m, sink := newTestLagMonitor(t, scanner, "alertLag = 10\nalertStall = 0\n")
+	// 未收到区块通知时按已扫描高度计算
+	m.check()
+	if sink.count() != 0 || m.height != 995 {
+		t.Fatalf("unexpected alert before first block: %d (height %d)", sink.count(), m.height)
+	}
+	scanner.tip = 1100
+	m.check()
+	m.check()
+	if sink.count() != 1 || sink.sent[0].Type != EventAlert {
+		t.Fatalf("expected one alert, got %+v", sink.sent)
+	}
+	m.Notify(1100)
+	m.check()
+	if sink.count() != 2 || sink.sent[1].Type != EventRecovery {
+		t.Fatalf("expected recovery, got %+v", sink.sent)
+	}
+
+	// 扫块器未扫描过区块时不检查落后区块数
+	m, sink = newTestLagMonitor(t, &testBlockScanner{Scanning: true, tip: 1000}, "alertLag = 10\nalertStall = 0\n")
+	m.check()
+	if sink.count() != 0 {
+		t.Fatalf("unexpected alert without scanned height: %d", sink.count())
+	}
+}
+
+func TestLagMonitorStall(t *testing.T) {
+	scanner := &testBlockScanner{tip: 100, scanned: 100}
+	m, sink := newTestLagMonitor(t, scanner, "alertLag = 0\nalertStall = 1\n")
+	m.last = time.Now().Add(-2 * time.Second)
+	// 暂停中的扫块器不告警
+	m.check()
+	if sink.count() != 0 {
+		t.Fatalf("unexpected alert while paused: %d", sink.count())
+	}
+	scanner.Scanning = true
+	m.check()
+	if sink.count() != 1 || sink.sent[0].Type != EventAlert {
+		t.Fatalf("expected stall alert, got %+v", sink.sent)
+	}
+}
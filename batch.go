@@ -0,0 +1,274 @@
+package open_scanner
+
+import (
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/sqlc"
+	"github.com/godaddy-x/jorm/sqld"
+	"github.com/nbit99/open_base/model"
+	"github.com/nbit99/open_scanner/event"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTxBatchTimeout = 30
+	txBatchRetries        = 3
+)
+
+// 查询账户/合约失败后的重试间隔
+var txBatchRetryWait = time.Second
+
+// TxBatcher 按区块缓存交易单提取结果,区块扫描完成时批量查询账户/合约,每个应用合并为一条消息发送
+// [币种ini] txBatch = true 开启, txBatchTimeout 未收到区块通知时的发送超时(秒,默认30)
+// 交易单批量消息先于区块消息发送,同一区块内按提取顺序排列
+// 查询账户/合约失败时原地重试,仍失败则丢弃区块交易单并使区块通知返回错误,由适配器重扫区块
+type TxBatcher struct {
+	mu         sync.Mutex
+	o          *OpenWScanner
+	timeout    time.Duration
+	blocks     map[string]*txBatchBlock // 区块hash -> 待发送的交易单
+	findOwners func(keys []string) (map[string]*txOwner, error)
+	done       chan struct{}
+	once       sync.Once
+}
+
+type txBatchBlock struct {
+	hash   string
+	height uint64
+	items  []txBatchItem
+	ctime  time.Time
+}
+
+type txBatchItem struct {
+	sourceKey string
+	data      *openwallet.TxExtractData
+}
+
+// 同一应用的交易单及发送后记录所需的数据
+type txBatchEntry struct {
+	sourceKey string
+	owner     *txOwner
+	result    *event.Tx
+}
+
+func NewTxBatcher(o *OpenWScanner, timeout time.Duration) *TxBatcher {
+	if timeout <= 0 {
+		timeout = defaultTxBatchTimeout * time.Second
+	}
+	b := &TxBatcher{o: o, timeout: timeout, blocks: make(map[string]*txBatchBlock), done: make(chan struct{})}
+	b.findOwners = func(keys []string) (map[string]*txOwner, error) {
+		return findTxOwners(o.lookups, keys)
+	}
+	return b
+}
+
+// 按币种配置开启交易单批量发送
+func (o *OpenWScanner) initTxBatcher(c config.Configer) {
+	if o.batcher != nil || !c.DefaultBool("txBatch", false) {
+		return
+	}
+	o.batcher = NewTxBatcher(o, time.Duration(c.DefaultInt64("txBatchTimeout", defaultTxBatchTimeout))*time.Second)
+	o.batcher.Start()
+}
+
+// Add 缓存交易单,按交易单所在区块分组
+func (b *TxBatcher) Add(sourceKey string, data *openwallet.TxExtractData) {
+	tx := data.Transaction
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	block, ok := b.blocks[tx.BlockHash]
+	if !ok {
+		block = &txBatchBlock{hash: tx.BlockHash, height: tx.BlockHeight, ctime: time.Now()}
+		b.blocks[tx.BlockHash] = block
+	}
+	block.items = append(block.items, txBatchItem{sourceKey: sourceKey, data: data})
+}
+
+// Flush 发送区块的交易单,区块扫描完成时调用,返回错误时区块须重扫
+func (b *TxBatcher) Flush(hash string) error {
+	b.mu.Lock()
+	block, ok := b.blocks[hash]
+	delete(b.blocks, hash)
+	b.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return b.send(block)
+}
+
+func (b *TxBatcher) Start() {
+	go func() {
+		for {
+			select {
+			case <-b.done:
+				return
+			case <-time.After(b.timeout / 2):
+				b.expire()
+			}
+		}
+	}()
+}
+
+// Stop 停止超时检查并发送全部缓存的交易单
+func (b *TxBatcher) Stop() {
+	b.once.Do(func() {
+		close(b.done)
+	})
+	for _, block := range b.take(func(*txBatchBlock) bool { return true }) {
+		if err := b.send(block); err != nil {
+			log2.Error("停止时区块交易单发送失败,需重扫区块", 0, log2.String("symbol", b.o.Symbol), log2.Uint64("height", block.height), log2.String("hash", block.hash), log2.Int("txs", len(block.items)), log2.AddError(err))
+		}
+	}
+}
+
+// 适配器未在交易单之后通知区块时,超时的区块直接发送
+func (b *TxBatcher) expire() {
+	for _, block := range b.take(func(block *txBatchBlock) bool { return time.Since(block.ctime) >= b.timeout }) {
+		log2.Warn("区块交易单等待区块通知超时,直接发送", 0, log2.String("symbol", b.o.Symbol), log2.Uint64("height", block.height), log2.String("hash", block.hash), log2.Int("txs", len(block.items)))
+		if err := b.send(block); err != nil {
+			log2.Error("超时区块交易单发送失败,需重扫区块", 0, log2.String("symbol", b.o.Symbol), log2.Uint64("height", block.height), log2.String("hash", block.hash), log2.Int("txs", len(block.items)), log2.AddError(err))
+		}
+	}
+}
+
+// 取出满足条件的区块,按高度排序
+func (b *TxBatcher) take(match func(block *txBatchBlock) bool) []*txBatchBlock {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	list := make([]*txBatchBlock, 0)
+	for hash, block := range b.blocks {
+		if match(block) {
+			list = append(list, block)
+			delete(b.blocks, hash)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].height < list[j].height })
+	return list
+}
+
+// 批量查询账户/合约后按应用合并发送,应用按区块内首次出现的顺序发送
+// 查询失败时原地重试,保证交易单仍先于区块消息发送
+func (b *TxBatcher) send(block *txBatchBlock) error {
+	defer b.o.callbacks.enter()()
+	o := b.o
+	keys := make([]string, 0, len(block.items))
+	for _, v := range block.items {
+		keys = append(keys, v.sourceKey)
+	}
+	owners, err := b.findOwners(keys)
+	for i := 1; err != nil && i < txBatchRetries; i++ {
+		log2.Warn("批量查询账户/合约失败,稍后重试", 0, log2.String("symbol", o.Symbol), log2.Uint64("height", block.height), log2.String("hash", block.hash), log2.Int("retry", i), log2.AddError(err))
+		time.Sleep(txBatchRetryWait)
+		owners, err = b.findOwners(keys)
+	}
+	if err != nil {
+		log2.Error("批量查询账户/合约失败", 0, log2.String("symbol", o.Symbol), log2.Uint64("height", block.height), log2.String("hash", block.hash), log2.Int("txs", len(block.items)), log2.AddError(err))
+		return err
+	}
+	apps := make([]string, 0)
+	batches := make(map[string][]txBatchEntry)
+	for _, v := range block.items {
+		owner, ok := owners[v.sourceKey]
+		if !ok {
+			log2.Error("Wrapper Account or Contract Not Exist", 0, log2.String("symbol", o.Symbol), log2.String("sourceKey", v.sourceKey), log2.String("txid", v.data.Transaction.TxID))
+			continue
+		}
+		result, err := o.newTxEvent(v.sourceKey, v.data, owner)
+		if err != nil {
+			log2.Error("生成交易单消息失败", 0, log2.String("symbol", o.Symbol), log2.String("sourceKey", v.sourceKey), log2.String("txid", v.data.Transaction.TxID), log2.AddError(err))
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		if _, ok := batches[owner.AppID]; !ok {
+			apps = append(apps, owner.AppID)
+		}
+		batches[owner.AppID] = append(batches[owner.AppID], txBatchEntry{sourceKey: v.sourceKey, owner: owner, result: result})
+	}
+	for _, appID := range apps {
+		entries := batches[appID]
+		batch := &event.TxBatch{SchemaVersion: event.SchemaVersion, AppID: appID, BlockHash: block.hash, BlockHeight: block.height, Txs: make([]*event.Tx, 0, len(entries))}
+		for _, v := range entries {
+			batch.Txs = append(batch.Txs, v.result)
+		}
+		ret, sig, err := o.Signer.Sign(batch)
+		if err != nil {
+			log2.Warn(err.Error(), 0, log2.String("appid", appID), log2.Uint64("height", block.height))
+			continue
+		}
+		err = o.publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + o.Symbol, Type: EventTxBatch, Content: ret, Signature: sig})
+		if err != nil {
+			log2.Error("发送MQ数据失败", 0, log2.String("appid", appID), log2.String("exchange", exchange), log2.String("queue", queue+o.Symbol), log2.Uint64("height", block.height), log2.Int("txs", len(entries)), log2.AddError(err))
+		}
+		for _, v := range entries {
+			o.txPublished(v.sourceKey, v.owner, v.result, err == nil)
+		}
+	}
+	return nil
+}
+
+// 批量查询sourceKey对应的资产账户,未找到的再查询合约,优先读取进程内缓存
+func findTxOwners(c *LookupCache, keys []string) (map[string]*txOwner, error) {
+	owners := make(map[string]*txOwner)
+	missing := make([]string, 0, len(keys))
+	for _, v := range keys {
+		if _, ok := owners[v]; ok {
+			continue
+		}
+		if account, ok := c.Get(accountCachePrefix + v); ok && account != nil {
+			owners[v] = accountOwner(account.(*model.OwAccount))
+		} else if contract, ok := c.Get(contractCachePrefix + v); ok && contract != nil {
+			owners[v] = contractOwner(contract.(*model.OwContract))
+		} else {
+			missing = append(missing, v)
+		}
+	}
+	values := uniqueValues(missing, owners)
+	if len(values) == 0 {
+		return owners, nil
+	}
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return nil, err
+	}
+	defer mongo.Close()
+	accounts := make([]*model.OwAccount, 0)
+	if err := mongo.FindList(sqlc.M(model.OwAccount{}).In("accountID", values...).Eq("state", 1), &accounts); err != nil {
+		return nil, err
+	}
+	for _, v := range accounts {
+		owners[v.AccountID] = accountOwner(v)
+		c.Put(accountCachePrefix+v.AccountID, v)
+	}
+	if values = uniqueValues(missing, owners); len(values) == 0 {
+		return owners, nil
+	}
+	contracts := make([]*model.OwContract, 0)
+	if err := mongo.FindList(sqlc.M(model.OwContract{}).In("contractID", values...).Eq("state", 1), &contracts); err != nil {
+		return nil, err
+	}
+	for _, v := range contracts {
+		owners[v.ContractID] = contractOwner(v)
+		c.Put(contractCachePrefix+v.ContractID, v)
+	}
+	return owners, nil
+}
+
+// 去重后未在owners中的sourceKey
+func uniqueValues(keys []string, owners map[string]*txOwner) []interface{} {
+	exist := make(map[string]bool)
+	values := make([]interface{}, 0, len(keys))
+	for _, v := range keys {
+		if _, ok := owners[v]; ok || exist[v] {
+			continue
+		}
+		exist[v] = true
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,134 @@
+package open_scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbit99/open_scanner/event"
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+// 批量发送的测试扫块服务,sourceKey与应用的对应关系由owners指定
+func newTestBatcher(timeout time.Duration, owners map[string]string) (*OpenWScanner, *memorySink) {
+	sink := &memorySink{name: SinkMQ}
+	o := &OpenWScanner{Symbol: "BTC", Signer: &Signer{legacy: "secret"}, routes: []*SinkRoute{{Sink: sink}}}
+	o.batcher = NewTxBatcher(o, timeout)
+	o.batcher.findOwners = func(keys []string) (map[string]*txOwner, error) {
+		result := make(map[string]*txOwner)
+		for _, v := range keys {
+			if appID, ok := owners[v]; ok {
+				result[v] = &txOwner{AppID: appID, WalletID: "w-" + v, AccountID: v}
+			}
+		}
+		return result, nil
+	}
+	return o, sink
+}
+
+func testExtractData(txid, hash string, height uint64) *openwallet.TxExtractData {
+	return &openwallet.TxExtractData{
+		Transaction: &openwallet.Transaction{TxID: txid, BlockHash: hash, BlockHeight: height},
+		TxOutputs:   []*openwallet.TxOutPut{{Recharge: openwallet.Recharge{TxID: txid, Address: "addr", Amount: "1"}}},
+	}
+}
+
+// 解析发送的交易单批量消息,返回每个应用的交易单txid
+func sentBatch(t *testing.T, sink *memorySink, i int) (string, []string) {
+	if sink.sent[i].Type != EventTxBatch {
+		t.Fatalf("message %d: expected tx batch, got type %d", i, sink.sent[i].Type)
+	}
+	batch, err := event.DecodeTxBatch(sink.sent[i].Content.(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txids := make([]string, 0, len(batch.Txs))
+	for _, v := range batch.Txs {
+		txids = append(txids, v.Content.TxID)
+	}
+	return batch.AppID, txids
+}
+
+func TestTxBatcherOrder(t *testing.T) {
+	o, sink := newTestBatcher(time.Minute, map[string]string{"a1": "app1", "a2": "app2", "a3": "app1"})
+	for _, v := range []struct{ key, txid string }{{"a1", "tx1"}, {"a2", "tx2"}, {"a3", "tx3"}, {"unknown", "tx4"}} {
+		if err := o.BlockExtractDataNotify(v.key, testExtractData(v.txid, "h100", 100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sink.count() != 0 {
+		t.Fatalf("expected no message before block notify, got %d", sink.count())
+	}
+	if err := o.BlockScanNotify(&openwallet.BlockHeader{Hash: "h100", Height: 100, Symbol: "BTC"}); err != nil {
+		t.Fatal(err)
+	}
+	// 每个应用一条批量消息,按区块内首次出现的顺序,先于区块消息发送
+	if sink.count() != 3 {
+		t.Fatalf("expected 3 messages, got %d", sink.count())
+	}
+	if appID, txids := sentBatch(t, sink, 0); appID != "app1" || len(txids) != 2 || txids[0] != "tx1" || txids[1] != "tx3" {
+		t.Fatalf("unexpected first batch: %s %v", appID, txids)
+	}
+	if appID, txids := sentBatch(t, sink, 1); appID != "app2" || len(txids) != 1 || txids[0] != "tx2" {
+		t.Fatalf("unexpected second batch: %s %v", appID, txids)
+	}
+	if sink.sent[2].Type != EventBlock {
+		t.Fatalf("expected block message last, got type %d", sink.sent[2].Type)
+	}
+}
+
+func TestTxBatcherTimeout(t *testing.T) {
+	o, sink := newTestBatcher(100*time.Millisecond, map[string]string{"a1": "app1"})
+	o.batcher.Start()
+	defer o.batcher.Stop()
+	o.batcher.Add("a1", testExtractData("tx1", "h100", 100))
+	// 未收到区块通知时超时发送
+	for deadline := time.Now().Add(2 * time.Second); sink.count() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("batch not sent after timeout")
+		}
+	}
+	if appID, txids := sentBatch(t, sink, 0); appID != "app1" || len(txids) != 1 {
+		t.Fatalf("unexpected batch: %s %v", appID, txids)
+	}
+}
+
+func TestTxBatcherRetry(t *testing.T) {
+	wait := txBatchRetryWait
+	txBatchRetryWait = time.Millisecond
+	defer func() { txBatchRetryWait = wait }()
+	o, sink := newTestBatcher(time.Minute, map[string]string{"a1": "app1"})
+	find := o.batcher.findOwners
+	calls := 0
+	o.batcher.findOwners = func(keys []string) (map[string]*txOwner, error) {
+		if calls++; calls < txBatchRetries {
+			return nil, errSinkFailed
+		}
+		return find(keys)
+	}
+	// 查询失败时原地重试,交易单仍先于区块消息发送
+	o.batcher.Add("a1", testExtractData("tx1", "h100", 100))
+	if err := o.BlockScanNotify(&openwallet.BlockHeader{Hash: "h100", Height: 100, Symbol: "BTC"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != txBatchRetries || sink.count() != 2 {
+		t.Fatalf("expected %d lookups and 2 messages, got %d and %d", txBatchRetries, calls, sink.count())
+	}
+	if appID, txids := sentBatch(t, sink, 0); appID != "app1" || len(txids) != 1 || txids[0] != "tx1" {
+		t.Fatalf("unexpected batch: %s %v", appID, txids)
+	}
+	if sink.sent[1].Type != EventBlock {
+		t.Fatalf("expected block message last, got type %d", sink.sent[1].Type)
+	}
+
+	// 重试仍失败时区块通知返回错误,不发送区块消息,交易单不留在缓存中
+	o.batcher.findOwners = func(keys []string) (map[string]*txOwner, error) {
+		return nil, errSinkFailed
+	}
+	o.batcher.Add("a1", testExtractData("tx2", "h101", 101))
+	if err := o.BlockScanNotify(&openwallet.BlockHeader{Hash: "h101", Height: 101, Symbol: "BTC"}); err == nil {
+		t.Fatal("expected error after failed lookup")
+	}
+	if sink.count() != 2 || len(o.batcher.blocks) != 0 {
+		t.Fatalf("expected no new message and empty cache, got %d and %d", sink.count(), len(o.batcher.blocks))
+	}
+}
@@ -0,0 +1,248 @@
+package open_scanner
+
+import (
+	"container/list"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/sqlc"
+	"github.com/godaddy-x/jorm/sqld"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_base/model"
+	tradeutil "github.com/nbit99/open_scanner/uitl"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLookupCacheSize    = 10000
+	defaultLookupCacheTTL     = 300
+	defaultLookupCacheMissTTL = 10
+
+	accountCachePrefix  = "account."
+	contractCachePrefix = "contract."
+	addressCachePrefix  = "contract.address."
+)
+
+// LookupCache 扫块回调中账户/合约查询的进程内LRU缓存,超过容量时淘汰最久未使用的记录,超过ttl后重新查询
+// 未找到的结果按较短的missTTL缓存(0不缓存),避免新增账户/合约在通知丢失时长时间查询不到
+// 通过tradeutil.OnInvalidate接收账户/合约变更通知,其他进程的变更通过Redis订阅(tradeutil.SubscribeInvalidate)同步
+type LookupCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	missTTL time.Duration
+	list    *list.List
+	items   map[string]*list.Element
+}
+
+type lookupEntry struct {
+	key    string
+	value  interface{}
+	expire time.Time
+}
+
+func NewLookupCache(size int, ttl, missTTL time.Duration) *LookupCache {
+	return &LookupCache{size: size, ttl: ttl, missTTL: missTTL, list: list.New(), items: make(map[string]*list.Element)}
+}
+
+// 按币种配置创建查询缓存: lookupCache = 最大记录数(默认10000,0关闭), lookupCacheTTL = 有效期(秒,默认300)
+// lookupCacheMissTTL = 未找到结果的有效期(秒,默认10,0不缓存), lookupCacheSync = 订阅其他进程的变更通知(默认true)
+func (o *OpenWScanner) initLookupCache(c config.Configer) {
+	if o.lookups != nil {
+		return
+	}
+	size := c.DefaultInt64("lookupCache", defaultLookupCacheSize)
+	if size <= 0 {
+		return
+	}
+	ttl := time.Duration(c.DefaultInt64("lookupCacheTTL", defaultLookupCacheTTL)) * time.Second
+	missTTL := time.Duration(c.DefaultInt64("lookupCacheMissTTL", defaultLookupCacheMissTTL)) * time.Second
+	o.lookups = NewLookupCache(int(size), ttl, missTTL)
+	tradeutil.OnInvalidate(o.lookups.invalidate)
+	if c.DefaultBool("lookupCacheSync", true) {
+		tradeutil.SubscribeInvalidate()
+	}
+}
+
+// Get 读取未过期的记录,value为nil表示已查询过且不存在
+func (c *LookupCache) Get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lookupEntry)
+	if !entry.expire.IsZero() && time.Now().After(entry.expire) {
+		c.list.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.list.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Put 写入记录,value为nil表示不存在,按missTTL缓存
+func (c *LookupCache) Put(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+	ttl := c.ttl
+	if value == nil {
+		if c.missTTL <= 0 {
+			c.Remove(key)
+			return
+		}
+		ttl = c.missTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// ttl为0时不过期
+	var expire time.Time
+	if ttl > 0 {
+		expire = time.Now().Add(ttl)
+	}
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lookupEntry)
+		entry.value, entry.expire = value, expire
+		c.list.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.list.PushFront(&lookupEntry{key: key, value: value, expire: expire})
+	for c.list.Len() > c.size {
+		elem := c.list.Back()
+		c.list.Remove(elem)
+		delete(c.items, elem.Value.(*lookupEntry).key)
+	}
+}
+
+func (c *LookupCache) Remove(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.list.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// RemoveIf 删除满足条件的记录
+func (c *LookupCache) RemoveIf(match func(key string, value interface{}) bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.items {
+		if match(key, elem.Value.(*lookupEntry).value) {
+			c.list.Remove(elem)
+			delete(c.items, key)
+		}
+	}
+}
+
+// Purge 清空缓存
+func (c *LookupCache) Purge() {
+	c.RemoveIf(func(string, interface{}) bool { return true })
+}
+
+func (c *LookupCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.list.Len()
+}
+
+// 账户变更删除该账户,合约变更删除该合约及未找到的合约地址(新增合约的地址),订阅中断后清空缓存
+func (c *LookupCache) invalidate(kind, id string) {
+	switch kind {
+	case tradeutil.CacheAll:
+		c.Purge()
+	case tradeutil.CacheAccount:
+		c.Remove(accountCachePrefix + id)
+	case tradeutil.CacheContract:
+		c.RemoveIf(func(key string, value interface{}) bool {
+			if !strings.HasPrefix(key, contractCachePrefix) {
+				return false
+			}
+			contract, ok := value.(*model.OwContract)
+			return key == contractCachePrefix+id || (ok && contract.ContractID == id) || (value == nil && strings.HasPrefix(key, addressCachePrefix))
+		})
+	}
+}
+
+// 按accountID查询资产账户,不存在时返回nil
+func findAccount(c *LookupCache, accountID string) (*model.OwAccount, error) {
+	key := accountCachePrefix + accountID
+	if v, ok := c.Get(key); ok {
+		account, _ := v.(*model.OwAccount)
+		return account, nil
+	}
+	account := &model.OwAccount{}
+	if err := findOne(sqlc.M(model.OwAccount{}).Eq("accountID", accountID).Eq("state", 1), account); err != nil {
+		return nil, err
+	}
+	if account.Id == 0 {
+		c.Put(key, nil)
+		return nil, nil
+	}
+	c.Put(key, account)
+	return account, nil
+}
+
+// 按contractID查询合约,不存在时返回nil
+func findContract(c *LookupCache, contractID string) (*model.OwContract, error) {
+	key := contractCachePrefix + contractID
+	if v, ok := c.Get(key); ok {
+		contract, _ := v.(*model.OwContract)
+		return contract, nil
+	}
+	contract := &model.OwContract{}
+	if err := findOne(sqlc.M(model.OwContract{}).Eq("contractID", contractID).Eq("state", 1), contract); err != nil {
+		return nil, err
+	}
+	if contract.Id == 0 {
+		c.Put(key, nil)
+		return nil, nil
+	}
+	c.Put(key, contract)
+	return contract, nil
+}
+
+// 按币种及合约地址查询合约,不存在时返回nil,按实际查询的存储记录耗时
+func findContractByAddress(c *LookupCache, symbol, address string) (*model.OwContract, error) {
+	start := time.Now()
+	key := util.AddStr(addressCachePrefix, symbol, ".", address)
+	if v, ok := c.Get(key); ok {
+		observeLookup(strings.ToUpper(symbol), lookupStoreCache, "contract", start)
+		contract, _ := v.(*model.OwContract)
+		return contract, nil
+	}
+	defer observeLookup(strings.ToUpper(symbol), lookupStoreMongo, "contract", start)
+	contract := &model.OwContract{}
+	if err := findOne(sqlc.M(model.OwContract{}).Eq("symbol", symbol).Eq("address", address).Eq("state", 1), contract); err != nil {
+		return nil, err
+	}
+	if contract.Id == 0 {
+		c.Put(key, nil)
+		return nil, nil
+	}
+	c.Put(key, contract)
+	return contract, nil
+}
+
+func findOne(cnd *sqlc.Cnd, data interface{}) error {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return err
+	}
+	defer mongo.Close()
+	return mongo.FindOne(cnd, data)
+}
@@ -0,0 +1,54 @@
+package open_scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbit99/open_base/model"
+	tradeutil "github.com/nbit99/open_scanner/uitl"
+)
+
+func TestLookupCacheMissTTL(t *testing.T) {
+	c := NewLookupCache(10, time.Minute, 50*time.Millisecond)
+	c.Put("account.a1", &model.OwAccount{AccountID: "a1"})
+	c.Put("account.a2", nil)
+	if v, ok := c.Get("account.a2"); !ok || v != nil {
+		t.Fatal("expected cached miss")
+	}
+	// 未找到的结果按missTTL过期,找到的结果仍有效
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := c.Get("account.a2"); ok {
+		t.Fatal("expected miss to expire")
+	}
+	if v, ok := c.Get("account.a1"); !ok || v == nil {
+		t.Fatal("expected cached account")
+	}
+
+	// missTTL为0时不缓存未找到的结果,并删除原有记录
+	c = NewLookupCache(10, 0, 0)
+	c.Put("account.a1", &model.OwAccount{AccountID: "a1"})
+	c.Put("account.a1", nil)
+	if _, ok := c.Get("account.a1"); ok || c.Len() != 0 {
+		t.Fatal("expected miss not cached")
+	}
+}
+
+func TestLookupCacheInvalidate(t *testing.T) {
+	c := NewLookupCache(10, time.Minute, time.Minute)
+	c.Put(accountCachePrefix+"a1", &model.OwAccount{AccountID: "a1"})
+	c.Put(contractCachePrefix+"c1", &model.OwContract{ContractID: "c1"})
+	c.Put(addressCachePrefix+"ETH.0xc", nil)
+	c.invalidate(tradeutil.CacheContract, "c2")
+	if _, ok := c.Get(addressCachePrefix + "ETH.0xc"); ok || c.Len() != 2 {
+		t.Fatal("expected missing contract address removed")
+	}
+	c.invalidate(tradeutil.CacheAccount, "a1")
+	if _, ok := c.Get(accountCachePrefix + "a1"); ok {
+		t.Fatal("expected account removed")
+	}
+	// 订阅中断后清空缓存
+	c.invalidate(tradeutil.CacheAll, "")
+	if c.Len() != 0 {
+		t.Fatalf("expected empty cache, got %d", c.Len())
+	}
+}
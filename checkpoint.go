@@ -0,0 +1,314 @@
+package open_scanner
+
+import (
+	"encoding/json"
+	"github.com/astaxie/beego/config"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/sqlc"
+	"github.com/godaddy-x/jorm/sqld"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/openwallet/v2/common/file"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	CheckpointFile  = "file"
+	CheckpointMongo = "mongo"
+	CheckpointOff   = "off"
+
+	defaultCheckpointEvery = 1
+)
+
+// ScanCheckpoint 扫描检查点,记录最近扫描完成的区块,不依赖适配器的BlockchainDAI
+type ScanCheckpoint struct {
+	Id     int64  `json:"id" bson:"_id" tb:"ow_scan_checkpoint" mg:"true"`
+	Symbol string `json:"symbol" bson:"symbol"`
+	Height uint64 `json:"height" bson:"height"`
+	Hash   string `json:"hash" bson:"hash"`
+	Time   uint64 `json:"time" bson:"time"`   // 区块时间
+	Utime  int64  `json:"utime" bson:"utime"` // 更新时间
+}
+
+// CheckpointStore 扫描检查点存储
+type CheckpointStore interface {
+	// Load 读取币种检查点,不存在时返回nil
+	Load(symbol string) (*ScanCheckpoint, error)
+	Save(checkpoint *ScanCheckpoint) error
+}
+
+// NewCheckpointStore 按类型创建检查点存储,未知类型使用本地文件
+func NewCheckpointStore(kind, dir string) CheckpointStore {
+	switch kind {
+	case CheckpointMongo:
+		return new(MongoCheckpointStore)
+	default:
+		return &FileCheckpointStore{Dir: dir}
+	}
+}
+
+// FileCheckpointStore 检查点保存在dataDir/<SYMBOL>_checkpoint.json
+type FileCheckpointStore struct {
+	Dir string
+}
+
+func (s *FileCheckpointStore) path(symbol string) string {
+	return filepath.Join(s.Dir, symbol+"_checkpoint.json")
+}
+
+func (s *FileCheckpointStore) Load(symbol string) (*ScanCheckpoint, error) {
+	b, err := ioutil.ReadFile(s.path(symbol))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	checkpoint := &ScanCheckpoint{}
+	if err := json.Unmarshal(b, checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// Save 先写临时文件再重命名,避免写入中断后文件损坏
+func (s *FileCheckpointStore) Save(checkpoint *ScanCheckpoint) error {
+	b, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	file.MkdirAll(s.Dir)
+	path := s.path(checkpoint.Symbol)
+	if err := ioutil.WriteFile(path+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// MongoCheckpointStore 检查点保存在ow_scan_checkpoint,每个币种一条记录
+type MongoCheckpointStore struct {
+}
+
+func (s *MongoCheckpointStore) Load(symbol string) (*ScanCheckpoint, error) {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return nil, err
+	}
+	defer mongo.Close()
+	checkpoint := &ScanCheckpoint{}
+	if err := mongo.FindOne(sqlc.M(ScanCheckpoint{}).Eq("symbol", symbol), checkpoint); err != nil {
+		return nil, err
+	}
+	if checkpoint.Id == 0 {
+		return nil, nil
+	}
+	return checkpoint, nil
+}
+
+func (s *MongoCheckpointStore) Save(checkpoint *ScanCheckpoint) error {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return err
+	}
+	defer mongo.Close()
+	if checkpoint.Id == 0 {
+		old := ScanCheckpoint{}
+		if err := mongo.FindOne(sqlc.M(ScanCheckpoint{}).Eq("symbol", checkpoint.Symbol), &old); err != nil {
+			return err
+		}
+		checkpoint.Id = old.Id
+	}
+	return mongo.Save(checkpoint)
+}
+
+// Checkpointer 扫描完成的区块更新检查点,每every个区块写入一次
+type Checkpointer struct {
+	mu     sync.Mutex
+	store  CheckpointStore
+	symbol string
+	every  uint64
+	last   *ScanCheckpoint // 最近扫描完成的区块
+	saved  uint64          // 最近写入的高度
+	dirty  bool
+}
+
+func NewCheckpointer(store CheckpointStore, symbol string, every uint64) *Checkpointer {
+	if every == 0 {
+		every = defaultCheckpointEvery
+	}
+	return &Checkpointer{store: store, symbol: symbol, every: every}
+}
+
+// 按币种配置初始化检查点: checkpoint = file|mongo|off, checkpointEvery = 写入间隔(区块数)
+func (o *OpenWScanner) initCheckpointer(c config.Configer) {
+	if o.checkpoint != nil {
+		return
+	}
+	store := o.Checkpoints
+	if store == nil {
+		kind := c.DefaultString("checkpoint", CheckpointFile)
+		if kind == CheckpointOff {
+			return
+		}
+		store = NewCheckpointStore(kind, o.DbPath)
+	}
+	o.checkpoint = NewCheckpointer(store, o.Symbol, uint64(c.DefaultInt64("checkpointEvery", defaultCheckpointEvery)))
+}
+
+// Load 读取已保存的检查点
+func (c *Checkpointer) Load() (*ScanCheckpoint, error) {
+	checkpoint, err := c.store.Load(c.symbol)
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.last == nil {
+		c.last, c.saved = checkpoint, checkpoint.Height
+	}
+	c.mu.Unlock()
+	return checkpoint, nil
+}
+
+// Sync 重新读取存储中的检查点,高于本地时替换,主节点切换时接管其他实例写入的扫描高度
+func (c *Checkpointer) Sync() (*ScanCheckpoint, error) {
+	checkpoint, err := c.store.Load(c.symbol)
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.last == nil || checkpoint.Height > c.last.Height {
+		c.last, c.saved, c.dirty = checkpoint, checkpoint.Height, false
+	}
+	c.mu.Unlock()
+	return checkpoint, nil
+}
+
+// Current 最近扫描完成的区块,未扫描过时返回nil
+func (c *Checkpointer) Current() *ScanCheckpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last == nil {
+		return nil
+	}
+	checkpoint := *c.last
+	return &checkpoint
+}
+
+// Notify 区块扫描完成,高度回退(链重组)时立即写入
+func (c *Checkpointer) Notify(header *openwallet.BlockHeader) {
+	c.mu.Lock()
+	id := int64(0)
+	if c.last != nil {
+		id = c.last.Id
+	}
+	c.last = &ScanCheckpoint{Id: id, Symbol: c.symbol, Height: header.Height, Hash: header.Hash, Time: header.Time, Utime: util.Time()}
+	c.dirty = true
+	save := header.Height < c.saved || header.Height-c.saved >= c.every
+	c.mu.Unlock()
+	if save {
+		c.Flush()
+	}
+}
+
+// Flush 写入最近扫描完成的区块
+func (c *Checkpointer) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty || c.last == nil {
+		return
+	}
+	if err := c.store.Save(c.last); err != nil {
+		log2.Error("写入扫描检查点失败", 0, log2.String("symbol", c.symbol), log2.Uint64("height", c.last.Height), log2.AddError(err))
+		return
+	}
+	c.saved, c.dirty = c.last.Height, false
+}
+
+// 启动时确定扫描高度: InitHeight > 检查点 > 适配器已扫描高度
+// 适配器未持久化扫描高度(不支持BlockchainDAI)或数据丢失时从检查点的下一个区块继续扫描
+func (o *OpenWScanner) reconcileCheckpoint() {
+	scanner := o.BlockScanner
+	local := scanner.GetScannedBlockHeight()
+	if o.InitHeight > 0 {
+		log2.Info("按InitHeight设置扫描高度", 0, log2.String("symbol", o.Symbol), log2.Int64("initHeight", o.InitHeight), log2.Uint64("local", local))
+		scanner.SetRescanBlockHeight(uint64(o.InitHeight))
+		return
+	}
+	if o.checkpoint == nil {
+		return
+	}
+	checkpoint, err := o.checkpoint.Load()
+	if err != nil {
+		log2.Error("读取扫描检查点失败", 0, log2.String("symbol", o.Symbol), log2.AddError(err))
+		return
+	}
+	if checkpoint == nil {
+		return
+	}
+	switch {
+	case checkpoint.Height > local:
+		log2.Warn("适配器扫描高度落后检查点,从检查点继续扫描", 0, log2.String("symbol", o.Symbol), log2.Uint64("checkpoint", checkpoint.Height), log2.String("hash", checkpoint.Hash), log2.Uint64("local", local))
+		if err := scanner.SetRescanBlockHeight(o.checkpointResume(checkpoint)); err != nil {
+			log2.Error("设置扫描高度失败", 0, log2.String("symbol", o.Symbol), log2.AddError(err))
+		}
+	case checkpoint.Height < local:
+		log2.Info("适配器扫描高度领先检查点", 0, log2.String("symbol", o.Symbol), log2.Uint64("checkpoint", checkpoint.Height), log2.Uint64("local", local))
+	}
+}
+
+// BlockHashReader 读取链上指定高度的区块hash,区块扫描器实现后启动时校验检查点
+type BlockHashReader interface {
+	GetBlockHash(height uint64) (string, error)
+}
+
+// 检查点之后的扫描高度,检查点区块已被孤立(链上hash不一致)时回退重组窗口大小的区块重新扫描
+// 扫描器未实现BlockHashReader时使用本地区块库中的区块头,均无法读取时不校验
+func (o *OpenWScanner) checkpointResume(checkpoint *ScanCheckpoint) uint64 {
+	hash, err := o.chainBlockHash(checkpoint.Height)
+	if err != nil {
+		log2.Warn("读取检查点区块hash失败,不校验检查点", 0, log2.String("symbol", o.Symbol), log2.Uint64("height", checkpoint.Height), log2.AddError(err))
+		return checkpoint.Height + 1
+	}
+	if len(hash) == 0 || len(checkpoint.Hash) == 0 || hash == checkpoint.Hash {
+		return checkpoint.Height + 1
+	}
+	depth := uint64(reorgWindowSize)
+	if o.reorg != nil {
+		depth = o.reorg.size
+	}
+	height := uint64(1)
+	if checkpoint.Height > depth {
+		height = checkpoint.Height - depth + 1
+	}
+	log2.Error("检查点区块hash与链上不一致,回退重新扫描", 0, log2.String("symbol", o.Symbol), log2.Uint64("checkpoint", checkpoint.Height), log2.String("hash", checkpoint.Hash), log2.String("chainHash", hash), log2.Uint64("rescan", height))
+	return height
+}
+
+// 链上区块hash,无法读取时返回空
+func (o *OpenWScanner) chainBlockHash(height uint64) (string, error) {
+	if reader, ok := o.BlockScanner.(BlockHashReader); ok {
+		return reader.GetBlockHash(height)
+	}
+	if o.dai == nil {
+		return "", nil
+	}
+	header, err := o.dai.GetLocalBlockHeadByHeight(height, o.Symbol)
+	if err != nil || header == nil {
+		return "", nil
+	}
+	return header.Hash, nil
+}
+
+// Checkpoint 当前扫描检查点,未开启或未扫描过时返回nil
+func (o *OpenWScanner) Checkpoint() (*ScanCheckpoint, error) {
+	if o.checkpoint == nil {
+		return nil, util.Error("[", o.Symbol, "]扫描检查点未开启")
+	}
+	if checkpoint := o.checkpoint.Current(); checkpoint != nil {
+		return checkpoint, nil
+	}
+	return o.checkpoint.Load()
+}
@@ -0,0 +1,86 @@
+package open_scanner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+func TestCheckpointerSync(t *testing.T) {
+	// 两个实例共用同一检查点存储
+	dir, remove := tempDir(t)
+	defer remove()
+	store := &FileCheckpointStore{Dir: dir}
+	leader := NewCheckpointer(store, "BTC", 10)
+	standby := NewCheckpointer(store, "BTC", 10)
+	if v, err := standby.Sync(); err != nil || v != nil {
+		t.Fatalf("expected no checkpoint, got %v (%v)", v, err)
+	}
+	standby.Notify(&openwallet.BlockHeader{Height: 5, Hash: "h5"})
+
+	for h := uint64(1); h <= 20; h++ {
+		leader.Notify(&openwallet.BlockHeader{Height: h, Hash: "h"})
+	}
+	// 未达到写入间隔的区块在切换前写入
+	leader.Notify(&openwallet.BlockHeader{Height: 25, Hash: "h25"})
+	leader.Flush()
+
+	v, err := standby.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == nil || v.Height != 25 || v.Hash != "h25" {
+		t.Fatalf("unexpected checkpoint: %+v", v)
+	}
+	if cur := standby.Current(); cur == nil || cur.Height != 25 {
+		t.Fatalf("standby not advanced: %+v", cur)
+	}
+	// 本地高于存储时保留本地检查点
+	standby.Notify(&openwallet.BlockHeader{Height: 30, Hash: "h30"})
+	if _, err := standby.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if cur := standby.Current(); cur.Height != 30 {
+		t.Fatalf("expected local checkpoint kept, got %d", cur.Height)
+	}
+}
+
+// 链上区块hash为h<高度>
+type hashBlockScanner struct {
+	*testBlockScanner
+	fork uint64 // 该高度的区块已被替换
+}
+
+func (s *hashBlockScanner) GetBlockHash(height uint64) (string, error) {
+	if height == s.fork {
+		return "x", nil
+	}
+	return fmt.Sprint("h", height), nil
+}
+
+func TestReconcileCheckpointHash(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		height uint64
+		fork   uint64
+		rescan uint64
+	}{
+		{"same hash", 100, 0, 101},
+		{"orphaned checkpoint", 100, 100, 91},
+		{"orphaned near genesis", 5, 5, 1},
+	} {
+		dir, remove := tempDir(t)
+		store := &FileCheckpointStore{Dir: dir}
+		if err := store.Save(&ScanCheckpoint{Symbol: "BTC", Height: c.height, Hash: fmt.Sprint("h", c.height)}); err != nil {
+			t.Fatal(err)
+		}
+		scanner := &hashBlockScanner{testBlockScanner: &testBlockScanner{}, fork: c.fork}
+		o := &OpenWScanner{Symbol: "BTC", BlockScanner: scanner, checkpoint: NewCheckpointer(store, "BTC", 10), reorg: NewReorgWindow(10)}
+		o.reconcileCheckpoint()
+		if scanner.rescan != c.rescan {
+			t.Fatalf("%s: expected rescan from %d, got %d", c.name, c.rescan, scanner.rescan)
+		}
+		remove()
+	}
+}
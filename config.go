@@ -0,0 +1,149 @@
+package open_scanner
+
+import (
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/util"
+	"strings"
+	"sync"
+)
+
+// 币种配置及原始ini内容,热加载时整体替换
+type coinConfig struct {
+	c   config.Configer
+	raw []byte
+}
+
+// 当前币种配置,未加载时返回nil
+func (o *OpenWScanner) currentConfig() config.Configer {
+	if v, ok := o.conf.Load().(*coinConfig); ok {
+		return v.c
+	}
+	return nil
+}
+
+// 当前币种配置的原始ini内容
+func (o *OpenWScanner) currentConfigRaw() []byte {
+	if v, ok := o.conf.Load().(*coinConfig); ok {
+		return v.raw
+	}
+	return nil
+}
+
+// 替换币种配置,扫块回调等并发读取方通过currentConfig读取
+func (o *OpenWScanner) setConfig(c config.Configer, raw []byte) {
+	o.conf.Store(&coinConfig{c: c, raw: raw})
+}
+
+// keyRecorder 记录读取过的配置项(小写,分组内为section::key),用于生成需重启生效的配置项
+type keyRecorder struct {
+	config.Configer
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+func newKeyRecorder(c config.Configer) *keyRecorder {
+	return &keyRecorder{Configer: c, keys: make(map[string]bool)}
+}
+
+func (r *keyRecorder) record(key string) {
+	r.mu.Lock()
+	r.keys[strings.ToLower(key)] = true
+	r.mu.Unlock()
+}
+
+// Keys 已读取的配置项
+func (r *keyRecorder) Keys() map[string]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make(map[string]bool, len(r.keys))
+	for k := range r.keys {
+		keys[k] = true
+	}
+	return keys
+}
+
+func (r *keyRecorder) String(key string) string {
+	r.record(key)
+	return r.Configer.String(key)
+}
+
+func (r *keyRecorder) Strings(key string) []string {
+	r.record(key)
+	return r.Configer.Strings(key)
+}
+
+func (r *keyRecorder) Int(key string) (int, error) {
+	r.record(key)
+	return r.Configer.Int(key)
+}
+
+func (r *keyRecorder) Int64(key string) (int64, error) {
+	r.record(key)
+	return r.Configer.Int64(key)
+}
+
+func (r *keyRecorder) Bool(key string) (bool, error) {
+	r.record(key)
+	return r.Configer.Bool(key)
+}
+
+func (r *keyRecorder) Float(key string) (float64, error) {
+	r.record(key)
+	return r.Configer.Float(key)
+}
+
+func (r *keyRecorder) DefaultString(key string, defaultVal string) string {
+	r.record(key)
+	return r.Configer.DefaultString(key, defaultVal)
+}
+
+func (r *keyRecorder) DefaultStrings(key string, defaultVal []string) []string {
+	r.record(key)
+	return r.Configer.DefaultStrings(key, defaultVal)
+}
+
+func (r *keyRecorder) DefaultInt(key string, defaultVal int) int {
+	r.record(key)
+	return r.Configer.DefaultInt(key, defaultVal)
+}
+
+func (r *keyRecorder) DefaultInt64(key string, defaultVal int64) int64 {
+	r.record(key)
+	return r.Configer.DefaultInt64(key, defaultVal)
+}
+
+func (r *keyRecorder) DefaultBool(key string, defaultVal bool) bool {
+	r.record(key)
+	return r.Configer.DefaultBool(key, defaultVal)
+}
+
+func (r *keyRecorder) DefaultFloat(key string, defaultVal float64) float64 {
+	r.record(key)
+	return r.Configer.DefaultFloat(key, defaultVal)
+}
+
+func (r *keyRecorder) DIY(key string) (interface{}, error) {
+	r.record(key)
+	return r.Configer.DIY(key)
+}
+
+// GetSection 读取整个分组时记录为section::*
+func (r *keyRecorder) GetSection(section string) (map[string]string, error) {
+	r.record(section + "::*")
+	return r.Configer.GetSection(section)
+}
+
+// 读取整数配置,未配置时返回默认值,无法解析或小于min时返回错误
+func configInt(c config.Configer, key string, def, min int64) (int64, error) {
+	if len(c.String(key)) == 0 {
+		return def, nil
+	}
+	v, err := c.Int64(key)
+	if err != nil {
+		return 0, util.Error("配置项[", key, "]不是整数: ", c.String(key))
+	}
+	if v < min {
+		return 0, util.Error("配置项[", key, "]不能小于", min, ": ", v)
+	}
+	return v, nil
+}
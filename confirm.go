@@ -0,0 +1,141 @@
+package open_scanner
+
+import (
+	"github.com/asdine/storm"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+)
+
+const (
+	confirmBatch = 100
+)
+
+// PendingTx 等待确认的交易单
+type PendingTx struct {
+	ID          string `storm:"id" json:"-"` // AccountID:txid
+	AppID       string `json:"appID"`
+	WalletID    string `json:"walletID"`
+	AccountID   string `json:"accountID"`
+	ContractID  string `json:"contractID"`
+	TxID        string `json:"txid"`
+	BlockHash   string `storm:"index" json:"blockHash"`
+	BlockHeight uint64 `storm:"index" json:"blockHeight"`
+	Ctime       int64  `json:"-"`
+}
+
+// TxConfirmed 交易单确认消息
+type TxConfirmed struct {
+	PendingTx
+	Confirmations uint64 `json:"confirmations"`
+}
+
+// ConfirmTracker 跟踪已发送的交易单,达到确认数后发送确认消息
+// 数据保存在发件箱文件中,重启后继续跟踪
+type ConfirmTracker struct {
+	node  storm.Node
+	depth uint64
+}
+
+func NewConfirmTracker(node storm.Node, depth uint64) *ConfirmTracker {
+	return &ConfirmTracker{node: node, depth: depth}
+}
+
+// 初始化确认跟踪,确认数优先读取币种ini(confirmations),否则使用OwSymbol.Confirm,不大于0时不跟踪
+func (o *OpenWScanner) initConfirmTracker(c config.Configer, confirm int64) {
+	if o.confirm != nil || o.outbox == nil {
+		return
+	}
+	confirm = c.DefaultInt64("confirmations", confirm)
+	if confirm <= 0 {
+		log2.Info("交易单确认跟踪未开启", 0, log2.String("symbol", o.Symbol))
+		return
+	}
+	o.confirm = NewConfirmTracker(o.outbox.db.From("confirm"), uint64(confirm))
+}
+
+// Depth 确认数
+func (t *ConfirmTracker) Depth() uint64 {
+	return t.depth
+}
+
+// Track 记录等待确认的交易单,同一交易单重新提取时覆盖
+func (t *ConfirmTracker) Track(tx PendingTx) error {
+	tx.ID = util.AddStr(tx.AccountID, ":", tx.TxID)
+	tx.Ctime = util.Time()
+	return t.node.Save(&tx)
+}
+
+// Due 在当前高度达到确认数的交易单,按BlockHeight索引从低到高读取,不遍历全部记录
+func (t *ConfirmTracker) Due(height uint64) ([]PendingTx, error) {
+	result := []PendingTx{}
+	if height+1 < t.depth {
+		return result, nil
+	}
+	max := height + 1 - t.depth
+	if err := t.node.Range("BlockHeight", uint64(0), max, &result, storm.Limit(confirmBatch)); err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Remove 删除已确认或已回滚的交易单,ID不保存在记录内容中,按AccountID/TxID重新生成
+func (t *ConfirmTracker) Remove(tx PendingTx) error {
+	tx.ID = util.AddStr(tx.AccountID, ":", tx.TxID)
+	return t.node.DeleteStruct(&tx)
+}
+
+// Rollback 删除被孤立区块中的交易单,重新提取后再次跟踪
+func (t *ConfirmTracker) Rollback(blocks []RollbackBlock) error {
+	for _, b := range blocks {
+		list := []PendingTx{}
+		if err := t.node.Find("BlockHash", b.Hash, &list); err != nil {
+			if err == storm.ErrNotFound {
+				continue
+			}
+			return err
+		}
+		for _, v := range list {
+			if err := t.Remove(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Pending 等待确认的交易单数
+func (t *ConfirmTracker) Pending() (int, error) {
+	return t.node.Count(&PendingTx{})
+}
+
+// 发送达到确认数的交易单确认消息,消息写入发件箱后删除跟踪记录
+func (o *OpenWScanner) notifyConfirmed(height uint64) {
+	for {
+		list, err := o.confirm.Due(height)
+		if err != nil {
+			log2.Error("读取待确认交易单失败", 0, log2.String("symbol", o.Symbol), log2.AddError(err))
+			return
+		}
+		for _, v := range list {
+			result := TxConfirmed{PendingTx: v, Confirmations: height - v.BlockHeight + 1}
+			ret, sig, err := o.Signer.Sign(result)
+			if err != nil {
+				log2.Warn(err.Error(), 0, log2.Any("content", result))
+				return
+			}
+			if err := o.publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + o.Symbol, Type: EventConfirmed, Content: ret, Signature: sig}); err != nil {
+				log2.Error("交易单确认数据发送MQ异常", 0, log2.String("symbol", o.Symbol), log2.Any("content", result), log2.AddError(err))
+				return
+			}
+			if err := o.confirm.Remove(v); err != nil {
+				log2.Error("删除待确认交易单失败", 0, log2.String("symbol", o.Symbol), log2.String("txid", v.TxID), log2.AddError(err))
+				return
+			}
+		}
+		if len(list) < confirmBatch {
+			return
+		}
+	}
+}
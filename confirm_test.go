@@ -0,0 +1,94 @@
+package open_scanner
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/asdine/storm"
+	"github.com/nbit99/open_scanner/event"
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+// 返回关闭数据库并删除文件的函数
+func newTestConfirmTracker(t *testing.T, depth uint64) (*ConfirmTracker, func()) {
+	dir, remove := tempDir(t)
+	db, err := storm.Open(filepath.Join(dir, "confirm.db"))
+	if err != nil {
+		remove()
+		t.Fatal(err)
+	}
+	return NewConfirmTracker(db.From("confirm"), depth), func() {
+		db.Close()
+		remove()
+	}
+}
+
+func TestConfirmTrackerDue(t *testing.T) {
+	tracker, closeTracker := newTestConfirmTracker(t, 6)
+	defer closeTracker()
+	// 高度按数值而不是字符串排序
+	for _, h := range []uint64{100, 9, 10, 95, 11} {
+		if err := tracker.Track(PendingTx{AccountID: "acc", TxID: "tx" + string(rune('a'+h%26)), BlockHeight: h}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := tracker.Due(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("expected no due txs below depth, got %d", len(list))
+	}
+	list, err = tracker.Due(15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	heights := make([]uint64, 0)
+	for _, v := range list {
+		heights = append(heights, v.BlockHeight)
+	}
+	if len(heights) != 2 || heights[0] != 9 || heights[1] != 10 {
+		t.Fatalf("unexpected due heights: %v", heights)
+	}
+	for _, v := range list {
+		if err := tracker.Remove(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, _ := tracker.Pending(); n != 3 {
+		t.Fatalf("expected 3 pending txs after remove, got %d", n)
+	}
+}
+
+func TestConfirmTrackerDueBatch(t *testing.T) {
+	tracker, closeTracker := newTestConfirmTracker(t, 1)
+	defer closeTracker()
+	for i := 1; i <= confirmBatch+10; i++ {
+		if err := tracker.Track(PendingTx{AccountID: "acc", TxID: string(rune(0x4e00 + i)), BlockHeight: uint64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := tracker.Due(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != confirmBatch || list[0].BlockHeight != 1 || list[confirmBatch-1].BlockHeight != confirmBatch {
+		t.Fatalf("unexpected batch: %d", len(list))
+	}
+}
+
+func TestTxPublishedTracksOnlyPublished(t *testing.T) {
+	tracker, closeTracker := newTestConfirmTracker(t, 6)
+	defer closeTracker()
+	o := &OpenWScanner{Symbol: "BTC", confirm: tracker}
+	owner := &txOwner{AppID: "app", WalletID: "w", AccountID: "acc"}
+	result := &event.Tx{Content: &openwallet.Transaction{TxID: "tx1", BlockHash: "h1", BlockHeight: 1}}
+	o.txPublished("acc", owner, result, false)
+	if n, _ := tracker.Pending(); n != 0 {
+		t.Fatalf("tracked unpublished tx: %d", n)
+	}
+	o.txPublished("acc", owner, result, true)
+	if n, _ := tracker.Pending(); n != 1 {
+		t.Fatalf("expected published tx tracked, got %d", n)
+	}
+}
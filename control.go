@@ -0,0 +1,277 @@
+package open_scanner
+
+import (
+	"encoding/json"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/nbit99/open_base/major"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	controlNode       = "scanner/state/"
+	controlWait       = 5 * time.Minute
+	controlMinBackoff = time.Second
+	controlMaxBackoff = time.Minute
+)
+
+// ScannerControl consul中的扫块器开关(scanner/state/<SYMBOL>),JSON格式,不存在或被删除时按币种BlockStop运行
+type ScannerControl struct {
+	Pause      bool   `json:"pause"`
+	Reason     string `json:"reason"`
+	UpdateTime int64  `json:"updateTime"`
+}
+
+// ControlWatcher 通过consul阻塞查询监听扫块器开关,变更后暂停/恢复扫块
+type ControlWatcher struct {
+	mu    sync.Mutex
+	o     *OpenWScanner
+	key   string
+	kv    controlKV
+	state *ScannerControl
+	done  chan struct{}
+	once  sync.Once
+}
+
+// 扫块器开关的读写,即consul KV
+type controlKV interface {
+	Get(key string, q *consulapi.QueryOptions) (*consulapi.KVPair, *consulapi.QueryMeta, error)
+	Put(p *consulapi.KVPair, q *consulapi.WriteOptions) (*consulapi.WriteMeta, error)
+}
+
+func NewControlWatcher(o *OpenWScanner) *ControlWatcher {
+	return &ControlWatcher{o: o, key: controlNode + strings.ToUpper(o.Symbol), done: make(chan struct{})}
+}
+
+// CheckBlockScannerState 启动扫块器开关监听,替代原mongo轮询
+func (o *OpenWScanner) CheckBlockScannerState() error {
+	if o.BlockScanner == nil {
+		return util.Error("[", o.Symbol, "]扫块器未启动")
+	}
+	if o.control != nil {
+		return nil
+	}
+	o.control = NewControlWatcher(o)
+	o.control.Start()
+	return nil
+}
+
+// State 当前开关状态,未配置时返回nil
+func (w *ControlWatcher) State() *ScannerControl {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == nil {
+		return nil
+	}
+	state := *w.state
+	return &state
+}
+
+// Set 写入开关状态,由监听生效
+func (w *ControlWatcher) Set(pause bool, reason string) error {
+	b, err := json.Marshal(&ScannerControl{Pause: pause, Reason: reason, UpdateTime: util.Time()})
+	if err != nil {
+		return err
+	}
+	_, err = w.kv.Put(&consulapi.KVPair{Key: w.key, Value: b}, nil)
+	return err
+}
+
+func (w *ControlWatcher) Start() {
+	if w.kv == nil {
+		w.kv = major.InitDc().Consulx.KV()
+	}
+	go w.watch()
+}
+
+func (w *ControlWatcher) Stop() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+func (w *ControlWatcher) watch() {
+	index := uint64(0)
+	backoff := controlMinBackoff
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+		pair, meta, err := w.kv.Get(w.key, &consulapi.QueryOptions{WaitIndex: index, WaitTime: controlWait})
+		if err != nil {
+			log2.Error("监听扫块器开关失败", 0, log2.String("key", w.key), log2.AddError(err))
+			select {
+			case <-w.done:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > controlMaxBackoff {
+				backoff = controlMaxBackoff
+			}
+			continue
+		}
+		backoff = controlMinBackoff
+		index = w.handle(index, pair, meta)
+	}
+}
+
+// 处理一次阻塞查询的结果,返回下次查询的索引
+func (w *ControlWatcher) handle(index uint64, pair *consulapi.KVPair, meta *consulapi.QueryMeta) uint64 {
+	// 索引回退时(consul重建等)重新开始
+	if meta.LastIndex < index {
+		return 0
+	}
+	if meta.LastIndex == index {
+		return index
+	}
+	if pair == nil || len(pair.Value) == 0 {
+		w.reset()
+		return meta.LastIndex
+	}
+	state, err := parseScannerControl(pair.Value)
+	if err != nil {
+		log2.Error("扫块器开关格式错误", 0, log2.String("key", w.key), log2.String("value", string(pair.Value)), log2.AddError(err))
+		return meta.LastIndex
+	}
+	w.apply(state)
+	return meta.LastIndex
+}
+
+// 兼容直接写入pause/run
+func parseScannerControl(b []byte) (*ScannerControl, error) {
+	switch strings.ToLower(strings.TrimSpace(string(b))) {
+	case "pause", "off", "0":
+		return &ScannerControl{Pause: true}, nil
+	case "run", "on", "1":
+		return &ScannerControl{}, nil
+	}
+	state := &ScannerControl{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// 开关不存在或被删除时按币种BlockStop暂停/恢复,状态为未配置
+func (w *ControlWatcher) reset() {
+	reason := "扫块器开关未配置,按BlockStop运行"
+	w.apply(&ScannerControl{Pause: w.o.blockStop, Reason: reason})
+	w.setState(nil)
+}
+
+func (w *ControlWatcher) setState(state *ScannerControl) {
+	w.mu.Lock()
+	w.state = state
+	w.mu.Unlock()
+}
+
+// 开关变更后暂停/恢复扫块
+func (w *ControlWatcher) apply(state *ScannerControl) {
+	prev := w.State()
+	w.setState(state)
+	if prev != nil && prev.Pause == state.Pause {
+		return
+	}
+	scanner := w.o.BlockScanner
+	running, closed := scannerRunning(scanner)
+	if closed || running != state.Pause {
+		return
+	}
+	// 备用实例只记录开关,获得主节点后按开关启动
+	if !state.Pause && w.o.CheckLeader() != nil {
+		return
+	}
+	var err error
+	if state.Pause {
+		err = scanner.Pause()
+		log2.Warn("扫块器已暂停", 0, log2.String("symbol", w.o.Symbol), log2.String("reason", state.Reason))
+	} else {
+		err = w.o.resumeScanner()
+		log2.Warn("扫块器已恢复", 0, log2.String("symbol", w.o.Symbol), log2.String("reason", state.Reason))
+	}
+	if err != nil {
+		log2.Error("切换扫块器开关失败", 0, log2.String("symbol", w.o.Symbol), log2.AddError(err))
+	}
+}
+
+// 启动或恢复扫块,未Run过时调用Run,之后调用Restart
+func (o *OpenWScanner) resumeScanner() error {
+	o.scanMu.Lock()
+	defer o.scanMu.Unlock()
+	if o.scanStarted {
+		return o.BlockScanner.Restart()
+	}
+	if err := o.BlockScanner.Run(); err != nil {
+		return err
+	}
+	o.scanStarted = true
+	return nil
+}
+
+// 暂停扫块后等待当前区块扫描完成
+var rescanHeightWait = 30 * time.Second
+
+// RescanHeight 暂停扫块后设置扫描高度,按开关状态恢复,未配置开关时恢复至调用前的状态
+func (o *OpenWScanner) RescanHeight(height uint64) error {
+	if o.BlockScanner == nil {
+		return util.Error("[", o.Symbol, "]扫块器未启动")
+	}
+	if err := o.CheckLeader(); err != nil {
+		return err
+	}
+	run, _ := scannerRunning(o.BlockScanner)
+	if o.control != nil {
+		if state := o.control.State(); state != nil {
+			run = !state.Pause
+		}
+	}
+	if err := o.BlockScanner.Pause(); err != nil {
+		return err
+	}
+	time.Sleep(rescanHeightWait)
+	if err := o.BlockScanner.SetRescanBlockHeight(height); err != nil {
+		return err
+	}
+	// 等待期间失去主节点时保持暂停
+	if !run || o.CheckLeader() != nil {
+		return nil
+	}
+	return o.resumeScanner()
+}
+
+// ScannerStatus 扫块器状态,control为consul中的开关,未配置时为nil
+type ScannerStatus struct {
+	ScannerState
+	Control *ScannerControl `json:"control"`
+}
+
+// Status 扫块器当前状态
+func (o *OpenWScanner) Status() ScannerStatus {
+	status := ScannerStatus{ScannerState: o.scannerState()}
+	if o.control != nil {
+		status.Control = o.control.State()
+	}
+	return status
+}
+
+// SetPause 暂停/恢复扫块,已启动开关监听时写入consul,否则直接切换
+func (o *OpenWScanner) SetPause(pause bool, reason string) error {
+	if o.BlockScanner == nil {
+		return util.Error("[", o.Symbol, "]扫块器未启动")
+	}
+	if o.control != nil {
+		return o.control.Set(pause, reason)
+	}
+	if pause {
+		return o.BlockScanner.Pause()
+	}
+	if err := o.CheckLeader(); err != nil {
+		return err
+	}
+	return o.resumeScanner()
+}
@@ -0,0 +1,138 @@
+package open_scanner
+
+import (
+	"reflect"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// 内存中的扫块器开关
+type testControlKV struct {
+	pairs map[string]*consulapi.KVPair
+}
+
+func (kv *testControlKV) Get(key string, q *consulapi.QueryOptions) (*consulapi.KVPair, *consulapi.QueryMeta, error) {
+	return kv.pairs[key], &consulapi.QueryMeta{}, nil
+}
+
+func (kv *testControlKV) Put(p *consulapi.KVPair, q *consulapi.WriteOptions) (*consulapi.WriteMeta, error) {
+	kv.pairs[p.Key] = p
+	return &consulapi.WriteMeta{}, nil
+}
+
+func newTestControl(scanner *testBlockScanner, blockStop bool) *ControlWatcher {
+	o := &OpenWScanner{Symbol: "BTC", BlockScanner: scanner, blockStop: blockStop}
+	o.control = NewControlWatcher(o)
+	o.control.kv = &testControlKV{pairs: make(map[string]*consulapi.KVPair)}
+	return o.control
+}
+
+func controlPair(value string) *consulapi.KVPair {
+	return &consulapi.KVPair{Key: controlNode + "BTC", Value: []byte(value)}
+}
+
+func TestControlWatcherIndex(t *testing.T) {
+	scanner := &testBlockScanner{Scanning: true}
+	w := newTestControl(scanner, false)
+	if index := w.handle(0, controlPair("pause"), &consulapi.QueryMeta{LastIndex: 5}); index != 5 || !w.State().Pause {
+		t.Fatalf("unexpected index %d state %+v", index, w.State())
+	}
+	// 索引未变化时阻塞查询超时,不处理结果
+	if index := w.handle(5, controlPair("run"), &consulapi.QueryMeta{LastIndex: 5}); index != 5 || !w.State().Pause {
+		t.Fatalf("unexpected index %d state %+v", index, w.State())
+	}
+	// 索引回退时重新开始
+	if index := w.handle(5, controlPair("run"), &consulapi.QueryMeta{LastIndex: 3}); index != 0 || !w.State().Pause {
+		t.Fatalf("unexpected index %d state %+v", index, w.State())
+	}
+	// 格式错误时保持原状态
+	if index := w.handle(0, controlPair("{"), &consulapi.QueryMeta{LastIndex: 6}); index != 6 || !w.State().Pause {
+		t.Fatalf("unexpected index %d state %+v", index, w.State())
+	}
+	if index := w.handle(6, controlPair(`{"pause":false,"reason":"ok"}`), &consulapi.QueryMeta{LastIndex: 7}); index != 7 || w.State().Pause {
+		t.Fatalf("unexpected index %d state %+v", index, w.State())
+	}
+	if !reflect.DeepEqual(scanner.calls, []string{"pause", "run"}) {
+		t.Fatalf("unexpected scanner calls %v", scanner.calls)
+	}
+}
+
+func TestControlWatcherReset(t *testing.T) {
+	for _, blockStop := range []bool{true, false} {
+		scanner := &testBlockScanner{Scanning: !blockStop}
+		w := newTestControl(scanner, blockStop)
+		value := "pause"
+		if blockStop {
+			value = "run"
+		}
+		w.handle(0, controlPair(value), &consulapi.QueryMeta{LastIndex: 1})
+		if scanner.Scanning != blockStop {
+			t.Fatalf("blockStop %v: control %s not applied", blockStop, value)
+		}
+		// 开关被删除后按BlockStop运行,状态为未配置
+		w.handle(1, nil, &consulapi.QueryMeta{LastIndex: 2})
+		if w.State() != nil {
+			t.Fatalf("blockStop %v: expected state reset, got %+v", blockStop, w.State())
+		}
+		if scanner.Scanning == blockStop {
+			t.Fatalf("blockStop %v: unexpected scanning %v after reset", blockStop, scanner.Scanning)
+		}
+	}
+}
+
+func TestControlWatcherStandby(t *testing.T) {
+	scanner := &testBlockScanner{}
+	w := newTestControl(scanner, false)
+	w.o.leader = NewLeaderElector(w.o, false)
+	// 备用实例只记录开关,不启动扫块
+	w.apply(&ScannerControl{})
+	if scanner.Scanning || len(scanner.calls) != 0 || w.State() == nil || w.State().Pause {
+		t.Fatalf("unexpected standby resume: %v %+v", scanner.calls, w.State())
+	}
+	w.o.leader.setLeader(true)
+	w.apply(&ScannerControl{Pause: true})
+	w.apply(&ScannerControl{})
+	w.apply(&ScannerControl{Pause: true})
+	w.apply(&ScannerControl{})
+	// 首次启动调用Run,之后调用Restart
+	if !reflect.DeepEqual(scanner.calls, []string{"run", "pause", "restart"}) {
+		t.Fatalf("unexpected scanner calls %v", scanner.calls)
+	}
+}
+
+func TestSetPause(t *testing.T) {
+	// 已启动开关监听时写入consul,由监听生效
+	scanner := &testBlockScanner{Scanning: true}
+	w := newTestControl(scanner, false)
+	if err := w.o.SetPause(true, "maintain"); err != nil {
+		t.Fatal(err)
+	}
+	pair, meta, _ := w.kv.Get(w.key, nil)
+	if pair == nil || !scanner.Scanning {
+		t.Fatalf("unexpected control %v scanning %v", pair, scanner.Scanning)
+	}
+	meta.LastIndex = 1
+	w.handle(0, pair, meta)
+	if !reflect.DeepEqual(scanner.calls, []string{"pause"}) || w.State().Reason != "maintain" {
+		t.Fatalf("unexpected scanner calls %v state %+v", scanner.calls, w.State())
+	}
+
+	// 未启动开关监听时直接切换,备用实例不能启动扫块
+	scanner = &testBlockScanner{}
+	o := &OpenWScanner{Symbol: "BTC", BlockScanner: scanner}
+	o.leader = NewLeaderElector(o, false)
+	if err := o.SetPause(false, ""); err == nil || scanner.Scanning {
+		t.Fatal("expected standby resume rejected")
+	}
+	o.leader.setLeader(true)
+	if err := o.SetPause(false, ""); err != nil || !scanner.Scanning {
+		t.Fatalf("expected scanner resumed: %v", err)
+	}
+	if err := o.SetPause(true, ""); err != nil || scanner.Scanning {
+		t.Fatalf("expected scanner paused: %v", err)
+	}
+	if err := (&OpenWScanner{Symbol: "BTC"}).SetPause(true, ""); err == nil {
+		t.Fatal("expected error without scanner")
+	}
+}
@@ -0,0 +1,155 @@
+package open_scanner
+
+import (
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/astaxie/beego/config"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	"sync"
+)
+
+const (
+	DedupeOff      = "off"      // 不去重
+	DedupeSuppress = "suppress" // 已发送的交易单不再发送
+	DedupeFlag     = "flag"     // 已发送的交易单标记redelivery后发送
+
+	dedupeKeepBlocks = 10000
+	dedupePruneEvery = 100
+)
+
+// DeliveredTx 已发送的交易单
+type DeliveredTx struct {
+	ID          string `storm:"id"`
+	BlockHeight uint64 `storm:"index"`
+	Ctime       int64
+}
+
+// DedupeLedger 交易单发送记录,按(symbol, txid, sourceKey, blockHash)去重
+// 数据保存在发件箱文件中,只保留最近keep个区块的记录
+type DedupeLedger struct {
+	mu     sync.Mutex
+	node   storm.Node
+	mode   string
+	keep   uint64
+	pruned uint64
+	seq    int64
+	force  map[int64]*forceRange
+}
+
+// 强制重新发送的高度区间
+type forceRange struct {
+	from uint64
+	to   uint64
+	auto bool // 扫描超过to后自动移除
+}
+
+func NewDedupeLedger(node storm.Node, mode string, keep uint64) *DedupeLedger {
+	if keep == 0 {
+		keep = dedupeKeepBlocks
+	}
+	return &DedupeLedger{node: node, mode: mode, keep: keep, force: make(map[int64]*forceRange)}
+}
+
+// 按币种配置初始化去重: dedupe = off|suppress|flag(默认off), dedupeKeep = 保留区块数
+// flag模式下重新发送的交易单redelivery为true,消费方需兼容该字段后再开启
+func (o *OpenWScanner) initDedupeLedger(c config.Configer) {
+	if o.dedupe != nil || o.outbox == nil {
+		return
+	}
+	mode := c.DefaultString("dedupe", DedupeOff)
+	if mode != DedupeSuppress && mode != DedupeFlag {
+		if mode != DedupeOff {
+			log2.Warn("去重模式不支持,不去重", 0, log2.String("symbol", o.Symbol), log2.String("dedupe", mode))
+		}
+		return
+	}
+	o.dedupe = NewDedupeLedger(o.outbox.db.From("dedupe"), mode, uint64(c.DefaultInt64("dedupeKeep", dedupeKeepBlocks)))
+}
+
+func dedupeKey(symbol, txid, sourceKey, blockHash string) string {
+	return util.MD5(util.AddStr(symbol, "|", txid, "|", sourceKey, "|", blockHash))
+}
+
+// Mode 去重模式
+func (d *DedupeLedger) Mode() string {
+	return d.mode
+}
+
+// Delivered 交易单是否已发送
+func (d *DedupeLedger) Delivered(symbol, txid, sourceKey, blockHash string) (bool, error) {
+	tx := DeliveredTx{}
+	if err := d.node.One("ID", dedupeKey(symbol, txid, sourceKey, blockHash), &tx); err != nil {
+		if err == storm.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Mark 记录已发送的交易单
+func (d *DedupeLedger) Mark(symbol, txid, sourceKey, blockHash string, height uint64) error {
+	return d.node.Save(&DeliveredTx{ID: dedupeKey(symbol, txid, sourceKey, blockHash), BlockHeight: height, Ctime: util.Time()})
+}
+
+// Force 区间内的交易单强制重新发送,返回区间ID用于Release
+func (d *DedupeLedger) Force(from, to uint64, auto bool) int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seq++
+	d.force[d.seq] = &forceRange{from: from, to: to, auto: auto}
+	return d.seq
+}
+
+// Release 移除强制重新发送区间
+func (d *DedupeLedger) Release(id int64) {
+	d.mu.Lock()
+	delete(d.force, id)
+	d.mu.Unlock()
+}
+
+// Forced 高度是否处于强制重新发送区间
+func (d *DedupeLedger) Forced(height uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, v := range d.force {
+		if height >= v.from && height <= v.to {
+			return true
+		}
+	}
+	return false
+}
+
+// Advance 新区块扫描完成,移除已扫描完成的自动区间并定期清理过期记录
+func (d *DedupeLedger) Advance(height uint64) {
+	d.mu.Lock()
+	for k, v := range d.force {
+		if v.auto && height > v.to {
+			delete(d.force, k)
+		}
+	}
+	prune := height >= d.pruned+dedupePruneEvery && height > d.keep
+	if prune {
+		d.pruned = height
+	}
+	d.mu.Unlock()
+	if !prune {
+		return
+	}
+	if err := d.node.Select(q.Lt("BlockHeight", height-d.keep)).Delete(&DeliveredTx{}); err != nil && err != storm.ErrNotFound {
+		log2.Error("清理交易单发送记录失败", 0, log2.Uint64("height", height), log2.AddError(err))
+	}
+}
+
+// ForceRepublish 强制重新发送区间内的交易单,auto为true时扫描超过to后自动移除,返回移除函数
+func (o *OpenWScanner) ForceRepublish(from, to uint64, auto bool) func() {
+	if o.dedupe == nil {
+		return func() {}
+	}
+	id := o.dedupe.Force(from, to, auto)
+	log2.Info("强制重新发送交易单", 0, log2.String("symbol", o.Symbol), log2.Uint64("from", from), log2.Uint64("to", to))
+	return func() {
+		o.dedupe.Release(id)
+	}
+}
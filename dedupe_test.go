@@ -0,0 +1,108 @@
+package open_scanner
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdine/storm"
+	"github.com/nbit99/open_scanner/event"
+)
+
+// 返回关闭数据库并删除文件的函数
+func newTestDedupe(t *testing.T, mode string, keep uint64) (*DedupeLedger, func()) {
+	dir, remove := tempDir(t)
+	db, err := storm.Open(filepath.Join(dir, "dedupe.db"))
+	if err != nil {
+		remove()
+		t.Fatal(err)
+	}
+	return NewDedupeLedger(db.From("dedupe"), mode, keep), func() {
+		db.Close()
+		remove()
+	}
+}
+
+// 提取交易单并按发送成功记录,返回交易单消息,被忽略时返回nil
+func extractTx(t *testing.T, o *OpenWScanner, height uint64) *event.Tx {
+	owner := &txOwner{AppID: "app", WalletID: "w", AccountID: "acc"}
+	result, err := o.newTxEvent("acc", testExtractData("tx1", "h", height), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != nil {
+		o.txPublished("acc", owner, result, true)
+	}
+	return result
+}
+
+func TestDedupeLedgerModes(t *testing.T) {
+	for _, mode := range []string{DedupeSuppress, DedupeFlag} {
+		ledger, closeLedger := newTestDedupe(t, mode, 0)
+		o := &OpenWScanner{Symbol: "BTC", dedupe: ledger}
+		if tx := extractTx(t, o, 100); tx == nil || tx.Redelivery {
+			t.Fatalf("%s: expected first delivery, got %+v", mode, tx)
+		}
+		tx := extractTx(t, o, 100)
+		if mode == DedupeSuppress && tx != nil {
+			t.Fatalf("%s: expected suppressed tx", mode)
+		}
+		if mode == DedupeFlag && (tx == nil || !tx.Redelivery) {
+			t.Fatalf("%s: expected redelivery flag, got %+v", mode, tx)
+		}
+		// 强制重新发送区间内不去重
+		release := o.ForceRepublish(90, 110, false)
+		if tx := extractTx(t, o, 100); tx == nil || tx.Redelivery {
+			t.Fatalf("%s: expected forced delivery, got %+v", mode, tx)
+		}
+		release()
+		if o.dedupe.Forced(100) {
+			t.Fatalf("%s: expected range released", mode)
+		}
+		closeLedger()
+	}
+}
+
+func TestDedupeLedgerAdvance(t *testing.T) {
+	ledger, closeLedger := newTestDedupe(t, DedupeSuppress, 100)
+	defer closeLedger()
+	auto := ledger.Force(10, 20, true)
+	manual := ledger.Force(10, 20, false)
+	ledger.Advance(21)
+	if !ledger.Forced(15) {
+		t.Fatal("expected manual range kept")
+	}
+	ledger.Release(manual)
+	if ledger.Forced(15) {
+		t.Fatal("expected auto range removed after scanning past it")
+	}
+	ledger.Release(auto)
+
+	// 超过保留区块数的记录定期清理
+	for _, h := range []uint64{1, 150} {
+		if err := ledger.Mark("BTC", fmt.Sprint("tx", h), "acc", fmt.Sprint("h", h), h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ledger.Advance(250)
+	if ok, err := ledger.Delivered("BTC", "tx1", "acc", "h1"); err != nil || ok {
+		t.Fatalf("expected old record pruned, got %v (%v)", ok, err)
+	}
+	if ok, err := ledger.Delivered("BTC", "tx150", "acc", "h150"); err != nil || !ok {
+		t.Fatalf("expected recent record kept, got %v (%v)", ok, err)
+	}
+}
+
+func TestInitDedupeLedgerDefaultOff(t *testing.T) {
+	box, closeBox := newTestOutbox(t)
+	defer closeBox()
+	o := &OpenWScanner{Symbol: "BTC", outbox: box}
+	o.initDedupeLedger(newTestConfig(t, "dataDir = /data/btc\n"))
+	if o.dedupe != nil {
+		t.Fatal("expected dedupe off by default")
+	}
+	o.initDedupeLedger(newTestConfig(t, "dedupe = flag\n"))
+	if o.dedupe == nil || o.dedupe.Mode() != DedupeFlag {
+		t.Fatal("expected flag mode")
+	}
+}
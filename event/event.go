@@ -0,0 +1,154 @@
+// Package event 扫块服务发送的MQ/Webhook/Kafka消息结构,消费方引用本包解析消息内容
+package event
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+// SchemaVersion 当前消息结构版本,新增字段不升级,删除/修改字段时升级
+// 未包含schemaVersion的消息为版本0(升级前发送),结构与版本1相同
+const SchemaVersion = 1
+
+// 消息类型,对应MsgData.Type
+const (
+	TypeBlock     int64 = 1 // 区块
+	TypeTx        int64 = 2 // 交易单
+	TypeReceipt   int64 = 3 // 智能合约交易回执
+	TypeRollback  int64 = 4 // 区块回滚
+	TypeConfirmed int64 = 5 // 交易单达到确认数
+	TypeAlert     int64 = 6 // 扫块延迟告警
+	TypeRecovery  int64 = 7 // 扫块延迟恢复
+	TypeTxBatch   int64 = 8 // 同一区块同一应用的交易单批量消息
+)
+
+// DataTypeTx 交易单消息的dataType
+const DataTypeTx int64 = 2
+
+// Block 区块消息,区块头字段与openwallet.BlockHeader一致
+type Block struct {
+	SchemaVersion int `json:"schemaVersion"`
+	openwallet.BlockHeader
+}
+
+// Tx 交易单消息,普通交易单的appID/walletID/accountID为资产账户,合约交易单均为合约ID
+type Tx struct {
+	SchemaVersion int                     `json:"schemaVersion"`
+	AppID         string                  `json:"appID"`
+	WalletID      string                  `json:"walletID"`
+	AccountID     string                  `json:"accountID"`
+	DataType      int64                   `json:"dataType"`
+	Content       *openwallet.Transaction `json:"content"`
+	Inputs        []*openwallet.TxInput   `json:"inputs"`
+	Outputs       []*openwallet.TxOutPut  `json:"outputs"`
+	ContractID    string                  `json:"contractID"`
+	Redelivery    bool                    `json:"redelivery,omitempty"` // 已发送过的交易单重新发送
+}
+
+// Receipt 智能合约交易回执消息,回执字段与openwallet.SmartContractReceipt一致
+type Receipt struct {
+	SchemaVersion int `json:"schemaVersion"`
+	openwallet.SmartContractReceipt
+}
+
+// TxBatch 交易单批量消息,txs按区块内提取顺序排列,合约交易单的appID为合约ID
+type TxBatch struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	AppID         string `json:"appID"`
+	BlockHash     string `json:"blockHash"`
+	BlockHeight   uint64 `json:"blockHeight"`
+	Txs           []*Tx  `json:"txs"`
+}
+
+func NewBlock(header *openwallet.BlockHeader) *Block {
+	return &Block{SchemaVersion: SchemaVersion, BlockHeader: *header}
+}
+
+func NewReceipt(receipt *openwallet.SmartContractReceipt) *Receipt {
+	return &Receipt{SchemaVersion: SchemaVersion, SmartContractReceipt: *receipt}
+}
+
+// Unmarshal 解析消息内容(base64url编码的JSON),签名由发送方Verifier校验
+func Unmarshal(content string, v interface{}) error {
+	b, err := base64.URLEncoding.DecodeString(content)
+	if err != nil {
+		return fmt.Errorf("event: content base64 decode failed: %v", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("event: content json decode failed: %v", err)
+	}
+	return nil
+}
+
+// Decode 按消息类型解析区块/交易单/合约回执/交易单批量消息,返回*Block/*Tx/*Receipt/*TxBatch
+// 消息版本高于当前版本时返回错误,消费方需升级本包
+func Decode(typ int64, content string) (interface{}, error) {
+	var v interface{}
+	switch typ {
+	case TypeBlock:
+		v = &Block{}
+	case TypeTx:
+		v = &Tx{}
+	case TypeReceipt:
+		v = &Receipt{}
+	case TypeTxBatch:
+		v = &TxBatch{}
+	default:
+		return nil, fmt.Errorf("event: type %d not supported", typ)
+	}
+	if err := Unmarshal(content, v); err != nil {
+		return nil, err
+	}
+	if version := versionOf(v); version > SchemaVersion {
+		return nil, fmt.Errorf("event: schema version %d is newer than %d", version, SchemaVersion)
+	}
+	return v, nil
+}
+
+func DecodeBlock(content string) (*Block, error) {
+	v, err := Decode(TypeBlock, content)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Block), nil
+}
+
+func DecodeTx(content string) (*Tx, error) {
+	v, err := Decode(TypeTx, content)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Tx), nil
+}
+
+func DecodeReceipt(content string) (*Receipt, error) {
+	v, err := Decode(TypeReceipt, content)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Receipt), nil
+}
+
+func DecodeTxBatch(content string) (*TxBatch, error) {
+	v, err := Decode(TypeTxBatch, content)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*TxBatch), nil
+}
+
+func versionOf(v interface{}) int {
+	switch e := v.(type) {
+	case *Block:
+		return e.SchemaVersion
+	case *Tx:
+		return e.SchemaVersion
+	case *Receipt:
+		return e.SchemaVersion
+	case *TxBatch:
+		return e.SchemaVersion
+	}
+	return 0
+}
@@ -0,0 +1,230 @@
+package event
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+var testCoin = openwallet.Coin{Symbol: "ETH", IsContract: true, ContractID: "c1", Contract: openwallet.SmartContract{ContractID: "c1", Symbol: "ETH", Address: "0xc", Token: "USDT", Decimals: 6}}
+
+func testBlock() *Block {
+	return NewBlock(&openwallet.BlockHeader{Hash: "h100", Confirmations: 1, Merkleroot: "m", Previousblockhash: "h99", Height: 100, Version: 2, Time: 1600000000, Symbol: "ETH"})
+}
+
+func testTx() *Tx {
+	return &Tx{
+		SchemaVersion: SchemaVersion,
+		AppID:         "app",
+		WalletID:      "w",
+		AccountID:     "acc",
+		DataType:      DataTypeTx,
+		Content: &openwallet.Transaction{WxID: "wx1", TxID: "tx1", Coin: testCoin, From: []string{"a:1"}, To: []string{"b:1"}, Amount: "-1.5", Decimal: 6,
+			TxType: 1, TxAction: "Transfer", BlockHash: "h100", BlockHeight: 100, Fees: "0.01", SubmitTime: 1600000000, ConfirmTime: 1600000001, Status: "1", ExtParam: "{}"},
+		Inputs:     []*openwallet.TxInput{{SourceTxID: "s0", SourceIndex: 1, Recharge: openwallet.Recharge{Sid: "i1", TxID: "tx1", Address: "a", Coin: testCoin, Amount: "1.5", BlockHash: "h100", BlockHeight: 100}}},
+		Outputs:    []*openwallet.TxOutPut{{ExtParam: "x", Recharge: openwallet.Recharge{Sid: "o1", TxID: "tx1", Address: "b", Coin: testCoin, Amount: "1.5", BlockHash: "h100", BlockHeight: 100, Index: 1}}},
+		ContractID: "c1",
+		Redelivery: true,
+	}
+}
+
+func testReceipt() *Receipt {
+	return NewReceipt(&openwallet.SmartContractReceipt{Coin: testCoin, WxID: "wx1", TxID: "tx1", From: "a", To: "0xc", Value: "0", Fees: "0.01", RawReceipt: "{}",
+		Events: []*openwallet.SmartContractEvent{{Contract: &testCoin.Contract, Event: "Transfer", Value: "{}"}}, BlockHash: "h100", BlockHeight: 100, ConfirmTime: 1600000001, Status: "1"})
+}
+
+func testTxBatch() *TxBatch {
+	return &TxBatch{SchemaVersion: SchemaVersion, AppID: "app", BlockHash: "h100", BlockHeight: 100, Txs: []*Tx{testTx(), testTx()}}
+}
+
+func testEvents() map[int64]interface{} {
+	return map[int64]interface{}{TypeBlock: testBlock(), TypeTx: testTx(), TypeReceipt: testReceipt(), TypeTxBatch: testTxBatch()}
+}
+
+// 与扫块服务签名前的编码相同: JSON后base64url
+func encodeContent(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	for typ, v := range testEvents() {
+		decoded, err := Decode(typ, encodeContent(t, v))
+		if err != nil {
+			t.Fatalf("type %d: %v", typ, err)
+		}
+		if !bytes.Equal(mustJSON(t, decoded), mustJSON(t, v)) {
+			t.Fatalf("type %d: round trip mismatch\n%s\n%s", typ, mustJSON(t, decoded), mustJSON(t, v))
+		}
+	}
+	tx, err := DecodeTx(encodeContent(t, testTx()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Content.TxID != "tx1" || tx.Inputs[0].SourceTxID != "s0" || tx.Outputs[0].Index != 1 || !tx.Redelivery {
+		t.Fatalf("unexpected tx: %+v", tx)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := Decode(TypeRollback, encodeContent(t, testBlock())); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+	if _, err := Decode(TypeBlock, "!"); err == nil {
+		t.Fatal("expected base64 error")
+	}
+	block := testBlock()
+	block.SchemaVersion = SchemaVersion + 1
+	if _, err := DecodeBlock(encodeContent(t, block)); err == nil {
+		t.Fatal("expected error for newer schema version")
+	}
+	// 版本0(升级前发送)的消息
+	block.SchemaVersion = 0
+	if _, err := DecodeBlock(encodeContent(t, block)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var schemaNames = map[int64]string{TypeBlock: "block", TypeTx: "tx", TypeReceipt: "receipt", TypeTxBatch: "txbatch"}
+
+func TestSchemaValidatesMessages(t *testing.T) {
+	for typ, v := range testEvents() {
+		var doc interface{}
+		if err := json.Unmarshal(mustJSON(t, v), &doc); err != nil {
+			t.Fatal(err)
+		}
+		root := loadSchema(t, schemaNames[typ])
+		if err := validateSchema(t, root, root, doc, schemaNames[typ]); err != "" {
+			t.Fatalf("type %d: %s", typ, err)
+		}
+	}
+	// 缺少必填字段
+	var doc map[string]interface{}
+	json.Unmarshal(mustJSON(t, testTx()), &doc)
+	delete(doc, "accountID")
+	root := loadSchema(t, "tx")
+	if err := validateSchema(t, root, root, doc, "tx"); err == "" {
+		t.Fatal("expected missing accountID to fail")
+	}
+}
+
+func TestSchemaHandler(t *testing.T) {
+	handler := SchemaHandler("/schema/")
+	for name, schema := range Schemas {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/schema/"+name+".json", nil))
+		if w.Code != http.StatusOK || w.Body.String() != schema || w.Header().Get("Content-Type") != "application/schema+json" {
+			t.Fatalf("%s: unexpected response %d", name, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/schema/unknown.json", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func loadSchema(t *testing.T, name string) map[string]interface{} {
+	schema := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(Schemas[name]), &schema); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return schema
+}
+
+// 校验消息使用到的JSON Schema子集: type/const/required/properties/items/$ref,返回第一个错误
+func validateSchema(t *testing.T, root, schema map[string]interface{}, v interface{}, path string) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		if strings.HasPrefix(ref, "#/definitions/") {
+			return validateSchema(t, root, root["definitions"].(map[string]interface{})[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{}), v, path)
+		}
+		next := loadSchema(t, strings.TrimSuffix(ref, ".json"))
+		return validateSchema(t, next, next, v, path)
+	}
+	if types, ok := schema["type"]; ok && !matchType(types, v) {
+		return path + ": unexpected type"
+	}
+	if c, ok := schema["const"]; ok && c != v {
+		return path + ": unexpected const value"
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, k := range required {
+				if _, ok := value[k.(string)]; !ok {
+					return path + "." + k.(string) + ": required"
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for k, p := range props {
+			if pv, ok := value[k]; ok {
+				if err := validateSchema(t, root, p.(map[string]interface{}), pv, path+"."+k); err != "" {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for _, item := range value {
+				if err := validateSchema(t, root, items, item, path+"[]"); err != "" {
+					return err
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func matchType(types interface{}, v interface{}) bool {
+	list, ok := types.([]interface{})
+	if !ok {
+		list = []interface{}{types}
+	}
+	for _, typ := range list {
+		switch typ {
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := v.(float64); ok && f == float64(int64(f)) {
+				return true
+			}
+		case "null":
+			if v == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
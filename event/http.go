@@ -0,0 +1,20 @@
+package event
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SchemaHandler 按<prefix><name>.json返回JSON Schema,如prefix为/schema/时/schema/tx.json返回TxSchema
+func SchemaHandler(prefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix), ".json")
+		schema, ok := Schemas[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write([]byte(schema))
+	}
+}
@@ -0,0 +1,378 @@
+package event
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/nbit99/open_scanner/event/eventpb"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	"google.golang.org/protobuf/proto"
+)
+
+//go:generate protoc --go_out=eventpb --go_opt=paths=source_relative event.proto
+
+// Protobuf编解码,消息结构由event.proto生成(eventpb),与JSON消息结构逐字段转换,未知字段解码时忽略
+
+// MarshalProto 将*Block/*Tx/*Receipt/*TxBatch编码为protobuf
+func MarshalProto(v interface{}) ([]byte, error) {
+	var m proto.Message
+	switch e := v.(type) {
+	case *Block:
+		m = e.toProto()
+	case *Tx:
+		m = e.toProto()
+	case *Receipt:
+		m = e.toProto()
+	case *TxBatch:
+		m = e.toProto()
+	default:
+		return nil, fmt.Errorf("event: %T not supported", v)
+	}
+	return proto.Marshal(m)
+}
+
+// DecodeProto 按消息类型解析protobuf消息内容(base64url编码),返回*Block/*Tx/*Receipt/*TxBatch
+func DecodeProto(typ int64, content string) (interface{}, error) {
+	b, err := base64.URLEncoding.DecodeString(content)
+	if err != nil {
+		return nil, fmt.Errorf("event: content base64 decode failed: %v", err)
+	}
+	var v interface{}
+	switch typ {
+	case TypeBlock:
+		m := &eventpb.Block{}
+		if err = proto.Unmarshal(b, m); err == nil {
+			v = blockFromProto(m)
+		}
+	case TypeTx:
+		m := &eventpb.Tx{}
+		if err = proto.Unmarshal(b, m); err == nil {
+			v = txFromProto(m)
+		}
+	case TypeReceipt:
+		m := &eventpb.Receipt{}
+		if err = proto.Unmarshal(b, m); err == nil {
+			v = receiptFromProto(m)
+		}
+	case TypeTxBatch:
+		m := &eventpb.TxBatch{}
+		if err = proto.Unmarshal(b, m); err == nil {
+			v = txBatchFromProto(m)
+		}
+	default:
+		return nil, fmt.Errorf("event: type %d not supported", typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("event: content protobuf decode failed: %v", err)
+	}
+	if version := versionOf(v); version > SchemaVersion {
+		return nil, fmt.Errorf("event: schema version %d is newer than %d", version, SchemaVersion)
+	}
+	return v, nil
+}
+
+func (e *Block) toProto() *eventpb.Block {
+	return &eventpb.Block{
+		SchemaVersion:     int32(e.SchemaVersion),
+		Hash:              e.Hash,
+		Confirmations:     e.Confirmations,
+		Merkleroot:        e.Merkleroot,
+		Previousblockhash: e.Previousblockhash,
+		Height:            e.Height,
+		Version:           e.Version,
+		Time:              e.Time,
+		Fork:              e.Fork,
+		Symbol:            e.Symbol,
+	}
+}
+
+func blockFromProto(m *eventpb.Block) *Block {
+	e := &Block{SchemaVersion: int(m.SchemaVersion)}
+	e.Hash = m.Hash
+	e.Confirmations = m.Confirmations
+	e.Merkleroot = m.Merkleroot
+	e.Previousblockhash = m.Previousblockhash
+	e.Height = m.Height
+	e.Version = m.Version
+	e.Time = m.Time
+	e.Fork = m.Fork
+	e.Symbol = m.Symbol
+	return e
+}
+
+func (e *Tx) toProto() *eventpb.Tx {
+	m := &eventpb.Tx{
+		SchemaVersion: int32(e.SchemaVersion),
+		AppId:         e.AppID,
+		WalletId:      e.WalletID,
+		AccountId:     e.AccountID,
+		DataType:      e.DataType,
+		ContractId:    e.ContractID,
+		Redelivery:    e.Redelivery,
+	}
+	if e.Content != nil {
+		m.Content = transactionToProto(e.Content)
+	}
+	for _, v := range e.Inputs {
+		if v != nil {
+			m.Inputs = append(m.Inputs, rechargeToProto(&v.Recharge, v.SourceTxID, v.SourceIndex, ""))
+		}
+	}
+	for _, v := range e.Outputs {
+		if v != nil {
+			m.Outputs = append(m.Outputs, rechargeToProto(&v.Recharge, "", 0, v.ExtParam))
+		}
+	}
+	return m
+}
+
+func txFromProto(m *eventpb.Tx) *Tx {
+	e := &Tx{
+		SchemaVersion: int(m.SchemaVersion),
+		AppID:         m.AppId,
+		WalletID:      m.WalletId,
+		AccountID:     m.AccountId,
+		DataType:      m.DataType,
+		ContractID:    m.ContractId,
+		Redelivery:    m.Redelivery,
+	}
+	if m.Content != nil {
+		e.Content = transactionFromProto(m.Content)
+	}
+	for _, v := range m.Inputs {
+		e.Inputs = append(e.Inputs, &openwallet.TxInput{SourceTxID: v.SourceTxId, SourceIndex: v.SourceIndex, Recharge: rechargeFromProto(v)})
+	}
+	for _, v := range m.Outputs {
+		e.Outputs = append(e.Outputs, &openwallet.TxOutPut{ExtParam: v.ExtParam, Recharge: rechargeFromProto(v)})
+	}
+	return e
+}
+
+func (e *Receipt) toProto() *eventpb.Receipt {
+	m := &eventpb.Receipt{
+		SchemaVersion: int32(e.SchemaVersion),
+		Coin:          coinToProto(&e.Coin),
+		Wxid:          e.WxID,
+		Txid:          e.TxID,
+		From:          e.From,
+		To:            e.To,
+		Value:         e.Value,
+		Fees:          e.Fees,
+		RawReceipt:    e.RawReceipt,
+		BlockHash:     e.BlockHash,
+		BlockHeight:   e.BlockHeight,
+		ConfirmTime:   e.ConfirmTime,
+		Status:        e.Status,
+		Reason:        e.Reason,
+		ExtParam:      e.ExtParam,
+	}
+	for _, v := range e.Events {
+		if v == nil {
+			continue
+		}
+		ev := &eventpb.ContractEvent{Event: v.Event, Value: v.Value}
+		if v.Contract != nil {
+			ev.Contract = contractToProto(v.Contract)
+		}
+		m.Events = append(m.Events, ev)
+	}
+	return m
+}
+
+func receiptFromProto(m *eventpb.Receipt) *Receipt {
+	e := &Receipt{SchemaVersion: int(m.SchemaVersion)}
+	e.Coin = coinFromProto(m.Coin)
+	e.WxID = m.Wxid
+	e.TxID = m.Txid
+	e.From = m.From
+	e.To = m.To
+	e.Value = m.Value
+	e.Fees = m.Fees
+	e.RawReceipt = m.RawReceipt
+	for _, v := range m.Events {
+		ev := &openwallet.SmartContractEvent{Event: v.Event, Value: v.Value}
+		if v.Contract != nil {
+			contract := contractFromProto(v.Contract)
+			ev.Contract = &contract
+		}
+		e.Events = append(e.Events, ev)
+	}
+	e.BlockHash = m.BlockHash
+	e.BlockHeight = m.BlockHeight
+	e.ConfirmTime = m.ConfirmTime
+	e.Status = m.Status
+	e.Reason = m.Reason
+	e.ExtParam = m.ExtParam
+	return e
+}
+
+func (e *TxBatch) toProto() *eventpb.TxBatch {
+	m := &eventpb.TxBatch{
+		SchemaVersion: int32(e.SchemaVersion),
+		AppId:         e.AppID,
+		BlockHash:     e.BlockHash,
+		BlockHeight:   e.BlockHeight,
+	}
+	for _, v := range e.Txs {
+		if v != nil {
+			m.Txs = append(m.Txs, v.toProto())
+		}
+	}
+	return m
+}
+
+func txBatchFromProto(m *eventpb.TxBatch) *TxBatch {
+	e := &TxBatch{
+		SchemaVersion: int(m.SchemaVersion),
+		AppID:         m.AppId,
+		BlockHash:     m.BlockHash,
+		BlockHeight:   m.BlockHeight,
+	}
+	for _, v := range m.Txs {
+		e.Txs = append(e.Txs, txFromProto(v))
+	}
+	return e
+}
+
+func contractToProto(c *openwallet.SmartContract) *eventpb.SmartContract {
+	return &eventpb.SmartContract{
+		ContractId: c.ContractID,
+		Symbol:     c.Symbol,
+		Address:    c.Address,
+		Token:      c.Token,
+		Protocol:   c.Protocol,
+		Name:       c.Name,
+		Decimals:   c.Decimals,
+	}
+}
+
+func contractFromProto(m *eventpb.SmartContract) openwallet.SmartContract {
+	return openwallet.SmartContract{
+		ContractID: m.GetContractId(),
+		Symbol:     m.GetSymbol(),
+		Address:    m.GetAddress(),
+		Token:      m.GetToken(),
+		Protocol:   m.GetProtocol(),
+		Name:       m.GetName(),
+		Decimals:   m.GetDecimals(),
+	}
+}
+
+func coinToProto(c *openwallet.Coin) *eventpb.Coin {
+	return &eventpb.Coin{
+		Symbol:     c.Symbol,
+		IsContract: c.IsContract,
+		ContractId: c.ContractID,
+		Contract:   contractToProto(&c.Contract),
+	}
+}
+
+func coinFromProto(m *eventpb.Coin) openwallet.Coin {
+	return openwallet.Coin{
+		Symbol:     m.GetSymbol(),
+		IsContract: m.GetIsContract(),
+		ContractID: m.GetContractId(),
+		Contract:   contractFromProto(m.GetContract()),
+	}
+}
+
+func transactionToProto(t *openwallet.Transaction) *eventpb.Transaction {
+	return &eventpb.Transaction{
+		Wxid:        t.WxID,
+		Txid:        t.TxID,
+		AccountId:   t.AccountID,
+		Coin:        coinToProto(&t.Coin),
+		From:        t.From,
+		To:          t.To,
+		Amount:      t.Amount,
+		Decimal:     t.Decimal,
+		TxType:      t.TxType,
+		TxAction:    t.TxAction,
+		Confirm:     t.Confirm,
+		BlockHash:   t.BlockHash,
+		BlockHeight: t.BlockHeight,
+		IsMemo:      t.IsMemo,
+		Memo:        t.Memo,
+		Fees:        t.Fees,
+		Received:    t.Received,
+		SubmitTime:  t.SubmitTime,
+		ConfirmTime: t.ConfirmTime,
+		Status:      t.Status,
+		Reason:      t.Reason,
+		ExtParam:    t.ExtParam,
+	}
+}
+
+func transactionFromProto(m *eventpb.Transaction) *openwallet.Transaction {
+	return &openwallet.Transaction{
+		WxID:        m.Wxid,
+		TxID:        m.Txid,
+		AccountID:   m.AccountId,
+		Coin:        coinFromProto(m.Coin),
+		From:        m.From,
+		To:          m.To,
+		Amount:      m.Amount,
+		Decimal:     m.Decimal,
+		TxType:      m.TxType,
+		TxAction:    m.TxAction,
+		Confirm:     m.Confirm,
+		BlockHash:   m.BlockHash,
+		BlockHeight: m.BlockHeight,
+		IsMemo:      m.IsMemo,
+		Memo:        m.Memo,
+		Fees:        m.Fees,
+		Received:    m.Received,
+		SubmitTime:  m.SubmitTime,
+		ConfirmTime: m.ConfirmTime,
+		Status:      m.Status,
+		Reason:      m.Reason,
+		ExtParam:    m.ExtParam,
+	}
+}
+
+// 交易输入/输出,sourceTxID/sourceIndex仅用于输入,extParam仅用于输出
+func rechargeToProto(r *openwallet.Recharge, sourceTxID string, sourceIndex uint64, extParam string) *eventpb.Recharge {
+	return &eventpb.Recharge{
+		SourceTxId:  sourceTxID,
+		SourceIndex: sourceIndex,
+		Sid:         r.Sid,
+		Txid:        r.TxID,
+		AccountId:   r.AccountID,
+		Address:     r.Address,
+		Symbol:      r.Symbol,
+		Coin:        coinToProto(&r.Coin),
+		Amount:      r.Amount,
+		Confirm:     r.Confirm,
+		BlockHash:   r.BlockHash,
+		BlockHeight: r.BlockHeight,
+		IsMemo:      r.IsMemo,
+		Memo:        r.Memo,
+		Index:       r.Index,
+		Received:    r.Received,
+		CreatedAt:   r.CreateAt,
+		Delete:      r.Delete,
+		TxType:      r.TxType,
+		ExtParam:    extParam,
+	}
+}
+
+func rechargeFromProto(m *eventpb.Recharge) openwallet.Recharge {
+	return openwallet.Recharge{
+		Sid:         m.Sid,
+		TxID:        m.Txid,
+		AccountID:   m.AccountId,
+		Address:     m.Address,
+		Symbol:      m.Symbol,
+		Coin:        coinFromProto(m.Coin),
+		Amount:      m.Amount,
+		Confirm:     m.Confirm,
+		BlockHash:   m.BlockHash,
+		BlockHeight: m.BlockHeight,
+		IsMemo:      m.IsMemo,
+		Memo:        m.Memo,
+		Index:       m.Index,
+		Received:    m.Received,
+		CreateAt:    m.CreatedAt,
+		Delete:      m.Delete,
+		TxType:      m.TxType,
+	}
+}
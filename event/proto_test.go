@@ -0,0 +1,262 @@
+package event
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nbit99/open_scanner/event/eventpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	protoMessageLine = regexp.MustCompile(`^message\s+(\w+)\s*\{$`)
+	protoFieldLine   = regexp.MustCompile(`^(repeated\s+)?(\w+)\s+(\w+)\s*=\s*(\d+);$`)
+	protoScalars     = map[string]descriptorpb.FieldDescriptorProto_Type{
+		"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		"bool":   descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+		"int32":  descriptorpb.FieldDescriptorProto_TYPE_INT32,
+		"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		"uint64": descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	}
+)
+
+// 解析event.proto(只包含message及标量/消息字段)生成描述符,用于校验生成的eventpb与.proto定义一致
+func loadProtoFile(t *testing.T) protoreflect.FileDescriptor {
+	f, err := os.Open("event.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pkg := ""
+	file := &descriptorpb.FileDescriptorProto{Name: proto.String("event.proto"), Syntax: proto.String("proto3")}
+	var msg *descriptorpb.DescriptorProto
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case len(line) == 0 || strings.HasPrefix(line, "syntax") || strings.HasPrefix(line, "option"):
+		case strings.HasPrefix(line, "package "):
+			pkg = strings.TrimSuffix(strings.TrimPrefix(line, "package "), ";")
+			file.Package = proto.String(pkg)
+		case protoMessageLine.MatchString(line):
+			msg = &descriptorpb.DescriptorProto{Name: proto.String(protoMessageLine.FindStringSubmatch(line)[1])}
+			file.MessageType = append(file.MessageType, msg)
+		case line == "}":
+			msg = nil
+		case msg != nil && protoFieldLine.MatchString(line):
+			m := protoFieldLine.FindStringSubmatch(line)
+			var number int32
+			fmt.Sscan(m[4], &number)
+			field := &descriptorpb.FieldDescriptorProto{Name: proto.String(m[3]), Number: proto.Int32(number), JsonName: proto.String(protoJSONName(m[3]))}
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+			if len(m[1]) > 0 {
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			}
+			if typ, ok := protoScalars[m[2]]; ok {
+				field.Type = typ.Enum()
+			} else {
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				field.TypeName = proto.String("." + pkg + "." + m[2])
+			}
+			msg.Field = append(msg.Field, field)
+		default:
+			t.Fatalf("event.proto: unexpected line %q", line)
+		}
+	}
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+func protoJSONName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.Title(parts[i])
+	}
+	return strings.Join(parts, "")
+}
+
+var protoNames = map[int64]protoreflect.Name{TypeBlock: "Block", TypeTx: "Tx", TypeReceipt: "Receipt", TypeTxBatch: "TxBatch"}
+
+// event.proto修改后需重新生成eventpb(go generate)
+func TestGeneratedMatchesProto(t *testing.T) {
+	want := loadProtoFile(t)
+	got := eventpb.File_event_proto
+	if want.Package() != got.Package() || want.Messages().Len() != got.Messages().Len() {
+		t.Fatalf("eventpb out of date: package %s, %d messages", got.Package(), got.Messages().Len())
+	}
+	for i := 0; i < want.Messages().Len(); i++ {
+		wm := want.Messages().Get(i)
+		gm := got.Messages().ByName(wm.Name())
+		if gm == nil || gm.Fields().Len() != wm.Fields().Len() {
+			t.Fatalf("eventpb out of date: message %s", wm.Name())
+		}
+		for j := 0; j < wm.Fields().Len(); j++ {
+			wf := wm.Fields().Get(j)
+			gf := gm.Fields().ByNumber(wf.Number())
+			if gf == nil || gf.Name() != wf.Name() || gf.Kind() != wf.Kind() || gf.Cardinality() != wf.Cardinality() {
+				t.Fatalf("eventpb out of date: field %s.%s", wm.Name(), wf.Name())
+			}
+			if wf.Kind() == protoreflect.MessageKind && gf.Message().FullName() != wf.Message().FullName() {
+				t.Fatalf("eventpb out of date: field %s.%s type", wm.Name(), wf.Name())
+			}
+		}
+	}
+}
+
+func TestProtoMatchesDescriptor(t *testing.T) {
+	fd := eventpb.File_event_proto
+	for typ, v := range testEvents() {
+		md := fd.Messages().ByName(protoNames[typ])
+		b, err := MarshalProto(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 编码可被.proto定义解析,无未知字段,字段值与JSON消息一致
+		msg := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(b, msg); err != nil {
+			t.Fatalf("%s: %v", md.Name(), err)
+		}
+		if path := unknownFields(msg.ProtoReflect(), string(md.Name())); len(path) > 0 {
+			t.Fatalf("unknown fields in %s", path)
+		}
+		pj, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := compareDoc(numberDoc(t, pj), numberDoc(t, mustJSON(t, v)), string(md.Name())); err != "" {
+			t.Fatal(err)
+		}
+
+		// .proto定义编码的消息可由DecodeProto解析
+		b, err = proto.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeProto(typ, base64.URLEncoding.EncodeToString(b))
+		if err != nil {
+			t.Fatalf("%s: %v", md.Name(), err)
+		}
+		if string(mustJSON(t, decoded)) != string(mustJSON(t, v)) {
+			t.Fatalf("%s: decode mismatch\n%s\n%s", md.Name(), mustJSON(t, decoded), mustJSON(t, v))
+		}
+	}
+}
+
+// 数值解析为json.Number,避免大整数转为浮点数
+func numberDoc(t *testing.T, b []byte) interface{} {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func unknownFields(m protoreflect.Message, path string) string {
+	if len(m.GetUnknown()) > 0 {
+		return path
+	}
+	result := ""
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind {
+			return true
+		}
+		if fd.IsList() {
+			for i := 0; i < v.List().Len() && len(result) == 0; i++ {
+				result = unknownFields(v.List().Get(i).Message(), path+"."+string(fd.Name()))
+			}
+		} else {
+			result = unknownFields(v.Message(), path+"."+string(fd.Name()))
+		}
+		return len(result) == 0
+	})
+	return result
+}
+
+// 按protobuf的每个字段比较JSON消息(字段名忽略大小写及下划线),未设置的消息字段对应JSON零值
+func compareDoc(p, j interface{}, path string) string {
+	switch pv := p.(type) {
+	case nil:
+		if !zeroDoc(j) {
+			return path + ": expected zero value"
+		}
+	case map[string]interface{}:
+		jm, _ := j.(map[string]interface{})
+		keys := make(map[string]interface{})
+		for k, v := range jm {
+			keys[strings.ToLower(k)] = v
+		}
+		for k, v := range pv {
+			jv, ok := keys[strings.ToLower(k)]
+			if !ok {
+				// Recharge的source_tx_id/source_index只用于输入,ext_param只用于输出
+				if zeroDoc(v) {
+					continue
+				}
+				return path + "." + k + ": missing in json message"
+			}
+			if err := compareDoc(v, jv, path+"."+k); err != "" {
+				return err
+			}
+		}
+	case []interface{}:
+		jl, _ := j.([]interface{})
+		if len(pv) != len(jl) {
+			return path + ": length mismatch"
+		}
+		for i := range pv {
+			if err := compareDoc(pv[i], jl[i], fmt.Sprint(path, "[", i, "]")); err != "" {
+				return err
+			}
+		}
+	default:
+		// protojson中64位整数为字符串
+		if fmt.Sprint(pv) != fmt.Sprint(j) {
+			return fmt.Sprint(path, ": ", pv, " != ", j)
+		}
+	}
+	return ""
+}
+
+func zeroDoc(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		for _, v := range value {
+			if !zeroDoc(v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(value) == 0
+	case string:
+		// protojson中64位整数为字符串
+		return len(value) == 0 || value == "0"
+	case json.Number:
+		return value.String() == "0"
+	case bool:
+		return !value
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package event
+
+// JSON Schema(draft-07),扫块服务通过健康检查端口/schema/<name>.json发布
+// 只约束消费方依赖的字段,允许新增字段
+const (
+	BlockSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://github.com/nbit99/open_scanner/event/block.json",
+  "title": "Block",
+  "description": "区块消息, type=1",
+  "type": "object",
+  "required": ["schemaVersion", "hash", "previousblockhash", "height", "time", "fork", "symbol"],
+  "properties": {
+    "schemaVersion": {"type": "integer", "const": 1},
+    "hash": {"type": "string"},
+    "confirmations": {"type": "integer", "minimum": 0},
+    "merkleroot": {"type": "string"},
+    "previousblockhash": {"type": "string"},
+    "height": {"type": "integer", "minimum": 0},
+    "version": {"type": "integer", "minimum": 0},
+    "time": {"type": "integer", "minimum": 0},
+    "fork": {"type": "boolean"},
+    "symbol": {"type": "string"}
+  }
+}`
+
+	TxSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://github.com/nbit99/open_scanner/event/tx.json",
+  "title": "Tx",
+  "description": "交易单消息, type=2",
+  "type": "object",
+  "required": ["schemaVersion", "appID", "walletID", "accountID", "dataType", "content", "inputs", "outputs", "contractID"],
+  "properties": {
+    "schemaVersion": {"type": "integer", "const": 1},
+    "appID": {"type": "string"},
+    "walletID": {"type": "string"},
+    "accountID": {"type": "string"},
+    "dataType": {"type": "integer", "const": 2},
+    "contractID": {"type": "string", "description": "合约交易单的合约ID,普通交易单为空"},
+    "redelivery": {"type": "boolean", "description": "已发送过的交易单重新发送"},
+    "content": {
+      "type": "object",
+      "required": ["wxid", "txid", "coin", "amount", "decimal", "blockHash", "blockHeight", "fees", "status"],
+      "properties": {
+        "wxid": {"type": "string"},
+        "txid": {"type": "string"},
+        "accountID": {"type": "string"},
+        "coin": {"$ref": "#/definitions/coin"},
+        "from": {"type": ["array", "null"], "items": {"type": "string"}},
+        "to": {"type": ["array", "null"], "items": {"type": "string"}},
+        "amount": {"type": "string", "description": "账户净变动数量,十进制字符串"},
+        "decimal": {"type": "integer"},
+        "txType": {"type": "integer"},
+        "txAction": {"type": "string"},
+        "confirm": {"type": "integer"},
+        "blockHash": {"type": "string"},
+        "blockHeight": {"type": "integer", "minimum": 0},
+        "isMemo": {"type": "boolean"},
+        "memo": {"type": "string"},
+        "fees": {"type": "string"},
+        "received": {"type": "boolean"},
+        "submitTime": {"type": "integer"},
+        "confirmTime": {"type": "integer"},
+        "status": {"type": "string"},
+        "reason": {"type": "string"},
+        "extParam": {"type": "string"}
+      }
+    },
+    "inputs": {"type": ["array", "null"], "items": {"$ref": "#/definitions/recharge"}},
+    "outputs": {"type": ["array", "null"], "items": {"$ref": "#/definitions/recharge"}}
+  },
+  "definitions": {
+    "coin": {
+      "type": "object",
+      "required": ["symbol", "isContract", "contractID"],
+      "properties": {
+        "symbol": {"type": "string"},
+        "isContract": {"type": "boolean"},
+        "contractID": {"type": "string"},
+        "contract": {"type": "object"}
+      }
+    },
+    "recharge": {
+      "type": "object",
+      "required": ["txid", "address", "coin", "amount", "blockHash", "blockHeight", "index"],
+      "properties": {
+        "SourceTxID": {"type": "string"},
+        "SourceIndex": {"type": "integer"},
+        "sid": {"type": "string"},
+        "txid": {"type": "string"},
+        "accountID": {"type": "string"},
+        "address": {"type": "string"},
+        "coin": {"$ref": "#/definitions/coin"},
+        "amount": {"type": "string"},
+        "blockHash": {"type": "string"},
+        "blockHeight": {"type": "integer", "minimum": 0},
+        "index": {"type": "integer", "minimum": 0},
+        "txType": {"type": "integer"}
+      }
+    }
+  }
+}`
+
+	ReceiptSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://github.com/nbit99/open_scanner/event/receipt.json",
+  "title": "Receipt",
+  "description": "智能合约交易回执消息, type=3",
+  "type": "object",
+  "required": ["schemaVersion", "coin", "wxid", "txid", "from", "to", "rawReceipt", "events", "blockHash", "blockHeight", "confirmTime", "status"],
+  "properties": {
+    "schemaVersion": {"type": "integer", "const": 1},
+    "coin": {
+      "type": "object",
+      "required": ["symbol", "isContract", "contractID"],
+      "properties": {
+        "symbol": {"type": "string"},
+        "isContract": {"type": "boolean"},
+        "contractID": {"type": "string"}
+      }
+    },
+    "wxid": {"type": "string"},
+    "txid": {"type": "string"},
+    "from": {"type": "string"},
+    "to": {"type": "string"},
+    "value": {"type": "string"},
+    "fees": {"type": "string"},
+    "rawReceipt": {"type": "string"},
+    "events": {
+      "type": ["array", "null"],
+      "items": {
+        "type": "object",
+        "required": ["event", "value"],
+        "properties": {
+          "contract": {"type": ["object", "null"]},
+          "event": {"type": "string"},
+          "value": {"type": "string"}
+        }
+      }
+    },
+    "blockHash": {"type": "string"},
+    "blockHeight": {"type": "integer", "minimum": 0},
+    "confirmTime": {"type": "integer"},
+    "status": {"type": "string"},
+    "reason": {"type": "string"},
+    "extParam": {"type": "string"}
+  }
+}`
+
+	TxBatchSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://github.com/nbit99/open_scanner/event/txbatch.json",
+  "title": "TxBatch",
+  "description": "交易单批量消息, type=8",
+  "type": "object",
+  "required": ["schemaVersion", "appID", "blockHash", "blockHeight", "txs"],
+  "properties": {
+    "schemaVersion": {"type": "integer", "const": 1},
+    "appID": {"type": "string", "description": "普通交易单为应用ID,合约交易单为合约ID"},
+    "blockHash": {"type": "string"},
+    "blockHeight": {"type": "integer", "minimum": 0},
+    "txs": {"type": "array", "items": {"$ref": "tx.json"}}
+  }
+}`
+)
+
+// Schemas 按名称(block/tx/receipt/txbatch)索引的JSON Schema
+var Schemas = map[string]string{
+	"block":   BlockSchema,
+	"tx":      TxSchema,
+	"receipt": ReceiptSchema,
+	"txbatch": TxBatchSchema,
+}
@@ -3,13 +3,22 @@ module github.com/nbit99/open_scanner
 go 1.12
 
 require (
+	github.com/asdine/storm v2.1.2+incompatible
 	github.com/astaxie/beego v1.12.0
+	github.com/garyburd/redigo v1.6.0
 	github.com/godaddy-x/jorm v1.0.60
+	github.com/golang/protobuf v1.4.3
+	github.com/hashicorp/consul/api v1.1.0
 	github.com/nbit99/open_base v1.10.0
 	github.com/nbit99/openwallet/v2 v2.0.11
+	github.com/prometheus/client_golang v1.0.0
 	github.com/shopspring/decimal v0.0.0-20200105231215-408a2507e114
+	github.com/streadway/amqp v0.0.0-20190827072141-edfb9018d271
+	go.etcd.io/bbolt v1.3.3
 	go.uber.org/atomic v1.4.0 // indirect
 	go.uber.org/zap v1.10.0 // indirect
+	golang.org/x/crypto v0.0.0-20200728195943-123391ffb6de
+	google.golang.org/protobuf v1.23.0
 )
 
 //replace github.com/nbit99/open_base => ../open_base
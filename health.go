@@ -0,0 +1,277 @@
+package open_scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/godaddy-x/jorm/cache/redis"
+	"github.com/godaddy-x/jorm/consul"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/sqld"
+	"github.com/godaddy-x/jorm/util"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/nbit99/open_base/major"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	healthTimeout = 3 * time.Second
+	defaultMaxLag = 20
+)
+
+var healthOnce sync.Once
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// ScannerState 扫块器状态
+type ScannerState struct {
+	Running       bool   `json:"running"`
+	Closed        bool   `json:"closed"`
+	ScannedHeight uint64 `json:"scannedHeight"`
+	NodeHeight    uint64 `json:"nodeHeight"`
+	Lag           uint64 `json:"lag"`
+	MaxLag        uint64 `json:"maxLag"`
+	Role          string `json:"role,omitempty"` // 开启主节点选举时为leader/standby
+}
+
+// HealthReport 币种扫块服务健康状态
+type HealthReport struct {
+	Symbol  string        `json:"symbol"`
+	OK      bool          `json:"ok"`
+	Scanner ScannerState  `json:"scanner"`
+	Checks  []HealthCheck `json:"checks"`
+	Time    int64         `json:"time"`
+}
+
+// Liveness 进程存活检查: 扫块器已初始化且未关闭
+func (o *OpenWScanner) Liveness() HealthReport {
+	report := HealthReport{Symbol: o.Symbol, Scanner: o.scannerState(), Time: util.Time()}
+	check := HealthCheck{Name: "scanner", OK: o.BlockScanner != nil && !report.Scanner.Closed}
+	if !check.OK {
+		check.Message = "扫块器未启动或已关闭"
+	}
+	report.Checks = []HealthCheck{check}
+	report.OK = check.OK
+	return report
+}
+
+// Readiness 就绪检查: 扫块器运行中时高度落后不超过maxLag,且Mongo/Redis/MQ/Consul可访问
+func (o *OpenWScanner) Readiness() HealthReport {
+	report := o.Liveness()
+	state := report.Scanner
+	lag := HealthCheck{Name: "lag", OK: !state.Running || state.Lag <= state.MaxLag}
+	if !lag.OK {
+		lag.Message = fmt.Sprintf("扫描高度落后%d个区块", state.Lag)
+	}
+	report.Checks = append(report.Checks, lag,
+		runHealthCheck("mongo", pingMongo),
+		runHealthCheck("redis", pingRedis),
+		o.notifierCheck())
+	if o.provider().UseConsul() {
+		report.Checks = append(report.Checks, runHealthCheck("consul", pingConsul))
+	}
+	for _, v := range report.Checks {
+		if !v.OK {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+func (o *OpenWScanner) scannerState() ScannerState {
+	state := ScannerState{MaxLag: defaultMaxLag}
+	if c := o.currentConfig(); c != nil {
+		state.MaxLag = uint64(c.DefaultInt64("maxLag", defaultMaxLag))
+	}
+	scanner := o.BlockScanner
+	if scanner == nil {
+		return state
+	}
+	state.Running, state.Closed = scannerRunning(scanner)
+	if o.leader != nil {
+		state.Role = "standby"
+		if o.leader.IsLeader() {
+			state.Role = "leader"
+		}
+	}
+	state.ScannedHeight = scanner.GetScannedBlockHeight()
+	state.NodeHeight = scanner.GetGlobalMaxBlockHeight()
+	if state.NodeHeight > state.ScannedHeight {
+		state.Lag = state.NodeHeight - state.ScannedHeight
+	}
+	return state
+}
+
+// 扫块器接口未提供运行状态,读取BlockScannerBase的Scanning字段
+func scannerRunning(scanner openwallet.BlockScanner) (bool, bool) {
+	closed := false
+	if v, ok := scanner.(interface{ IsClose() bool }); ok {
+		closed = v.IsClose()
+	}
+	value := reflect.Indirect(reflect.ValueOf(scanner))
+	if value.Kind() != reflect.Struct {
+		return false, closed
+	}
+	field := value.FieldByName("Scanning")
+	if !field.IsValid() || field.Kind() != reflect.Bool {
+		return false, closed
+	}
+	return field.Bool(), closed
+}
+
+func (o *OpenWScanner) notifierCheck() HealthCheck {
+	check := HealthCheck{Name: "mq"}
+	if o.Notifier == nil {
+		check.Message = "消息发送未初始化"
+		return check
+	}
+	health := o.Notifier.Health()
+	check.OK = health.Connected
+	if !check.OK {
+		check.Message = health.LastError
+	}
+	return check
+}
+
+// 执行检查,超时视为失败
+func runHealthCheck(name string, call func() error) HealthCheck {
+	result := make(chan error, 1)
+	go func() {
+		result <- call()
+	}()
+	check := HealthCheck{Name: name}
+	select {
+	case err := <-result:
+		check.OK = err == nil
+		if err != nil {
+			check.Message = err.Error()
+		}
+	case <-time.After(healthTimeout):
+		check.Message = "检查超时"
+	}
+	return check
+}
+
+func pingMongo() error {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return err
+	}
+	defer mongo.Close()
+	session := mongo.Session.Copy()
+	defer session.Close()
+	return session.Ping()
+}
+
+func pingRedis() error {
+	client, err := new(cache.RedisManager).Client()
+	if err != nil {
+		return err
+	}
+	conn := client.Pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("PING")
+	return err
+}
+
+func pingConsul() error {
+	_, err := major.InitDc().Consulx.Status().Leader()
+	return err
+}
+
+// 注册/healthz及/readyz,与consul检查使用同一端口(CheckPort)
+// /healthz/<SYMBOL>, /readyz/<SYMBOL> 只检查该币种
+func registerHealthHandlers() {
+	healthOnce.Do(func() {
+		liveness := healthHandler("/healthz", func(o *OpenWScanner) HealthReport { return o.Liveness() })
+		readiness := healthHandler("/readyz", func(o *OpenWScanner) HealthReport { return o.Readiness() })
+		http.HandleFunc("/healthz", liveness)
+		http.HandleFunc("/healthz/", liveness)
+		http.HandleFunc("/readyz", readiness)
+		http.HandleFunc("/readyz/", readiness)
+	})
+}
+
+// 返回所有已启动币种的检查结果,任一币种失败时返回503
+// 路径为<prefix>/<SYMBOL>时只返回该币种的检查结果,币种未启动时返回404
+func healthHandler(prefix string, call func(o *OpenWScanner) HealthReport) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if symbol := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/"); len(symbol) > 0 {
+			o := GetScanner(symbol)
+			if o == nil {
+				http.NotFound(w, r)
+				return
+			}
+			report := call(o)
+			status := http.StatusOK
+			if !report.OK {
+				status = http.StatusServiceUnavailable
+			}
+			writeHealth(w, status, report)
+			return
+		}
+		reports := make([]HealthReport, 0)
+		scanners.Range(func(key, value interface{}) bool {
+			reports = append(reports, call(value.(*OpenWScanner)))
+			return true
+		})
+		sort.Slice(reports, func(i, j int) bool { return reports[i].Symbol < reports[j].Symbol })
+		status := http.StatusOK
+		if len(reports) == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		for _, v := range reports {
+			if !v.OK {
+				status = http.StatusServiceUnavailable
+			}
+		}
+		writeHealth(w, status, reports)
+	}
+}
+
+func writeHealth(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// 为已注册的RPC服务添加就绪检查,检查失败时consul将服务移出可用列表,checkPort为健康检查监听端口
+// 检查地址为/readyz/<SYMBOL>,多币种共用服务时每个币种一个检查,任一币种未就绪时共用的服务不可用
+func registerReadyCheck(consulx *consul.ConsulManager, tag, symbol string, checkPort int) error {
+	services, err := consulx.Consulx.Agent().Services()
+	if err != nil {
+		return err
+	}
+	for _, v := range services {
+		for _, t := range v.Tags {
+			if t != tag {
+				continue
+			}
+			check := &consulapi.AgentCheckRegistration{
+				ID:        v.ID + "/readyz/" + symbol,
+				Name:      tag + "就绪检查",
+				ServiceID: v.ID,
+				AgentServiceCheck: consulapi.AgentServiceCheck{
+					HTTP:     fmt.Sprintf("http://%s:%d/readyz/%s", v.Address, checkPort, symbol),
+					Interval: consulx.Config.Interval,
+					Timeout:  consulx.Config.Timeout,
+				},
+			}
+			if err := consulx.Consulx.Agent().CheckRegister(check); err != nil {
+				return err
+			}
+			log2.Info("注册就绪检查成功", 0, log2.String("service", v.ID), log2.String("http", check.HTTP))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package open_scanner
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandlerPerSymbol(t *testing.T) {
+	registerScanner(&OpenWScanner{Symbol: "AAA"})
+	registerScanner(&OpenWScanner{Symbol: "BBB"})
+	defer scanners.Delete("AAA")
+	defer scanners.Delete("BBB")
+	// BBB检查失败
+	handler := healthHandler("/readyz", func(o *OpenWScanner) HealthReport {
+		return HealthReport{Symbol: o.Symbol, OK: o.Symbol != "BBB"}
+	})
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := serve("/readyz/aaa")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for AAA, got %d", w.Code)
+	}
+	report := HealthReport{}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Symbol != "AAA" || !report.OK {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if w := serve("/readyz/BBB"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for BBB, got %d", w.Code)
+	}
+	if w := serve("/readyz/CCC"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown symbol, got %d", w.Code)
+	}
+
+	w = serve("/readyz")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when any symbol fails, got %d", w.Code)
+	}
+	reports := []HealthReport{}
+	if err := json.Unmarshal(w.Body.Bytes(), &reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0].Symbol != "AAA" || reports[1].Symbol != "BBB" {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+}
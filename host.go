@@ -0,0 +1,229 @@
+package open_scanner
+
+import (
+	"flag"
+	"fmt"
+	"github.com/godaddy-x/jorm/consul"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/nbit99/open_scanner/rpc"
+	"github.com/nbit99/openwallet/v2/log"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	"net/rpc"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const hostName = "host"
+
+var Symbols = flag.String("symbols", "", "")
+
+// ScannerHost 单进程运行多个币种扫块器
+// 各币种扫块日志(ScanLogPath/<SYMBOL>.log)、区块库及发件箱(dataDir/<SYMBOL>*.db)相互独立
+// 所有币种共用一个RPC监听及WalletApiService服务,请求按req.Symbol绑定到对应币种
+// 本地配置为目录时,数据库/日志等公共配置读取目录下的HOST.ini
+type ScannerHost struct {
+	Walletapi service.WalletApiService
+	Provider  ConfigProvider // 配置来源,为空时按-config参数选择
+	Scanners  []*OpenWScanner
+	consuls   []*consul.ConsulManager
+	handler   *hostWalletApi
+}
+
+func NewScannerHost(walletapi service.WalletApiService) *ScannerHost {
+	return &ScannerHost{Walletapi: walletapi}
+}
+
+// InitHost 启动-symbols参数(逗号分隔)指定的币种,未指定时启动adapters中的所有币种
+func InitHost(walletapi service.WalletApiService, adapters map[string]openwallet.AssetsAdapter) {
+	host := NewScannerHost(walletapi)
+	symbols := strings.Split(*Symbols, ",")
+	if len(*Symbols) == 0 {
+		symbols = []string{}
+		for k := range adapters {
+			symbols = append(symbols, k)
+		}
+		sort.Strings(symbols)
+	}
+	for _, v := range symbols {
+		symbol := strings.ToUpper(strings.TrimSpace(v))
+		adapter, ok := adapters[symbol]
+		if !ok {
+			panic(util.AddStr("币种[", symbol, "]适配器未找到"))
+		}
+		host.Add(adapter, &OpenWScanner{Symbol: symbol})
+	}
+	host.Run()
+}
+
+// Add 添加币种扫块器,需在Run前调用
+func (h *ScannerHost) Add(adapter openwallet.AssetsAdapter, o *OpenWScanner) {
+	o.Adapter = adapter
+	h.Scanners = append(h.Scanners, o)
+}
+
+// Run 初始化数据库/日志后依次启动各币种扫块器,注册RPC服务并等待退出信号
+func (h *ScannerHost) Run() {
+	if len(h.Scanners) == 0 {
+		panic("未指定扫块币种")
+	}
+	if h.Provider == nil {
+		h.Provider = NewConfigProvider()
+	}
+	if err := h.Provider.InitEnv(hostName); err != nil {
+		panic(err)
+	}
+	StartMetrics(*MetricsAddr)
+	for _, o := range h.Scanners {
+		o.Walletapi = h.Walletapi
+		o.Pause = *Pause
+		if o.Provider == nil {
+			o.Provider = h.Provider
+		}
+		o.StartWallet()
+	}
+	msg := util.AddStr("[", strings.Join(h.symbols(), ","), "]钱包RPC服务启动成功")
+	h.registerRPC()
+	if !h.Provider.UseConsul() {
+		serveLocalRPC(h.Scanners[0].currentConfig())
+		h.waitShutdown(msg)
+		return
+	}
+	h.register()
+	h.waitShutdown(msg)
+}
+
+func (h *ScannerHost) symbols() []string {
+	result := []string{}
+	for _, o := range h.Scanners {
+		result = append(result, o.Symbol)
+	}
+	return result
+}
+
+// 所有币种共用一个WalletApiService
+func (h *ScannerHost) registerRPC() {
+	h.handler = newHostWalletApi(h.Walletapi, h.symbols()...)
+	if err := h.handler.register(rpc.DefaultServer); err != nil {
+		panic(util.AddStr("注册RPC服务[", h.handler.name(), "]失败: ", err.Error()))
+	}
+}
+
+// 服务监听使用第一个币种的consul配置,WalletApiService在各币种consul中注册一次,使用同一consul的币种共用一条注册
+func (h *ScannerHost) register() {
+	listener, err := new(consul.ConsulManager).Client(h.Scanners[0].Symbol)
+	if err != nil {
+		panic(err)
+	}
+	registerHealthHandlers()
+	registerSchemaHandler()
+	hosts := []string{}
+	groups := make(map[string][]string)
+	clients := make(map[string]*consul.ConsulManager)
+	for _, o := range h.Scanners {
+		consulx, err := new(consul.ConsulManager).Client(o.Symbol)
+		if err != nil {
+			panic(err)
+		}
+		if err := deregisterTag(consulx, hostServiceTag(o.Symbol)); err != nil {
+			log.Error(o.Symbol, " 移除RPC服务失败: ", err.Error())
+		}
+		if _, ok := clients[consulx.Config.Host]; !ok {
+			hosts = append(hosts, consulx.Config.Host)
+			clients[consulx.Config.Host] = consulx
+		}
+		groups[consulx.Config.Host] = append(groups[consulx.Config.Host], o.Symbol)
+		h.consuls = append(h.consuls, consulx)
+	}
+	for _, host := range hosts {
+		if err := registerHostService(clients[host], groups[host], h.handler, listener.Config); err != nil {
+			panic(util.AddStr("Consul注册[", strings.Join(groups[host], ","), "]服务失败: ", err.Error()))
+		}
+	}
+	for i, o := range h.Scanners {
+		if err := registerReadyCheck(h.consuls[i], hostServiceTag(o.Symbol), o.Symbol, listener.Config.CheckPort); err != nil {
+			log.Error(o.Symbol, " 注册就绪检查失败: ", err.Error())
+		}
+	}
+	go listener.StartListenAndServe()
+}
+
+// 与单币种部署的服务标签一致
+func hostServiceTag(symbol string) string {
+	return symbol + "钱包RPC服务"
+}
+
+// 服务ID及方法列表,与ConsulManager.AddRegistration格式一致
+func hostServiceID(ip string, handler *hostWalletApi) (string, string) {
+	tof := reflect.TypeOf(handler)
+	sname := util.AddStr(ip, "/", handler.name())
+	methods := ","
+	for m := 0; m < tof.NumMethod(); m++ {
+		method := tof.Method(m)
+		methods = util.AddStr(methods, method.Name, ",")
+		sname = util.AddStr(sname, "/", method.Name)
+	}
+	return sname, methods
+}
+
+// 收到退出信号后同时停止各币种
+func (h *ScannerHost) waitShutdown(msg string) {
+	stop := waitSignal(msg)
+	defer stop()
+	var wg sync.WaitGroup
+	for i, o := range h.Scanners {
+		var consulx *consul.ConsulManager
+		if i < len(h.consuls) {
+			consulx = h.consuls[i]
+		}
+		wg.Add(1)
+		go func(o *OpenWScanner, consulx *consul.ConsulManager) {
+			defer wg.Done()
+			o.Shutdown(consulx, hostServiceTag(o.Symbol))
+		}(o, consulx)
+	}
+	wg.Wait()
+}
+
+// 与ConsulManager.AddRegistration相同,服务ID为ip/WalletApiService/方法...,调用方按ID中的/WalletApiService/查找服务
+// 标签为各币种的<SYMBOL>钱包RPC服务,按meta中的host访问共用的RPC监听
+func registerHostService(consulx *consul.ConsulManager, symbols []string, handler *hostWalletApi, listen *consul.ConsulConfig) error {
+	ip := util.GetLocalIP()
+	if ip == "" {
+		return util.Error("内网IP读取失败")
+	}
+	name := handler.name()
+	sname, methods := hostServiceID(ip, handler)
+	tags := []string{}
+	for _, v := range symbols {
+		tags = append(tags, hostServiceTag(v))
+	}
+	registration := &consulapi.AgentServiceRegistration{
+		ID:      sname,
+		Name:    util.AddStr(ip, "/", name),
+		Tags:    tags,
+		Address: ip,
+		Port:    listen.RpcPort,
+		Meta: map[string]string{
+			"host":     ip + ":" + util.AnyToStr(listen.ListenProt),
+			"protocol": listen.Protocol,
+			"version":  "1.0.0",
+			"methods":  methods,
+			"symbols":  strings.Join(symbols, ","),
+		},
+		Check: &consulapi.AgentServiceCheck{
+			HTTP:                           fmt.Sprintf("http://%s:%d%s", ip, listen.CheckPort, "/check"),
+			Timeout:                        listen.Timeout,
+			Interval:                       listen.Interval,
+			DeregisterCriticalServiceAfter: listen.DestroyAfter,
+		},
+	}
+	if err := consulx.Consulx.Agent().ServiceRegister(registration); err != nil {
+		return err
+	}
+	log2.Info("注册RPC服务成功", 0, log2.String("service", sname), log2.String("symbols", strings.Join(symbols, ",")))
+	return nil
+}
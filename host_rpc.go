@@ -0,0 +1,215 @@
+package open_scanner
+
+import (
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_scanner/rpc"
+	"github.com/nbit99/open_scanner/rpc/dto"
+	"net/rpc"
+	"strings"
+)
+
+const walletApiServiceName = "WalletApiService"
+
+// hostWalletApi 多币种进程共用的RPC服务,与单币种部署相同注册为WalletApiService
+// 请求按req.Symbol查找对应币种的扫块器/适配器,未指定或不属于本进程的币种拒绝
+type hostWalletApi struct {
+	symbols map[string]bool
+	api     service.WalletApiService
+}
+
+var _ service.WalletApiService = (*hostWalletApi)(nil)
+
+func newHostWalletApi(api service.WalletApiService, symbols ...string) *hostWalletApi {
+	h := &hostWalletApi{symbols: make(map[string]bool), api: api}
+	for _, v := range symbols {
+		h.symbols[strings.ToUpper(v)] = true
+	}
+	return h
+}
+
+// 服务名称
+func (h *hostWalletApi) name() string {
+	return walletApiServiceName
+}
+
+func (h *hostWalletApi) register(server *rpc.Server) error {
+	return server.RegisterName(h.name(), h)
+}
+
+func (h *hostWalletApi) bind(symbol *string) error {
+	if len(*symbol) == 0 {
+		return util.Error("请求未指定币种")
+	}
+	if !h.symbols[strings.ToUpper(*symbol)] {
+		return util.Error("币种[", *symbol, "]未在本服务启动")
+	}
+	return nil
+}
+
+func (h *hostWalletApi) BatchCreateAddress(req *dto.BatchCreateAddressReq, resp *dto.BatchCreateAddressResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.BatchCreateAddress(req, resp)
+}
+
+func (h *hostWalletApi) PublicKeyToAddress(req *dto.PublicKeyToAddressReq, resp *dto.PublicKeyToAddressResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.PublicKeyToAddress(req, resp)
+}
+
+func (h *hostWalletApi) CreateRawTransaction(req *dto.CreateRawTransactionReq, resp *dto.CreateRawTransactionResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.CreateRawTransaction(req, resp)
+}
+
+func (h *hostWalletApi) SubmitRawTransaction(req *dto.SubmitRawTransactionReq, resp *dto.SubmitRawTransactionResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.SubmitRawTransaction(req, resp)
+}
+
+func (h *hostWalletApi) CreateSummaryRawTransaction(req *dto.CreateSummaryRawTransactionReq, resp *dto.CreateSummaryRawTransactionReqResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.CreateSummaryRawTransaction(req, resp)
+}
+
+func (h *hostWalletApi) GetBalanceByAddress(req *dto.GetBalanceByAddressReq, resp *dto.GetBalanceByAddressResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.GetBalanceByAddress(req, resp)
+}
+
+func (h *hostWalletApi) GetTokenBalanceByAddress(req *dto.GetTokenBalanceByAddressReq, resp *dto.GetTokenBalanceByAddressResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.GetTokenBalanceByAddress(req, resp)
+}
+
+func (h *hostWalletApi) GetRawTransactionFeeRate(req *dto.GetRawTransactionFeeRateReq, resp *dto.GetRawTransactionFeeRateResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.GetRawTransactionFeeRate(req, resp)
+}
+
+func (h *hostWalletApi) RescannerHeight(req *dto.RescannerHeightReq, resp *dto.RescannerHeightResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.RescannerHeight(req, resp)
+}
+
+func (h *hostWalletApi) RescannerOneHeight(req *dto.RescannerOneHeightReq, resp *dto.RescannerOneHeightResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.RescannerOneHeight(req, resp)
+}
+
+func (h *hostWalletApi) RescanRange(req *dto.RescanRangeReq, resp *dto.RescanRangeResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.RescanRange(req, resp)
+}
+
+func (h *hostWalletApi) RescanAddress(req *dto.RescanAddressReq, resp *dto.RescanAddressResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.RescanAddress(req, resp)
+}
+
+func (h *hostWalletApi) GetRescanJob(req *dto.GetRescanJobReq, resp *dto.GetRescanJobResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.GetRescanJob(req, resp)
+}
+
+func (h *hostWalletApi) CancelRescanJob(req *dto.CancelRescanJobReq, resp *dto.CancelRescanJobResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.CancelRescanJob(req, resp)
+}
+
+func (h *hostWalletApi) GetBalanceType(req *dto.GetBalanceTypeReq, resp *dto.GetBalanceTypeResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.GetBalanceType(req, resp)
+}
+
+func (h *hostWalletApi) OnOffScanner(req *dto.OnOffScannerReq, resp *dto.OnOffScannerResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.OnOffScanner(req, resp)
+}
+
+func (h *hostWalletApi) GetScannerState(req *dto.GetScannerStateReq, resp *dto.GetScannerStateResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.GetScannerState(req, resp)
+}
+
+func (h *hostWalletApi) GetCheckpoint(req *dto.GetCheckpointReq, resp *dto.GetCheckpointResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.GetCheckpoint(req, resp)
+}
+
+func (h *hostWalletApi) GetDeadLetters(req *dto.GetDeadLettersReq, resp *dto.GetDeadLettersResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.GetDeadLetters(req, resp)
+}
+
+func (h *hostWalletApi) ReplayDeadLetters(req *dto.ReplayDeadLettersReq, resp *dto.ReplayDeadLettersResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.ReplayDeadLetters(req, resp)
+}
+
+func (h *hostWalletApi) VerifyAddress(req *dto.VerifyAddressReq, resp *dto.VerifyAddressResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.VerifyAddress(req, resp)
+}
+
+func (h *hostWalletApi) CallSmartContractABI(req *dto.CallSmartContractABIReq, resp *dto.CallSmartContractABIResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.CallSmartContractABI(req, resp)
+}
+
+func (h *hostWalletApi) CreateSmartContractTrade(req *dto.CreateSmartContractTradeReq, resp *dto.CreateSmartContractTradeResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.CreateSmartContractTrade(req, resp)
+}
+
+func (h *hostWalletApi) SubmitSmartContractTrade(req *dto.SubmitSmartContractTradeReq, resp *dto.SubmitSmartContractTradeResp) error {
+	if err := h.bind(&req.Symbol); err != nil {
+		return err
+	}
+	return h.api.SubmitSmartContractTrade(req, resp)
+}
@@ -0,0 +1,56 @@
+package open_scanner
+
+import (
+	"net"
+	"net/rpc"
+	"strings"
+	"testing"
+
+	service "github.com/nbit99/open_scanner/rpc"
+	"github.com/nbit99/open_scanner/rpc/dto"
+)
+
+// 只实现GetBalanceType,按请求中的币种返回余额模型
+type symbolEchoApi struct {
+	service.WalletApiService
+}
+
+var echoBalanceTypes = map[string]int64{"AAA": 1, "BBB": 2}
+
+func (s *symbolEchoApi) GetBalanceType(req *dto.GetBalanceTypeReq, resp *dto.GetBalanceTypeResp) error {
+	resp.BalanceType = echoBalanceTypes[req.Symbol]
+	return nil
+}
+
+func TestHostWalletApiShared(t *testing.T) {
+	server := rpc.NewServer()
+	api := &symbolEchoApi{}
+	if err := newHostWalletApi(api, "AAA", "BBB").register(server); err != nil {
+		t.Fatal(err)
+	}
+	c1, c2 := net.Pipe()
+	go server.ServeConn(c1)
+	client := rpc.NewClient(c2)
+	defer client.Close()
+
+	// 同一服务按请求中的币种绑定
+	for _, symbol := range []string{"AAA", "BBB"} {
+		resp := &dto.GetBalanceTypeResp{}
+		if err := client.Call("WalletApiService.GetBalanceType", &dto.GetBalanceTypeReq{Symbol: symbol}, resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.BalanceType != echoBalanceTypes[symbol] {
+			t.Fatalf("%s: unexpected balance type %d", symbol, resp.BalanceType)
+		}
+	}
+	for _, symbol := range []string{"", "CCC"} {
+		if err := client.Call("WalletApiService.GetBalanceType", &dto.GetBalanceTypeReq{Symbol: symbol}, &dto.GetBalanceTypeResp{}); err == nil {
+			t.Fatalf("expected error for symbol %q", symbol)
+		}
+	}
+
+	id, methods := hostServiceID("10.0.0.1", newHostWalletApi(api, "AAA"))
+	if !strings.HasPrefix(id, "10.0.0.1/WalletApiService/") || !strings.Contains(methods, ",GetBalanceType,") {
+		t.Fatalf("unexpected service id: %s (%s)", id, methods)
+	}
+}
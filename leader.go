@@ -0,0 +1,179 @@
+package open_scanner
+
+import (
+	"github.com/astaxie/beego/config"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/nbit99/open_base/major"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	leaderNode       = "scanner/leader/"
+	leaderSessionTTL = "15s"
+	leaderSync       = 10 * time.Second
+	leaderRetry      = 5 * time.Second
+)
+
+// LeaderElector 通过consul session锁选举主节点,同一币种只有主节点扫块
+// 备用实例保持RPC服务,扫块器暂停至获得锁;主节点定期写入扫描检查点,新主节点从检查点继续扫描
+// [币种ini] leaderElection = true 开启,检查点需使用各实例共用的存储(checkpoint = mongo)
+type LeaderElector struct {
+	mu       sync.Mutex
+	o        *OpenWScanner
+	key      string
+	autoRun  bool // 获得锁后是否启动扫块(币种BlockStop为0)
+	lock     *consulapi.Lock
+	leader   bool
+	done     chan struct{}
+	once     sync.Once
+	released chan struct{}
+}
+
+func NewLeaderElector(o *OpenWScanner, autoRun bool) *LeaderElector {
+	return &LeaderElector{o: o, key: leaderNode + strings.ToUpper(o.Symbol), autoRun: autoRun, done: make(chan struct{}), released: make(chan struct{})}
+}
+
+// 按币种配置启动主节点选举,未开启时返回false
+func (o *OpenWScanner) startLeaderElector(c config.Configer, autoRun bool) bool {
+	if o.leader != nil || !o.provider().UseConsul() || !c.DefaultBool("leaderElection", false) {
+		return false
+	}
+	if o.checkpoint == nil {
+		log2.Warn("扫描检查点未开启,主节点切换后从适配器扫描高度继续", 0, log2.String("symbol", o.Symbol))
+	} else if _, ok := o.checkpoint.store.(*FileCheckpointStore); ok {
+		log2.Warn("扫描检查点为本地文件,主节点切换无法接管扫描高度", 0, log2.String("symbol", o.Symbol))
+	}
+	o.leader = NewLeaderElector(o, autoRun)
+	go o.leader.run()
+	return true
+}
+
+// CheckLeader 备用实例返回错误,未开启选举时返回nil
+func (o *OpenWScanner) CheckLeader() error {
+	if o.leader != nil && !o.leader.IsLeader() {
+		return util.Error("[", o.Symbol, "]备用实例不能扫块")
+	}
+	return nil
+}
+
+// IsLeader 是否为主节点
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+func (e *LeaderElector) setLeader(leader bool) {
+	e.mu.Lock()
+	e.leader = leader
+	e.mu.Unlock()
+}
+
+func (e *LeaderElector) run() {
+	defer close(e.released)
+	host, _ := os.Hostname()
+	for {
+		lock, err := major.InitDc().Consulx.LockOpts(&consulapi.LockOptions{
+			Key:            e.key,
+			Value:          []byte(util.AddStr(host, "/", util.GetLocalIP())),
+			SessionName:    e.key,
+			SessionTTL:     leaderSessionTTL,
+			MonitorRetries: 3,
+		})
+		if err == nil {
+			e.lock = lock
+			var lost <-chan struct{}
+			if lost, err = lock.Lock(e.done); err == nil && lost != nil {
+				e.lead(lost)
+			}
+		}
+		if err != nil {
+			log2.Error("主节点选举失败", 0, log2.String("symbol", e.o.Symbol), log2.AddError(err))
+		}
+		select {
+		case <-e.done:
+			return
+		case <-time.After(leaderRetry):
+		}
+	}
+}
+
+// 获得锁后接管扫描高度并启动扫块,失去锁或停止时暂停扫块
+func (e *LeaderElector) lead(lost <-chan struct{}) {
+	o := e.o
+	scanner := o.BlockScanner
+	log2.Warn("获得主节点", 0, log2.String("symbol", o.Symbol))
+	if checkpoint, err := e.loadCheckpoint(); err != nil {
+		log2.Error("读取扫描检查点失败", 0, log2.String("symbol", o.Symbol), log2.AddError(err))
+	} else if local := scanner.GetScannedBlockHeight(); checkpoint != nil && checkpoint.Height > local {
+		log2.Info("接管扫描高度", 0, log2.String("symbol", o.Symbol), log2.Uint64("height", checkpoint.Height), log2.String("hash", checkpoint.Hash), log2.Uint64("local", local))
+		if err := scanner.SetRescanBlockHeight(o.checkpointResume(checkpoint)); err != nil {
+			log2.Error("设置扫描高度失败", 0, log2.String("symbol", o.Symbol), log2.AddError(err))
+		}
+	}
+	e.setLeader(true)
+	run := e.autoRun
+	if o.control != nil {
+		if state := o.control.State(); state != nil {
+			run = !state.Pause
+		}
+	}
+	if run {
+		if err := o.resumeScanner(); err != nil {
+			log2.Error("启动扫块失败", 0, log2.String("symbol", o.Symbol), log2.AddError(err))
+		}
+	}
+	for {
+		select {
+		case <-lost:
+			// 锁已失去,新主节点可能已接管,不再写入检查点
+			e.setLeader(false)
+			scanner.Pause()
+			log2.Warn("失去主节点,扫块已暂停", 0, log2.String("symbol", o.Symbol))
+			return
+		case <-e.done:
+			return
+		case <-time.After(leaderSync):
+			if e.IsLeader() {
+				e.flushCheckpoint()
+			}
+		}
+	}
+}
+
+// 读取其他实例写入的检查点,未开启检查点时返回nil
+func (e *LeaderElector) loadCheckpoint() (*ScanCheckpoint, error) {
+	if e.o.checkpoint == nil {
+		return nil, nil
+	}
+	return e.o.checkpoint.Sync()
+}
+
+// 写入最近扫描完成的区块,checkpointEvery较大时保证切换后重扫的区块不超过leaderSync内扫描的区块
+func (e *LeaderElector) flushCheckpoint() {
+	if e.o.checkpoint != nil {
+		e.o.checkpoint.Flush()
+	}
+}
+
+// Release 写入最终检查点并释放锁,备用实例随即接管,需在扫块暂停后调用
+func (e *LeaderElector) Release() {
+	if e.IsLeader() {
+		e.flushCheckpoint()
+	}
+	e.once.Do(func() {
+		close(e.done)
+	})
+	<-e.released
+	if e.IsLeader() && e.lock != nil {
+		if err := e.lock.Unlock(); err != nil {
+			log2.Error("释放主节点锁失败", 0, log2.String("symbol", e.o.Symbol), log2.AddError(err))
+		}
+	}
+	e.setLeader(false)
+}
@@ -0,0 +1,144 @@
+package open_scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+// 记录写入次数的检查点存储
+type countCheckpointStore struct {
+	CheckpointStore
+	saves int
+}
+
+func (s *countCheckpointStore) Save(checkpoint *ScanCheckpoint) error {
+	s.saves++
+	return s.CheckpointStore.Save(checkpoint)
+}
+
+// 其他实例写入高度50的检查点,本实例扫描至高度10,检查点记录至60未写入
+func newTestLeader(t *testing.T, autoRun bool) (*LeaderElector, *testBlockScanner, *countCheckpointStore, func()) {
+	dir, remove := tempDir(t)
+	store := &countCheckpointStore{CheckpointStore: &FileCheckpointStore{Dir: dir}}
+	other := NewCheckpointer(store, "BTC", 100)
+	other.Notify(&openwallet.BlockHeader{Height: 50, Hash: "h50"})
+	other.Flush()
+	store.saves = 0
+	scanner := &testBlockScanner{scanned: 10}
+	o := &OpenWScanner{Symbol: "BTC", BlockScanner: scanner, checkpoint: NewCheckpointer(store, "BTC", 100)}
+	o.checkpoint.Notify(&openwallet.BlockHeader{Height: 60, Hash: "h60"})
+	o.leader = NewLeaderElector(o, autoRun)
+	return o.leader, scanner, store, remove
+}
+
+func TestLeaderElectorLead(t *testing.T) {
+	e, scanner, store, remove := newTestLeader(t, true)
+	defer remove()
+	lost := make(chan struct{})
+	close(lost)
+	e.lead(lost)
+	// 接管检查点高度并启动扫块,失去锁后暂停
+	if scanner.rescan != 51 {
+		t.Fatalf("expected rescan from 51, got %d", scanner.rescan)
+	}
+	if !reflect.DeepEqual(scanner.calls, []string{"run", "pause"}) || e.IsLeader() {
+		t.Fatalf("unexpected scanner calls %v leader %v", scanner.calls, e.IsLeader())
+	}
+	// 失去锁后不再写入检查点
+	if store.saves != 0 {
+		t.Fatalf("unexpected checkpoint saves after lost: %d", store.saves)
+	}
+	if err := e.o.CheckLeader(); err == nil {
+		t.Fatal("expected standby error")
+	}
+}
+
+func TestLeaderElectorControl(t *testing.T) {
+	for _, c := range []struct {
+		autoRun bool
+		state   *ScannerControl
+		calls   []string
+	}{
+		{true, nil, []string{"run", "pause"}},
+		{false, nil, []string{"pause"}},
+		{true, &ScannerControl{Pause: true}, []string{"pause"}},
+		{false, &ScannerControl{}, []string{"run", "pause"}},
+	} {
+		e, scanner, _, remove := newTestLeader(t, c.autoRun)
+		e.o.control = &ControlWatcher{state: c.state}
+		lost := make(chan struct{})
+		close(lost)
+		e.lead(lost)
+		if !reflect.DeepEqual(scanner.calls, c.calls) {
+			t.Fatalf("autoRun %v state %+v: unexpected scanner calls %v", c.autoRun, c.state, scanner.calls)
+		}
+		remove()
+	}
+}
+
+func TestLeaderElectorRelease(t *testing.T) {
+	e, _, store, remove := newTestLeader(t, true)
+	defer remove()
+	close(e.released)
+	e.setLeader(true)
+	// 主节点释放锁前写入最终检查点
+	e.Release()
+	if store.saves != 1 || e.IsLeader() {
+		t.Fatalf("unexpected saves %d leader %v", store.saves, e.IsLeader())
+	}
+	if v, err := store.Load("BTC"); err != nil || v.Height != 60 {
+		t.Fatalf("unexpected checkpoint %+v (%v)", v, err)
+	}
+	select {
+	case <-e.done:
+	default:
+		t.Fatal("expected elector stopped")
+	}
+	// 备用实例不写入检查点
+	e, _, store, remove2 := newTestLeader(t, true)
+	defer remove2()
+	close(e.released)
+	e.Release()
+	if store.saves != 0 {
+		t.Fatalf("unexpected standby saves %d", store.saves)
+	}
+}
+
+func TestRescanHeight(t *testing.T) {
+	wait := rescanHeightWait
+	rescanHeightWait = 0
+	defer func() { rescanHeightWait = wait }()
+	for _, c := range []struct {
+		name     string
+		scanning bool
+		leader   bool
+		state    *ScannerControl
+		calls    []string
+	}{
+		{"standby", true, false, nil, []string{}},
+		{"running", true, true, nil, []string{"pause", "run"}},
+		{"paused", false, true, nil, []string{"pause"}},
+		{"control pause", true, true, &ScannerControl{Pause: true}, []string{"pause"}},
+		{"control run", false, true, &ScannerControl{}, []string{"pause", "run"}},
+	} {
+		scanner := &testBlockScanner{Scanning: c.scanning, calls: []string{}}
+		o := &OpenWScanner{Symbol: "BTC", BlockScanner: scanner}
+		o.leader = NewLeaderElector(o, true)
+		o.leader.setLeader(c.leader)
+		if c.state != nil {
+			o.control = &ControlWatcher{state: c.state}
+		}
+		err := o.RescanHeight(100)
+		if (err == nil) != c.leader {
+			t.Fatalf("%s: unexpected error %v", c.name, err)
+		}
+		if !reflect.DeepEqual(scanner.calls, c.calls) {
+			t.Fatalf("%s: unexpected scanner calls %v", c.name, scanner.calls)
+		}
+		if c.leader && scanner.rescan != 100 {
+			t.Fatalf("%s: expected rescan from 100, got %d", c.name, scanner.rescan)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package open_scanner
+
+import (
+	"flag"
+	"github.com/nbit99/openwallet/v2/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const (
+	metricsNamespace = "scanner"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var MetricsAddr = flag.String("metrics", ":9101", "")
+
+var (
+	scannedHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "scanned_height",
+		Help:      "当前已扫描区块高度",
+	}, []string{"symbol"})
+	// 每秒扫块数通过rate(scanner_blocks_total[1m])计算
+	scannedBlocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocks_total",
+		Help:      "已扫描区块数",
+	}, []string{"symbol"})
+	extractCallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "extract_callbacks_total",
+		Help:      "提取交易单回调次数,type为数据源类型(account/contract/receipt)",
+	}, []string{"symbol", "type"})
+	publishTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "publish_total",
+		Help:      "消息发送次数",
+	}, []string{"symbol", "sink", "result"})
+	lookupDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "lookup_duration_seconds",
+		Help:      "扫块回调地址/合约查询耗时,store为查询的存储(redis/memory/layered/cache/mongo)",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"symbol", "store", "target"})
+	rpcCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rpc_calls_total",
+		Help:      "WalletApiService调用次数",
+	}, []string{"method", "result"})
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "WalletApiService调用耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+func init() {
+	prometheus.MustRegister(scannedHeight, scannedBlocks, extractCallbacks, publishTotal, lookupDuration, rpcCalls, rpcDuration)
+}
+
+// StartMetrics 启动指标监听,addr为空时不启动
+func StartMetrics(addr string) {
+	if len(addr) == 0 {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		log.Info("指标服务启动: ", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error("指标服务启动失败: ", err.Error())
+		}
+	}()
+}
+
+// ObserveRPC 记录RPC调用次数及耗时,用法: defer ObserveRPC("Method", time.Now(), &err)
+func ObserveRPC(method string, start time.Time, err *error) {
+	result := ResultSuccess
+	if err != nil && *err != nil {
+		result = ResultFailure
+	}
+	rpcCalls.WithLabelValues(method, result).Inc()
+	rpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+func observeBlock(symbol string, height uint64) {
+	scannedHeight.WithLabelValues(symbol).Set(float64(height))
+	scannedBlocks.WithLabelValues(symbol).Inc()
+}
+
+func observeExtract(symbol, kind string) {
+	extractCallbacks.WithLabelValues(symbol, kind).Inc()
+}
+
+func observePublish(symbol, sink string, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	publishTotal.WithLabelValues(symbol, sink, result).Inc()
+}
+
+// 合约查询的存储: 进程内缓存或Mongo
+const (
+	lookupStoreCache = "cache"
+	lookupStoreMongo = "mongo"
+)
+
+func observeLookup(symbol, store, target string, start time.Time) {
+	lookupDuration.WithLabelValues(symbol, store, target).Observe(time.Since(start).Seconds())
+}
+
+// 地址索引对应的存储名称
+func indexStore(index AddressIndex) string {
+	switch index.(type) {
+	case *RedisAddressIndex:
+		return AddressIndexRedis
+	case *MemoryAddressIndex:
+		return AddressIndexMemory
+	case *LayeredAddressIndex:
+		return AddressIndexLayered
+	default:
+		return "custom"
+	}
+}
@@ -0,0 +1,118 @@
+package open_scanner
+
+import (
+	"github.com/godaddy-x/jorm/amqp"
+	"github.com/godaddy-x/jorm/util"
+	"sync"
+)
+
+// Notifier 扫块消息发送接口,负责连接的建立/重连及健康状态
+type Notifier interface {
+	// Publish 发送消息,返回nil表示消息已被接收
+	Publish(data rabbitmq.MsgData) error
+	// Health 当前连接及发送状态
+	Health() NotifierHealth
+	Close() error
+}
+
+// NotifierHealth 消息发送健康状态
+type NotifierHealth struct {
+	Connected     bool   `json:"connected"`
+	Reconnects    int64  `json:"reconnects"`
+	Published     int64  `json:"published"`
+	Failed        int64  `json:"failed"`
+	LastError     string `json:"lastError"`
+	LastErrorTime int64  `json:"lastErrorTime"`
+}
+
+func (h *NotifierHealth) setError(err error) {
+	h.LastError = err.Error()
+	h.LastErrorTime = util.Time()
+}
+
+// 未指定发送实现时使用AMQP
+func (o *OpenWScanner) initNotifier() error {
+	if o.Notifier != nil {
+		return nil
+	}
+	configs, err := o.provider().AmqpConfigs()
+	if err != nil {
+		return util.Error("读取mq配置失败: ", err.Error())
+	}
+	notifier, err := NewAmqpNotifierWithConfig(configs)
+	if err != nil {
+		return err
+	}
+	o.Notifier = notifier
+	return nil
+}
+
+// MemoryNotifier 基于内存的消息发送实现,用于单元测试及本地开发
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages []rabbitmq.MsgData
+	err      error
+	closed   bool
+	health   NotifierHealth
+}
+
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{health: NotifierHealth{Connected: true}}
+}
+
+func (m *MemoryNotifier) Publish(data rabbitmq.MsgData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return util.Error("MemoryNotifier已关闭")
+	}
+	if m.err != nil {
+		m.health.Failed++
+		m.health.setError(m.err)
+		return m.err
+	}
+	m.messages = append(m.messages, data)
+	m.health.Published++
+	return nil
+}
+
+// SetError 模拟连接异常,err为nil时恢复
+func (m *MemoryNotifier) SetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil && err == nil {
+		m.health.Reconnects++
+	}
+	m.err = err
+	m.health.Connected = err == nil
+}
+
+// Messages 已发送的消息
+func (m *MemoryNotifier) Messages() []rabbitmq.MsgData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]rabbitmq.MsgData, len(m.messages))
+	copy(result, m.messages)
+	return result
+}
+
+// Reset 清空已发送的消息
+func (m *MemoryNotifier) Reset() {
+	m.mu.Lock()
+	m.messages = nil
+	m.mu.Unlock()
+}
+
+func (m *MemoryNotifier) Health() NotifierHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.health
+}
+
+func (m *MemoryNotifier) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	m.health.Connected = false
+	m.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,252 @@
+package open_scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/godaddy-x/jorm/amqp"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/streadway/amqp"
+	"sync"
+	"time"
+)
+
+const (
+	confirmTimeout      = 10 * time.Second
+	reconnectMinBackoff = time.Second
+	reconnectMaxBackoff = time.Minute
+)
+
+// AmqpNotifier 开启发布确认的MQ发送实现,只有收到Broker的ack才视为发送成功
+// 连接断开后在后台按退避间隔重连,重连期间发送直接返回错误,由发件箱稍后重试
+type AmqpNotifier struct {
+	mu           sync.Mutex
+	config       rabbitmq.AmqpConfig
+	conn         *amqp.Connection
+	channel      *amqp.Channel
+	confirms     chan amqp.Confirmation
+	declared     map[string]bool
+	health       NotifierHealth
+	reconnecting bool
+	shutdown     bool
+	done         chan struct{}
+}
+
+// NewAmqpNotifier 读取consul中的MQ配置创建发送者并在后台建立连接
+func NewAmqpNotifier() (*AmqpNotifier, error) {
+	configs, err := new(ConsulProvider).AmqpConfigs()
+	if err != nil {
+		return nil, util.Error("读取mq配置失败: ", err.Error())
+	}
+	return NewAmqpNotifierWithConfig(configs)
+}
+
+// NewAmqpNotifierWithConfig 使用MASTER数据源配置创建发送者
+func NewAmqpNotifierWithConfig(configs []rabbitmq.AmqpConfig) (*AmqpNotifier, error) {
+	for _, v := range configs {
+		if len(v.DsName) == 0 || v.DsName == rabbitmq.MASTER {
+			n := &AmqpNotifier{config: v, done: make(chan struct{})}
+			n.reconnect()
+			return n, nil
+		}
+	}
+	return nil, util.Error("mq配置[", rabbitmq.MASTER, "]未找到")
+}
+
+// 调用方需持有锁
+func (n *AmqpNotifier) connect() error {
+	if n.channel != nil {
+		return nil
+	}
+	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s:%d/", n.config.Username, n.config.Password, n.config.Host, n.config.Port))
+	if err != nil {
+		return err
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if err := channel.Confirm(false); err != nil {
+		conn.Close()
+		return err
+	}
+	n.conn = conn
+	n.channel = channel
+	n.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	n.declared = make(map[string]bool)
+	n.health.Connected = true
+	go n.watch(conn, conn.NotifyClose(make(chan *amqp.Error, 1)))
+	return nil
+}
+
+// 监听连接断开并触发重连
+func (n *AmqpNotifier) watch(conn *amqp.Connection, closed chan *amqp.Error) {
+	err := <-closed
+	n.mu.Lock()
+	if n.conn == conn {
+		n.reset()
+	}
+	if err != nil {
+		n.health.setError(err)
+		log2.Warn("mq连接已断开", 0, log2.AddError(err))
+	}
+	n.mu.Unlock()
+	n.reconnect()
+}
+
+// 关闭当前连接,调用方需持有锁
+func (n *AmqpNotifier) reset() {
+	if n.conn != nil {
+		n.conn.Close()
+	}
+	n.conn = nil
+	n.channel = nil
+	n.confirms = nil
+	n.health.Connected = false
+}
+
+// 后台重连,同一时间只有一个重连任务
+func (n *AmqpNotifier) reconnect() {
+	n.mu.Lock()
+	if n.reconnecting || n.shutdown {
+		n.mu.Unlock()
+		return
+	}
+	n.reconnecting = true
+	n.mu.Unlock()
+	go func() {
+		backoff := reconnectMinBackoff
+		for {
+			n.mu.Lock()
+			if n.shutdown || n.channel != nil {
+				n.reconnecting = false
+				n.mu.Unlock()
+				return
+			}
+			err := n.connect()
+			if err == nil {
+				n.health.Reconnects++
+				n.reconnecting = false
+				n.mu.Unlock()
+				log2.Info("mq连接成功", 0, log2.String("host", n.config.Host))
+				return
+			}
+			n.health.setError(err)
+			n.mu.Unlock()
+			log2.Error("mq连接失败", 0, log2.String("host", n.config.Host), log2.Int64("retry", int64(backoff/time.Second)), log2.AddError(err))
+			select {
+			case <-n.done:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > reconnectMaxBackoff {
+				backoff = reconnectMaxBackoff
+			}
+		}
+	}()
+}
+
+func (n *AmqpNotifier) declare(exchange, queue string) error {
+	if n.declared[exchange+queue] {
+		return nil
+	}
+	if err := n.channel.ExchangeDeclare(exchange, rabbitmq.DIRECT, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := n.channel.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := n.channel.QueueBind(queue, queue, exchange, false, nil); err != nil {
+		return err
+	}
+	n.declared[exchange+queue] = true
+	return nil
+}
+
+// Publish 发送消息并等待Broker确认
+func (n *AmqpNotifier) Publish(data rabbitmq.MsgData) error {
+	body, err := n.body(data)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.publish(data, body); err != nil {
+		n.health.Failed++
+		n.health.setError(err)
+		return err
+	}
+	n.health.Published++
+	return nil
+}
+
+// protobuf消息以二进制数据作为消息体,事件类型及签名在消息头中,其余为MsgData JSON
+func (n *AmqpNotifier) body(data rabbitmq.MsgData) ([]byte, error) {
+	if data.Kind == ContentTypeProtobuf {
+		return protoContent(data)
+	}
+	return json.Marshal(data)
+}
+
+// 调用方需持有锁,连接异常时关闭连接由watch触发重连
+func (n *AmqpNotifier) publish(data rabbitmq.MsgData, body []byte) error {
+	if n.shutdown {
+		return util.Error("mq发送者已关闭")
+	}
+	if n.channel == nil {
+		return util.Error("mq未连接")
+	}
+	if err := n.declare(data.Exchange, data.Queue); err != nil {
+		n.reset()
+		return err
+	}
+	headers := amqp.Table{}
+	for k, v := range SignHeaders(data.Signature) {
+		headers[k] = v
+	}
+	contentType := "text/plain"
+	if data.Kind == ContentTypeProtobuf {
+		contentType = ContentTypeProtobuf
+		headers[SignatureHeader] = data.Signature
+		headers[EventTypeHeader] = data.Type
+	}
+	msg := amqp.Publishing{ContentType: contentType, DeliveryMode: amqp.Persistent, Headers: headers, Body: body}
+	if err := n.channel.Publish(data.Exchange, data.Queue, false, false, msg); err != nil {
+		n.reset()
+		return err
+	}
+	select {
+	case c, ok := <-n.confirms:
+		if !ok {
+			n.reset()
+			return util.Error("mq连接已关闭")
+		}
+		if !c.Ack {
+			return util.Error("mq拒绝消息[", c.DeliveryTag, "]")
+		}
+		return nil
+	case <-time.After(confirmTimeout):
+		n.reset()
+		return util.Error("mq发送确认超时")
+	}
+}
+
+func (n *AmqpNotifier) Health() NotifierHealth {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.health
+}
+
+// Close 停止重连并关闭连接
+func (n *AmqpNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.shutdown {
+		return nil
+	}
+	n.shutdown = true
+	close(n.done)
+	n.reset()
+	return nil
+}
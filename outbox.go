@@ -0,0 +1,401 @@
+package open_scanner
+
+import (
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/index"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/openwallet/v2/common/file"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	outboxBatch      = 100
+	outboxMinBackoff = time.Second
+	outboxMaxBackoff = time.Minute
+	outboxPoll       = 5 * time.Second
+	outboxDeadNode   = "dead"
+)
+
+// OutboxMessage 待发送的MQ消息,Data为按通道编码及签名后的内容
+type OutboxMessage struct {
+	ID    uint64 `storm:"id,increment"`
+	Data  rabbitmq.MsgData
+	Tries int64
+	Ctime int64
+}
+
+// DeadLetter 超过最大发送次数的消息,保存在死信节点(dead/<通道名称>),可查询及重新发送
+type DeadLetter struct {
+	ID    uint64 `storm:"id,increment"`
+	Data  rabbitmq.MsgData
+	Tries int64
+	Ctime int64  // 写入发件箱时间
+	Dtime int64  // 移入死信时间
+	Error string // 最后一次发送错误
+}
+
+// Outbox 本地持久化发件箱,消息先写入BoltDB,由后台分别发送到各通道直到确认后删除
+type Outbox struct {
+	symbol string
+	db     *storm.DB
+	relays []*outboxRelay
+	done   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// 单个通道的发送队列,各通道独立存储与重试,互不阻塞
+type outboxRelay struct {
+	symbol string
+	route  *SinkRoute
+	db     *storm.DB
+	node   storm.Node
+	dead   storm.Node
+	signal chan struct{}
+	done   chan struct{}
+}
+
+// NewOutbox 打开发件箱文件
+func NewOutbox(symbol, dbFile string) (*Outbox, error) {
+	db, err := storm.Open(dbFile, storm.BoltOptions(0600, &bolt.Options{Timeout: 10 * time.Second}))
+	if err != nil {
+		return nil, err
+	}
+	return &Outbox{symbol: symbol, db: db, done: make(chan struct{})}, nil
+}
+
+// AddRoute 添加发送通道,需在Start前调用
+// MQ通道使用根节点存储,其他通道使用以通道名称命名的节点
+func (b *Outbox) AddRoute(route *SinkRoute) {
+	var node storm.Node = b.db
+	if name := route.Sink.Name(); name != SinkMQ {
+		node = b.db.From(name)
+	}
+	dead := b.db.From(outboxDeadNode, route.Sink.Name())
+	b.relays = append(b.relays, &outboxRelay{symbol: b.symbol, route: route, db: b.db, node: node, dead: dead, signal: make(chan struct{}, 1), done: b.done})
+}
+
+// 初始化发件箱,文件与区块数据位于同一目录(DbPath)
+// BlockchainLocal每次读写都会独占打开区块库文件,发件箱使用独立文件避免文件锁互相等待
+func (o *OpenWScanner) initOutbox(c config.Configer) error {
+	routes, err := NewSinkRoutes(o, c)
+	if err != nil {
+		return err
+	}
+	file.MkdirAll(o.DbPath)
+	box, err := NewOutbox(o.Symbol, filepath.Join(o.DbPath, o.Symbol+"_outbox.db"))
+	if err != nil {
+		return err
+	}
+	for _, v := range routes {
+		box.AddRoute(v)
+	}
+	o.routes = routes
+	o.outbox = box
+	box.Start()
+	return nil
+}
+
+// DeadLetters 查询发送通道的死信消息
+func (o *OpenWScanner) DeadLetters(sink string, offset, limit int) ([]DeadLetter, error) {
+	if o.outbox == nil {
+		return nil, util.Error("[", o.Symbol, "]发件箱未初始化")
+	}
+	return o.outbox.DeadLetters(sink, offset, limit)
+}
+
+// ReplayDeadLetters 重新发送通道的死信消息,ids为空时重新发送全部
+func (o *OpenWScanner) ReplayDeadLetters(sink string, ids ...uint64) (int, error) {
+	if o.outbox == nil {
+		return 0, util.Error("[", o.Symbol, "]发件箱未初始化")
+	}
+	return o.outbox.Replay(sink, ids...)
+}
+
+// 消息写入发件箱,写入失败时直接发送到各通道
+// 各通道在同一事务中写入,失败时所有通道均未写入,直接发送不会与发件箱重复
+func (o *OpenWScanner) publish(data rabbitmq.MsgData) error {
+	if o.outbox != nil {
+		if err := o.outbox.Put(data); err == nil {
+			return nil
+		} else {
+			log2.Error("消息写入发件箱失败", 0, log2.String("symbol", o.Symbol), log2.String("queue", data.Queue), log2.AddError(err))
+		}
+	}
+	if len(o.routes) == 0 {
+		if o.Notifier == nil {
+			return util.Error("[", o.Symbol, "]消息发送未初始化")
+		}
+		err := o.Notifier.Publish(data)
+		observePublish(o.Symbol, SinkMQ, err)
+		return err
+	}
+	var result error
+	for _, v := range o.routes {
+		if !v.accept(data) {
+			continue
+		}
+		err := v.send(data)
+		observePublish(o.Symbol, v.Sink.Name(), err)
+		if err != nil {
+			log2.Error("消息发送失败", 0, log2.String("sink", v.Sink.Name()), log2.Int64("type", data.Type), log2.AddError(err))
+			result = err
+		}
+	}
+	return result
+}
+
+// Put 在同一事务中持久化消息到订阅该事件的全部通道,提交后唤醒发送
+func (b *Outbox) Put(data rabbitmq.MsgData) error {
+	relays := make([]*outboxRelay, 0, len(b.relays))
+	for _, v := range b.relays {
+		if v.route.accept(data) {
+			relays = append(relays, v)
+		}
+	}
+	if len(relays) == 0 {
+		return nil
+	}
+	tx, err := b.db.Bolt.Begin(true)
+	if err != nil {
+		return err
+	}
+	for _, v := range relays {
+		// 写入时按通道编码及签名,发送/重试时不再重复编码
+		encoded, err := v.route.encode(data)
+		if err != nil {
+			// 编码失败重试无效,直接移入死信
+			log2.Error("发件箱消息编码失败,已移入死信", 0, log2.String("sink", v.route.Sink.Name()), log2.Int64("type", data.Type), log2.AddError(err))
+			dead := DeadLetter{Data: data, Ctime: util.Time(), Dtime: util.Time(), Error: err.Error()}
+			if err := v.dead.WithTransaction(tx).Save(&dead); err != nil {
+				tx.Rollback()
+				return err
+			}
+			continue
+		}
+		msg := OutboxMessage{Data: encoded, Ctime: util.Time()}
+		if err := v.node.WithTransaction(tx).Save(&msg); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, v := range relays {
+		select {
+		case v.signal <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Pending 各通道未发送的消息数
+func (b *Outbox) Pending() (map[string]int, error) {
+	result := make(map[string]int)
+	for _, v := range b.relays {
+		count, err := v.node.Count(&OutboxMessage{})
+		if err != nil {
+			return nil, err
+		}
+		result[v.route.Sink.Name()] = count
+	}
+	return result, nil
+}
+
+// DeadLetters 查询通道的死信消息
+func (b *Outbox) DeadLetters(sink string, offset, limit int) ([]DeadLetter, error) {
+	r := b.relay(sink)
+	if r == nil {
+		return nil, util.Error("发送通道[", sink, "]不存在")
+	}
+	list := []DeadLetter{}
+	options := []func(*index.Options){storm.Skip(offset)}
+	if limit > 0 {
+		options = append(options, storm.Limit(limit))
+	}
+	if err := r.dead.All(&list, options...); err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Replay 死信消息重新写入通道发送队列,ids为空时重新发送全部,返回重新发送的数量
+func (b *Outbox) Replay(sink string, ids ...uint64) (int, error) {
+	r := b.relay(sink)
+	if r == nil {
+		return 0, util.Error("发送通道[", sink, "]不存在")
+	}
+	list := []DeadLetter{}
+	if len(ids) == 0 {
+		if err := r.dead.All(&list); err != nil && err != storm.ErrNotFound {
+			return 0, err
+		}
+	} else {
+		for _, id := range ids {
+			msg := DeadLetter{}
+			if err := r.dead.One("ID", id, &msg); err != nil {
+				if err == storm.ErrNotFound {
+					continue
+				}
+				return 0, err
+			}
+			list = append(list, msg)
+		}
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	tx, err := b.db.Bolt.Begin(true)
+	if err != nil {
+		return 0, err
+	}
+	for i := range list {
+		data, err := r.route.encode(list[i].Data)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		msg := OutboxMessage{Data: data, Ctime: util.Time()}
+		if err := r.node.WithTransaction(tx).Save(&msg); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if err := r.dead.WithTransaction(tx).DeleteStruct(&list[i]); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	select {
+	case r.signal <- struct{}{}:
+	default:
+	}
+	return len(list), nil
+}
+
+func (b *Outbox) relay(sink string) *outboxRelay {
+	for _, v := range b.relays {
+		if v.route.Sink.Name() == sink {
+			return v
+		}
+	}
+	return nil
+}
+
+// Start 启动后台发送
+func (b *Outbox) Start() {
+	for _, v := range b.relays {
+		b.wg.Add(1)
+		go func(r *outboxRelay) {
+			defer b.wg.Done()
+			r.relay()
+		}(v)
+	}
+}
+
+// Close 停止发送并关闭文件,未发送的消息在下次启动后继续发送
+func (b *Outbox) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+	})
+	b.wg.Wait()
+	return b.db.Close()
+}
+
+func (r *outboxRelay) relay() {
+	policy := r.route.Policy
+	if policy.MinBackoff <= 0 {
+		policy.MinBackoff = outboxMinBackoff
+	}
+	if policy.MaxBackoff < policy.MinBackoff {
+		policy.MaxBackoff = policy.MinBackoff
+	}
+	backoff := policy.MinBackoff
+	for {
+		wait := outboxPoll
+		if sent, err := r.flush(); err != nil {
+			wait = backoff
+			if backoff *= 2; backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		} else {
+			backoff = policy.MinBackoff
+			if sent == outboxBatch {
+				wait = 0
+			}
+		}
+		select {
+		case <-r.done:
+			return
+		case <-r.signal:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// 按写入顺序发送一批消息,发送失败时停止以保证顺序,超过最大发送次数的消息移入死信节点
+func (r *outboxRelay) flush() (int, error) {
+	name := r.route.Sink.Name()
+	list := []OutboxMessage{}
+	if err := r.node.All(&list, storm.Limit(outboxBatch)); err != nil {
+		if err == storm.ErrNotFound {
+			return 0, nil
+		}
+		log2.Error("读取发件箱消息失败", 0, log2.String("sink", name), log2.AddError(err))
+		return 0, err
+	}
+	for i := range list {
+		msg := list[i]
+		err := r.route.Sink.Send(msg.Data)
+		observePublish(r.symbol, name, err)
+		if err != nil {
+			tries := msg.Tries + 1
+			log2.Error("发件箱消息发送失败", 0, log2.String("sink", name), log2.Uint64("id", msg.ID), log2.Int64("tries", tries), log2.String("queue", msg.Data.Queue), log2.AddError(err))
+			if max := r.route.Policy.MaxTries; max > 0 && tries >= max {
+				if err := r.bury(&msg, tries, err); err != nil {
+					log2.Error("发件箱消息移入死信失败", 0, log2.String("sink", name), log2.Uint64("id", msg.ID), log2.AddError(err))
+					return i, err
+				}
+				log2.Error("发件箱消息超过最大发送次数,已移入死信", 0, log2.String("sink", name), log2.Uint64("id", msg.ID), log2.String("queue", msg.Data.Queue))
+				continue
+			}
+			if err := r.node.UpdateField(&msg, "Tries", tries); err != nil {
+				log2.Error("更新发件箱消息失败", 0, log2.String("sink", name), log2.Uint64("id", msg.ID), log2.AddError(err))
+			}
+			return i, err
+		}
+		if err := r.node.DeleteStruct(&msg); err != nil {
+			log2.Error("删除发件箱消息失败", 0, log2.String("sink", name), log2.Uint64("id", msg.ID), log2.AddError(err))
+			return i, err
+		}
+	}
+	return len(list), nil
+}
+
+// 消息移入死信节点,与删除在同一事务中完成
+func (r *outboxRelay) bury(msg *OutboxMessage, tries int64, cause error) error {
+	tx, err := r.db.Bolt.Begin(true)
+	if err != nil {
+		return err
+	}
+	dead := DeadLetter{Data: msg.Data, Tries: tries, Ctime: msg.Ctime, Dtime: util.Time(), Error: cause.Error()}
+	if err := r.dead.WithTransaction(tx).Save(&dead); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := r.node.WithTransaction(tx).DeleteStruct(msg); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,248 @@
+package open_scanner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/godaddy-x/jorm/amqp"
+	"github.com/nbit99/open_scanner/event"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 记录收到的消息,fail为true时发送失败
+type memorySink struct {
+	mu   sync.Mutex
+	name string
+	fail bool
+	sent []rabbitmq.MsgData
+}
+
+func (s *memorySink) Name() string {
+	return s.name
+}
+
+func (s *memorySink) Send(data rabbitmq.MsgData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errSinkFailed
+	}
+	s.sent = append(s.sent, data)
+	return nil
+}
+
+func (s *memorySink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sent)
+}
+
+type sinkError string
+
+func (e sinkError) Error() string {
+	return string(e)
+}
+
+const errSinkFailed = sinkError("sink failed")
+
+// 测试用临时目录,返回删除目录的函数
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "open_scanner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// 返回关闭发件箱并删除文件的函数
+func newTestOutbox(t *testing.T, routes ...*SinkRoute) (*Outbox, func()) {
+	dir, remove := tempDir(t)
+	box, err := NewOutbox("BTC", filepath.Join(dir, "outbox.db"))
+	if err != nil {
+		remove()
+		t.Fatal(err)
+	}
+	for _, v := range routes {
+		box.AddRoute(v)
+	}
+	return box, func() {
+		box.Close()
+		remove()
+	}
+}
+
+func TestOutboxPutRoutes(t *testing.T) {
+	mq := &SinkRoute{Sink: &memorySink{name: SinkMQ}}
+	hook := &SinkRoute{Sink: &memorySink{name: "hook"}, Events: map[int64]bool{EventBlock: true}}
+	box, closeBox := newTestOutbox(t, mq, hook)
+	defer closeBox()
+	if err := box.Put(rabbitmq.MsgData{Type: EventTx, Content: "tx"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := box.Put(rabbitmq.MsgData{Type: EventBlock, Content: "block"}); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := box.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending[SinkMQ] != 2 || pending["hook"] != 1 {
+		t.Fatalf("unexpected pending: %v", pending)
+	}
+}
+
+func TestOutboxPutAtomic(t *testing.T) {
+	mq := &SinkRoute{Sink: &memorySink{name: SinkMQ}}
+	broken := &SinkRoute{Sink: &memorySink{name: "broken"}}
+	box, closeBox := newTestOutbox(t, mq, broken)
+	defer closeBox()
+	// 通道节点下的存储桶名被普通键占用,写入该通道失败
+	if err := box.db.Bolt.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("broken"))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte("OutboxMessage"), []byte("x"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := box.Put(rabbitmq.MsgData{Type: EventTx, Content: "tx"}); err == nil {
+		t.Fatal("expected put error")
+	}
+	count, err := box.db.Count(&OutboxMessage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("message stored for mq after failed put: %d", count)
+	}
+}
+
+func TestOutboxFlush(t *testing.T) {
+	sink := &memorySink{name: SinkMQ}
+	box, closeBox := newTestOutbox(t, &SinkRoute{Sink: sink})
+	defer closeBox()
+	for i := 0; i < 3; i++ {
+		if err := box.Put(rabbitmq.MsgData{Type: EventTx, Content: "tx"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent, err := box.relays[0].flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 3 || sink.count() != 3 {
+		t.Fatalf("expected 3 messages sent, got %d/%d", sent, sink.count())
+	}
+	pending, _ := box.Pending()
+	if pending[SinkMQ] != 0 {
+		t.Fatalf("expected empty outbox, got %v", pending)
+	}
+}
+
+func TestOutboxDeadLetter(t *testing.T) {
+	sink := &memorySink{name: "hook", fail: true}
+	box, closeBox := newTestOutbox(t, &SinkRoute{Sink: sink, Policy: RetryPolicy{MaxTries: 2}})
+	defer closeBox()
+	if err := box.Put(rabbitmq.MsgData{Type: EventTx, Content: "tx1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := box.Put(rabbitmq.MsgData{Type: EventTx, Content: "tx2"}); err != nil {
+		t.Fatal(err)
+	}
+	relay := box.relays[0]
+	// 第一次失败只记录发送次数
+	if _, err := relay.flush(); err == nil {
+		t.Fatal("expected send error")
+	}
+	if list, _ := box.DeadLetters("hook", 0, 0); len(list) != 0 {
+		t.Fatalf("unexpected dead letters: %d", len(list))
+	}
+	// 达到最大发送次数后移入死信,继续发送下一条
+	if _, err := relay.flush(); err == nil {
+		t.Fatal("expected send error")
+	}
+	list, err := box.DeadLetters("hook", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Data.Content != "tx1" || list[0].Tries != 2 || list[0].Error != errSinkFailed.Error() {
+		t.Fatalf("unexpected dead letters: %+v", list)
+	}
+	pending, _ := box.Pending()
+	if pending["hook"] != 1 {
+		t.Fatalf("expected 1 pending message, got %v", pending)
+	}
+	if _, err := box.DeadLetters("unknown", 0, 0); err == nil {
+		t.Fatal("expected error for unknown sink")
+	}
+
+	// 重新发送死信,写入队列末尾
+	sink.fail = false
+	count, err := box.Replay("hook", list[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 replayed message, got %d", count)
+	}
+	if list, _ := box.DeadLetters("hook", 0, 0); len(list) != 0 {
+		t.Fatalf("dead letters left after replay: %d", len(list))
+	}
+	if _, err := relay.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sink.count() != 2 || sink.sent[0].Content != "tx2" || sink.sent[1].Content != "tx1" {
+		t.Fatalf("unexpected sent messages: %+v", sink.sent)
+	}
+}
+
+func TestOutboxEncodeOnPut(t *testing.T) {
+	sink := &memorySink{name: "hook", fail: true}
+	route := &SinkRoute{Sink: sink, ContentType: ContentTypeProtobuf, Policy: RetryPolicy{MaxTries: 3}, signer: &Signer{legacy: "secret"}}
+	box, closeBox := newTestOutbox(t, route)
+	defer closeBox()
+	b, err := json.Marshal(event.NewBlock(&openwallet.BlockHeader{Hash: "h100", Height: 100, Symbol: "BTC"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := box.Put(rabbitmq.MsgData{Type: EventBlock, Content: base64.URLEncoding.EncodeToString(b)}); err != nil {
+		t.Fatal(err)
+	}
+	// 无法解析的消息直接移入死信
+	if err := box.Put(rabbitmq.MsgData{Type: EventTx, Content: "!"}); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := box.DeadLetters("hook", 0, 0); len(list) != 1 || list[0].Data.Content != "!" {
+		t.Fatalf("unexpected dead letters: %+v", list)
+	}
+	// 写入时已编码,重试发送相同内容
+	relay := box.relays[0]
+	relay.flush()
+	sink.fail = false
+	if _, err := relay.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sink.count() != 1 || sink.sent[0].Kind != ContentTypeProtobuf {
+		t.Fatalf("unexpected sent messages: %+v", sink.sent)
+	}
+	content, _ := sink.sent[0].Content.(string)
+	v, err := event.DecodeProto(EventBlock, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block := v.(*event.Block); block.Hash != "h100" || block.Height != 100 {
+		t.Fatalf("unexpected block: %+v", block)
+	}
+	// 死信重新发送时重新编码,仍无法解析时返回错误
+	list, _ := box.DeadLetters("hook", 0, 0)
+	if _, err := box.Replay("hook", list[0].ID); err == nil {
+		t.Fatal("expected encode error on replay")
+	}
+}
@@ -0,0 +1,335 @@
+package open_scanner
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	"github.com/godaddy-x/jorm/cache/redis"
+	"github.com/godaddy-x/jorm/consul"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/sqld"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_base/major"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ProviderConsul = "consul"
+	ProviderLocal  = "local"
+
+	configEnv    = "SCANNER_CONFIG"
+	configEnvPre = "SCANNER_"
+)
+
+var ConfigFile = flag.String("config", "", "")
+
+// ConfigProvider 扫块服务配置来源
+type ConfigProvider interface {
+	Name() string
+	// InitEnv 初始化数据库/缓存/日志
+	InitEnv(symbol string) error
+	// LoadSymbol 加载币种相关配置
+	LoadSymbol(symbol string) error
+	// CoinConfig 币种ini配置及原始内容
+	CoinConfig(symbol string) (config.Configer, []byte, error)
+	Network() (*major.Network, error)
+	AmqpConfigs() ([]rabbitmq.AmqpConfig, error)
+	// SignConfig 消息签名配置,未配置时返回nil
+	SignConfig() (*SignConfig, error)
+	// UseConsul 是否使用consul服务注册/扫块器开关/配置监听
+	UseConsul() bool
+}
+
+// NewConfigProvider 指定-config参数或SCANNER_CONFIG环境变量时读取本地配置,否则读取consul
+func NewConfigProvider() ConfigProvider {
+	file := *ConfigFile
+	if len(file) == 0 {
+		file = os.Getenv(configEnv)
+	}
+	if len(file) == 0 {
+		return &ConsulProvider{}
+	}
+	return &LocalProvider{File: file}
+}
+
+// 未指定配置来源时按启动参数选择
+func (o *OpenWScanner) provider() ConfigProvider {
+	if o.Provider == nil {
+		o.Provider = NewConfigProvider()
+	}
+	return o.Provider
+}
+
+// ConsulProvider 读取consul配置
+type ConsulProvider struct {
+}
+
+func (p *ConsulProvider) Name() string {
+	return ProviderConsul
+}
+
+func (p *ConsulProvider) InitEnv(symbol string) error {
+	major.InitDB()
+	major.InitLog("scanner_" + strings.ToLower(symbol))
+	return nil
+}
+
+func (p *ConsulProvider) LoadSymbol(symbol string) error {
+	return major.LoadConsulSymbol(symbol)
+}
+
+func (p *ConsulProvider) CoinConfig(symbol string) (config.Configer, []byte, error) {
+	consulx, err := new(consul.ConsulManager).Client()
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := consulx.GetKV(util.AddStr("coin/", symbol, ".ini"))
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := config.NewConfigData("ini", result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, result, nil
+}
+
+func (p *ConsulProvider) Network() (*major.Network, error) {
+	return major.InitNetwork(), nil
+}
+
+func (p *ConsulProvider) AmqpConfigs() ([]rabbitmq.AmqpConfig, error) {
+	configs := []rabbitmq.AmqpConfig{}
+	if err := major.ReadNodeAesData(major.InitDc(), "rpc/amqp", &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+func (p *ConsulProvider) SignConfig() (*SignConfig, error) {
+	return LoadSignConfig()
+}
+
+func (p *ConsulProvider) UseConsul() bool {
+	return true
+}
+
+// LocalProvider 读取本地ini文件,用于无consul环境(如本地regtest节点)
+// File为币种ini文件,或包含<SYMBOL>.ini的目录,除币种配置外还包含以下分组:
+// [network] scanLogPath/mqSecretKey/isTestNet
+// [log] level/console/dir
+// [mongo] addrs(逗号分隔)/database/username/password/timeout/poolLimit
+// [redis] host/port/password/maxIdle/maxActive/idleTimeout
+// [amqp] host/port/username/password
+// [sign] file = 签名配置json文件(格式同consul rpc/mqsign,不加密)
+// [rpc] listen = RPC监听地址, check = 健康检查监听地址
+// 任一配置可通过环境变量SCANNER_<分组>_<配置>覆盖(默认分组为SCANNER_<配置>),不区分大小写
+type LocalProvider struct {
+	File string
+	c    config.Configer
+}
+
+func (p *LocalProvider) Name() string {
+	return ProviderLocal
+}
+
+// 读取配置文件并应用环境变量,每次返回新的配置,多币种时互不影响
+func (p *LocalProvider) load(symbol string) (config.Configer, []byte, error) {
+	file := p.File
+	if info, err := os.Stat(file); err != nil {
+		return nil, nil, err
+	} else if info.IsDir() {
+		file = filepath.Join(file, strings.ToUpper(symbol)+".ini")
+	}
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := config.NewConfigData("ini", raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := iniValues(raw)
+	for _, env := range os.Environ() {
+		i := strings.Index(env, "=")
+		if i < 0 || !strings.HasPrefix(env, configEnvPre) || env[:i] == configEnv {
+			continue
+		}
+		name := strings.ToLower(env[len(configEnvPre):i])
+		for k := range keys {
+			if strings.ToLower(strings.Replace(k, "::", "_", 1)) == name {
+				c.Set(k, env[i+1:])
+			}
+		}
+	}
+	return c, raw, nil
+}
+
+// 读取配置,文件中不存在的配置也可通过环境变量指定
+func (p *LocalProvider) value(key string) string {
+	env := configEnvPre + strings.ToUpper(strings.Replace(key, "::", "_", 1))
+	if v, ok := os.LookupEnv(env); ok {
+		return v
+	}
+	if p.c == nil {
+		return ""
+	}
+	return p.c.String(key)
+}
+
+func (p *LocalProvider) int(key string, def int) int {
+	v := p.value(key)
+	if len(v) == 0 {
+		return def
+	}
+	i, err := util.StrToInt(v)
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+func (p *LocalProvider) InitEnv(symbol string) error {
+	// 进程级配置(日志/redis/mongo等)只读取一次
+	if p.c == nil {
+		c, _, err := p.load(symbol)
+		if err != nil {
+			return util.Error("读取本地配置失败: ", err.Error())
+		}
+		p.c = c
+	}
+	logConfig := &log2.ZapConfig{Level: p.value("log::level"), Console: true}
+	if len(logConfig.Level) == 0 {
+		logConfig.Level = "info"
+	}
+	if v := p.value("log::console"); len(v) > 0 {
+		logConfig.Console = v == "true" || v == "1"
+	}
+	if dir := p.value("log::dir"); len(dir) > 0 {
+		logConfig.FileConfig = &log2.FileConfig{Filename: filepath.Join(dir, "scanner_"+strings.ToLower(symbol)+".log"), MaxSize: 512, MaxBackups: 7, MaxAge: 7, Compress: true}
+	}
+	log2.InitDefaultLog(logConfig)
+	redisConfig := cache.RedisConfig{
+		Host:        p.value("redis::host"),
+		Port:        p.int("redis::port", 6379),
+		Password:    p.value("redis::password"),
+		MaxIdle:     p.int("redis::maxIdle", 10),
+		MaxActive:   p.int("redis::maxActive", 100),
+		IdleTimeout: p.int("redis::idleTimeout", 60),
+		Network:     "tcp",
+	}
+	if len(redisConfig.Host) == 0 {
+		return util.Error("本地配置[redis::host]为空")
+	}
+	if _, err := new(cache.RedisManager).InitConfig(redisConfig); err != nil {
+		return err
+	}
+	client, err := new(cache.RedisManager).Client()
+	if err != nil {
+		return err
+	}
+	mongoConfig := sqld.MGOConfig{
+		Addrs:     strings.Split(p.value("mongo::addrs"), ","),
+		Database:  p.value("mongo::database"),
+		Username:  p.value("mongo::username"),
+		Password:  p.value("mongo::password"),
+		Timeout:   int64(p.int("mongo::timeout", 10)),
+		PoolLimit: p.int("mongo::poolLimit", 100),
+	}
+	if len(p.value("mongo::addrs")) == 0 || len(mongoConfig.Database) == 0 {
+		return util.Error("本地配置[mongo::addrs/mongo::database]为空")
+	}
+	return new(sqld.MGOManager).InitConfigAndCache(client, mongoConfig)
+}
+
+func (p *LocalProvider) LoadSymbol(symbol string) error {
+	return nil
+}
+
+func (p *LocalProvider) CoinConfig(symbol string) (config.Configer, []byte, error) {
+	return p.load(symbol)
+}
+
+func (p *LocalProvider) Network() (*major.Network, error) {
+	network := &major.Network{
+		IsLoad:      true,
+		IsTestNet:   p.value("network::isTestNet") == "true",
+		ScanLogPath: p.value("network::scanLogPath"),
+		MQSecretKey: p.value("network::mqSecretKey"),
+	}
+	if len(network.ScanLogPath) == 0 {
+		network.ScanLogPath = "logs"
+	}
+	return network, nil
+}
+
+func (p *LocalProvider) AmqpConfigs() ([]rabbitmq.AmqpConfig, error) {
+	conf := rabbitmq.AmqpConfig{
+		DsName:   rabbitmq.MASTER,
+		Host:     p.value("amqp::host"),
+		Port:     p.int("amqp::port", 5672),
+		Username: p.value("amqp::username"),
+		Password: p.value("amqp::password"),
+	}
+	if len(conf.Host) == 0 {
+		return nil, util.Error("本地配置[amqp::host]为空")
+	}
+	return []rabbitmq.AmqpConfig{conf}, nil
+}
+
+func (p *LocalProvider) SignConfig() (*SignConfig, error) {
+	file := p.value("sign::file")
+	if len(file) == 0 {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, util.Error("读取签名配置失败: ", err.Error())
+	}
+	conf := &SignConfig{}
+	if err := json.Unmarshal(b, conf); err != nil {
+		return nil, util.Error("读取签名配置失败: ", err.Error())
+	}
+	return conf, nil
+}
+
+func (p *LocalProvider) UseConsul() bool {
+	return false
+}
+
+// 本地模式不注册consul,直接监听RPC及健康检查
+func (o *OpenWScanner) serveLocal(msg string) {
+	if err := rpc.Register(o.Walletapi); err != nil {
+		panic(util.AddStr("注册RPC服务失败: ", err.Error()))
+	}
+	serveLocalRPC(o.currentConfig())
+	o.waitShutdown(nil, "", msg)
+}
+
+// 监听已注册的RPC服务(gob编码,与consul注册的服务一致)及健康检查,地址读取ini(rpc::listen/rpc::check)
+func serveLocalRPC(c config.Configer) {
+	listen, check := ":18080", ":18081"
+	if c != nil {
+		listen = c.DefaultString("rpc::listen", listen)
+		check = c.DefaultString("rpc::check", check)
+	}
+	l, err := net.Listen("tcp", listen)
+	if err != nil {
+		panic(util.AddStr("RPC监听服务异常: ", err.Error()))
+	}
+	go rpc.Accept(l)
+	registerHealthHandlers()
+	registerSchemaHandler()
+	go func() {
+		if err := http.ListenAndServe(check, nil); err != nil {
+			log2.Error("健康检查服务启动失败", 0, log2.String("listen", check), log2.AddError(err))
+		}
+	}()
+}
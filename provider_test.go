@@ -0,0 +1,32 @@
+package open_scanner
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalProviderCoinConfig(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	for symbol, dataDir := range map[string]string{"BTC": "/data/btc", "ETH": "/data/eth"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, symbol+".ini"), []byte("dataDir = "+dataDir+"\n[redis]\nhost = "+symbol+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &LocalProvider{File: dir}
+	btc, _, err := p.CoinConfig("BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eth, _, err := p.CoinConfig("ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if btc.String("dataDir") != "/data/btc" || eth.String("dataDir") != "/data/eth" {
+		t.Fatalf("unexpected dataDir: %s/%s", btc.String("dataDir"), eth.String("dataDir"))
+	}
+	if p.c != nil {
+		t.Fatal("coin config replaced the process config")
+	}
+}
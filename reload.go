@@ -0,0 +1,336 @@
+package open_scanner
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/consul"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	consulapi "github.com/hashicorp/consul/api"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	reloadDrainTimeout = 30 * time.Second
+	reloadMask         = "******"
+)
+
+// 整数配置项及最小值,热加载前校验
+var configInts = map[string]int64{
+	"addressIndexRefresh": 0,
+	"alertLag":            0,
+	"alertStall":          0,
+	"alertInterval":       1,
+	"lookupCache":         0,
+	"lookupCacheTTL":      0,
+	"lookupCacheMissTTL":  0,
+	"confirmations":       0,
+	"maxLag":              0,
+	"shutdownTimeout":     0,
+	"txBatchTimeout":      1,
+	"checkpointEvery":     1,
+	"reorgWindow":         1,
+	"dedupeKeep":          1,
+	"rescanJobs":          1,
+	"rescanMaxRange":      1,
+}
+
+// 布尔配置项
+var configBools = []string{"txBatch", "leaderElection", "lookupCacheSync"}
+
+// 枚举配置项及可选值
+var configEnums = map[string][]string{
+	"addressIndex": {AddressIndexRedis, AddressIndexMemory, AddressIndexLayered},
+	"checkpoint":   {CheckpointFile, CheckpointMongo, CheckpointOff},
+	"dedupe":       {DedupeOff, DedupeSuppress, DedupeFlag},
+}
+
+// ConfigChange 配置变更项,old/new为空表示新增/删除
+type ConfigChange struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// ConfigWatcher 通过consul阻塞查询监听coin/<SYMBOL>.ini,校验后重新加载到资产适配器
+type ConfigWatcher struct {
+	mu   sync.Mutex
+	o    *OpenWScanner
+	key  string
+	raw  []byte
+	done chan struct{}
+	once sync.Once
+}
+
+func NewConfigWatcher(o *OpenWScanner, raw []byte) *ConfigWatcher {
+	return &ConfigWatcher{o: o, key: util.AddStr("coin/", o.Symbol, ".ini"), raw: raw, done: make(chan struct{})}
+}
+
+// 启动币种配置监听
+func (o *OpenWScanner) startConfigWatcher() {
+	raw := o.currentConfigRaw()
+	if o.reload != nil || raw == nil {
+		return
+	}
+	o.reload = NewConfigWatcher(o, raw)
+	o.reload.Start()
+}
+
+func (w *ConfigWatcher) Start() {
+	go w.watch()
+}
+
+func (w *ConfigWatcher) Stop() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+func (w *ConfigWatcher) watch() {
+	consulx, err := new(consul.ConsulManager).Client()
+	if err != nil {
+		log2.Error("币种配置监听启动失败", 0, log2.String("key", w.key), log2.AddError(err))
+		return
+	}
+	kv := consulx.Consulx.KV()
+	index := uint64(0)
+	backoff := controlMinBackoff
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+		pair, meta, err := kv.Get(w.key, &consulapi.QueryOptions{WaitIndex: index, WaitTime: controlWait})
+		if err != nil {
+			log2.Error("监听币种配置失败", 0, log2.String("key", w.key), log2.AddError(err))
+			select {
+			case <-w.done:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > controlMaxBackoff {
+				backoff = controlMaxBackoff
+			}
+			continue
+		}
+		backoff = controlMinBackoff
+		if meta.LastIndex < index {
+			index = 0
+			continue
+		}
+		if meta.LastIndex == index {
+			continue
+		}
+		index = meta.LastIndex
+		if pair == nil || len(pair.Value) == 0 {
+			log2.Warn("币种配置已删除,保留当前配置", 0, log2.String("key", w.key))
+			continue
+		}
+		if err := w.Reload(pair.Value); err != nil {
+			log2.Error("币种配置重新加载失败,保留当前配置", 0, log2.String("key", w.key), log2.AddError(err))
+		}
+	}
+}
+
+// Reload 校验并应用新配置,内容未变化时不处理
+func (w *ConfigWatcher) Reload(raw []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if bytes.Equal(w.raw, raw) {
+		return nil
+	}
+	o := w.o
+	c, err := validateConfig(o, raw)
+	if err != nil {
+		return err
+	}
+	changes := diffConfig(w.raw, raw)
+	if len(changes) == 0 {
+		w.raw = raw
+		return nil
+	}
+	log2.Info("币种配置变更", 0, log2.String("symbol", o.Symbol), log2.Any("changes", changes))
+	assetsMgr, err := GetAssetsManager(o.Symbol)
+	if err != nil {
+		return err
+	}
+	// 扫块中时暂停并等待当前回调完成,避免扫块使用一半新配置
+	resume := false
+	if o.BlockScanner != nil {
+		if running, _ := scannerRunning(o.BlockScanner); running {
+			if err := o.BlockScanner.Pause(); err != nil {
+				return err
+			}
+			resume = true
+			if !waitUntil(time.Now().Add(reloadDrainTimeout), func() bool { return o.callbacks.idle(shutdownQuiet) }) {
+				log2.Warn("等待扫块回调完成超时", 0, log2.String("symbol", o.Symbol))
+			}
+		}
+	}
+	// 热加载重新应用的配置项,其余启动时读取的配置项需重启生效
+	hot := newKeyRecorder(c)
+	prev := o.currentConfig()
+	err = assetsMgr.LoadAssetsConfig(hot)
+	if err == nil {
+		o.setConfig(c, raw)
+		w.raw = raw
+		o.lookups.Purge()
+		if o.lagMonitor != nil {
+			o.lagMonitor.Apply(hot)
+		}
+		for _, k := range restartChanges(changes, o.restartKeys, hot.Keys(), prev, c) {
+			log2.Warn("配置项需重启生效", 0, log2.String("symbol", o.Symbol), log2.String("key", k))
+		}
+	}
+	// 重新加载期间通过开关暂停的不恢复
+	if resume && (o.control == nil || o.control.State() == nil || !o.control.State().Pause) {
+		if err := o.BlockScanner.Restart(); err != nil {
+			log2.Error("恢复扫块失败", 0, log2.String("symbol", o.Symbol), log2.AddError(err))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	log2.Info("币种配置重新加载成功", 0, log2.String("symbol", o.Symbol), log2.Int("changes", len(changes)))
+	return nil
+}
+
+// 校验新配置: 可解析,dataDir与当前一致,数值/枚举/发送通道配置有效
+func validateConfig(o *OpenWScanner, raw []byte) (config.Configer, error) {
+	c, err := config.NewConfigData("ini", raw)
+	if err != nil {
+		return nil, err
+	}
+	if dataDir := c.String("dataDir"); len(dataDir) == 0 {
+		return nil, util.Error("[", o.Symbol, "]dataDir为空")
+	} else if dataDir != o.DbPath {
+		return nil, util.Error("[", o.Symbol, "]dataDir[", dataDir, "]不支持运行中修改")
+	}
+	for k, min := range configInts {
+		if _, err := configInt(c, k, 0, min); err != nil {
+			return nil, util.Error("[", o.Symbol, "]", err.Error())
+		}
+	}
+	for _, k := range configBools {
+		if v := c.String(k); len(v) > 0 {
+			if _, err := c.Bool(k); err != nil {
+				return nil, util.Error("[", o.Symbol, "]配置项[", k, "]不是布尔值: ", v)
+			}
+		}
+	}
+	for k, values := range configEnums {
+		if v := c.String(k); len(v) > 0 && !containsString(values, v) {
+			return nil, util.Error("[", o.Symbol, "]配置项[", k, "]不支持: ", v, ", 可选值: ", strings.Join(values, "|"))
+		}
+	}
+	names, err := sinkNames(c)
+	if err != nil {
+		return nil, util.Error("[", o.Symbol, "]", err.Error())
+	}
+	for _, name := range names {
+		if _, _, err := sinkRoute(c, name); err != nil {
+			return nil, util.Error("[", o.Symbol, "]", err.Error())
+		}
+	}
+	return c, nil
+}
+
+// 需重启生效的变更项: 启动时读取且热加载未重新应用的配置项,以及新旧配置中发送通道分组下的配置项
+func restartChanges(changes []ConfigChange, restart, hot map[string]bool, prev, next config.Configer) []string {
+	sections := make(map[string]bool)
+	for _, c := range []config.Configer{prev, next} {
+		if c == nil {
+			continue
+		}
+		if names, err := sinkNames(c); err == nil {
+			for _, v := range names {
+				sections[v] = true
+			}
+		}
+	}
+	keys := make([]string, 0)
+	for _, v := range changes {
+		key := strings.ToLower(v.Key)
+		if i := strings.Index(key, "::"); i > 0 && (sections[key[:i]] || restart[key[:i]+"::*"]) {
+			keys = append(keys, v.Key)
+		} else if restart[key] && !hot[key] {
+			keys = append(keys, v.Key)
+		}
+	}
+	return keys
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// 比较两份ini的键值,密钥类配置不输出原值
+func diffConfig(prev, next []byte) []ConfigChange {
+	a, b := iniValues(prev), iniValues(next)
+	changes := []ConfigChange{}
+	for k, v := range b {
+		if ov, ok := a[k]; !ok || ov != v {
+			changes = append(changes, maskChange(ConfigChange{Key: k, Old: ov, New: v}))
+		}
+	}
+	for k, v := range a {
+		if _, ok := b[k]; !ok {
+			changes = append(changes, maskChange(ConfigChange{Key: k, Old: v}))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+func maskChange(change ConfigChange) ConfigChange {
+	key := strings.ToLower(change.Key)
+	for _, v := range []string{"secret", "password", "passwd", "token", "key"} {
+		if strings.Contains(key, v) {
+			if len(change.Old) > 0 {
+				change.Old = reloadMask
+			}
+			if len(change.New) > 0 {
+				change.New = reloadMask
+			}
+			break
+		}
+	}
+	return change
+}
+
+// 读取ini键值,分组内的键为section::key
+func iniValues(raw []byte) map[string]string {
+	result := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		if len(section) > 0 && section != "default" {
+			key = section + "::" + key
+		}
+		result[key] = strings.Trim(strings.TrimSpace(line[i+1:]), `"`)
+	}
+	return result
+}
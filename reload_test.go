@@ -0,0 +1,67 @@
+package open_scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	o := &OpenWScanner{Symbol: "BTC", DbPath: "/data/btc"}
+	if _, err := validateConfig(o, []byte("dataDir = /data/btc\nalertLag = 10\ntxBatch = true\ndedupe = suppress\nsinks = hook\n[hook]\ntype = webhook\nmaxTries = 3\n")); err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range []string{
+		"dataDir = /data/other\n",
+		"dataDir = /data/btc\nalertLag = ten\n",
+		"dataDir = /data/btc\ncheckpointEvery = 0\n",
+		"dataDir = /data/btc\nalertStall = -1\n",
+		"dataDir = /data/btc\nleaderElection = maybe\n",
+		"dataDir = /data/btc\naddressIndex = mysql\n",
+		"dataDir = /data/btc\nsinks = hook\n[hook]\ntype = smtp\n",
+		"dataDir = /data/btc\nsinks = hook\n[hook]\ntype = webhook\nevents = block,unknown\n",
+		"dataDir = /data/btc\nsinks = hook\n[hook]\ntype = webhook\nmaxBackoff = x\n",
+	} {
+		if _, err := validateConfig(o, []byte(raw)); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestRestartChanges(t *testing.T) {
+	prev := newTestConfig(t, "sinks = hook\nalertLag = 10\ntxBatch = false\n[hook]\ntype = webhook\nurl = http://127.0.0.1/a\n")
+	next := newTestConfig(t, "sinks = hook\nalertLag = 20\ntxBatch = true\n[hook]\ntype = webhook\nurl = http://127.0.0.1/b\n")
+
+	// 启动时读取的配置项
+	rec := newKeyRecorder(prev)
+	rec.DefaultBool("txBatch", false)
+	rec.DefaultInt64("alertLag", 0)
+	if _, err := NewSinkRoutes(&OpenWScanner{Symbol: "BTC"}, rec); err != nil {
+		t.Fatal(err)
+	}
+	restart := rec.Keys()
+	if !restart["txbatch"] || !restart["hook::url"] || !restart["sinks"] {
+		t.Fatalf("unexpected recorded keys: %v", restart)
+	}
+	// 热加载时重新应用告警配置
+	hot := newKeyRecorder(next)
+	NewLagMonitor(&OpenWScanner{Symbol: "BTC"}, hot)
+
+	changes := diffConfig([]byte("sinks = hook\nalertLag = 10\ntxBatch = false\nServerAPI = a\n[hook]\nurl = http://127.0.0.1/a\n"),
+		[]byte("sinks = hook\nalertLag = 20\ntxBatch = true\nServerAPI = b\n[hook]\nurl = http://127.0.0.1/b\ntimeout = 5\n"))
+	keys := restartChanges(changes, restart, hot.Keys(), prev, next)
+	if expect := []string{"hook::timeout", "hook::url", "txBatch"}; !reflect.DeepEqual(keys, expect) {
+		t.Fatalf("expected %v, got %v", expect, keys)
+	}
+}
+
+func TestCurrentConfig(t *testing.T) {
+	o := &OpenWScanner{Symbol: "BTC"}
+	if o.currentConfig() != nil || o.currentConfigRaw() != nil {
+		t.Fatal("expected nil config before load")
+	}
+	c := newTestConfig(t, "maxLag = 5\n")
+	o.setConfig(c, []byte("maxLag = 5\n"))
+	if o.currentConfig() != c || string(o.currentConfigRaw()) != "maxLag = 5\n" {
+		t.Fatal("unexpected config")
+	}
+}
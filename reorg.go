@@ -0,0 +1,156 @@
+package open_scanner
+
+import (
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	"sort"
+	"sync"
+)
+
+const (
+	reorgWindowSize = 64
+)
+
+// BlockRollback 区块回滚消息,Blocks为已被孤立的区块及其中已发送的交易单,下游据此回退已入账的余额
+type BlockRollback struct {
+	Symbol string          `json:"symbol"`
+	Height uint64          `json:"height"` // 检测到分叉的区块高度
+	Hash   string          `json:"hash"`   // 检测到分叉的区块hash
+	Blocks []RollbackBlock `json:"blocks"`
+}
+
+// RollbackBlock 被孤立的区块
+type RollbackBlock struct {
+	Height uint64   `json:"height"`
+	Hash   string   `json:"hash"`
+	TxIDs  []string `json:"txids"`
+}
+
+// ReorgWindow 最近区块头窗口,通过父hash检测链重组
+// 窗口只保存在内存中,重启后从新扫描的区块开始跟踪
+type ReorgWindow struct {
+	mu      sync.Mutex
+	size    uint64
+	headers map[uint64]*openwallet.BlockHeader
+	txs     map[string]*windowTxs // 区块hash -> 交易单,交易单可能先于区块头通知
+}
+
+type windowTxs struct {
+	height uint64
+	txids  []string
+}
+
+func NewReorgWindow(size uint64) *ReorgWindow {
+	if size == 0 {
+		size = reorgWindowSize
+	}
+	return &ReorgWindow{size: size, headers: make(map[uint64]*openwallet.BlockHeader), txs: make(map[string]*windowTxs)}
+}
+
+// 按币种配置初始化重组窗口: reorgWindow = 窗口区块数
+func (o *OpenWScanner) initReorgWindow(c config.Configer) {
+	if o.reorg == nil {
+		o.reorg = NewReorgWindow(uint64(c.DefaultInt64("reorgWindow", reorgWindowSize)))
+	}
+}
+
+// AddTx 记录区块中已发送的交易单
+func (w *ReorgWindow) AddTx(height uint64, blockHash, txid string) {
+	if len(blockHash) == 0 || len(txid) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	v, ok := w.txs[blockHash]
+	if !ok {
+		v = &windowTxs{height: height}
+		w.txs[blockHash] = v
+	}
+	for _, id := range v.txids {
+		if id == txid {
+			return
+		}
+	}
+	v.txids = append(v.txids, txid)
+}
+
+// AddHeader 加入新区块头,返回被孤立的区块(按高度从低到高),未发生重组时返回空
+// 1. 同一高度出现不同hash: 该高度及以上的区块被孤立
+// 2. 父区块hash不一致: 父区块及以上的区块被孤立,更低的区块在扫块器回退重扫时按规则1检测
+// 3. 扫块器标记为分叉的区块: 该区块及以上的区块被孤立
+func (w *ReorgWindow) AddHeader(header *openwallet.BlockHeader) []RollbackBlock {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var from uint64
+	fork := false
+	if old, ok := w.headers[header.Height]; ok {
+		// 分叉区块与窗口中一致,或同一高度出现不同hash
+		if header.Fork == (old.Hash == header.Hash) {
+			from, fork = header.Height, true
+		}
+	} else if header.Height > 0 && !header.Fork {
+		if parent, ok := w.headers[header.Height-1]; ok && parent.Hash != header.Previousblockhash {
+			from, fork = header.Height-1, true
+		}
+	}
+	result := make([]RollbackBlock, 0)
+	if fork {
+		heights := make([]uint64, 0)
+		for h := range w.headers {
+			if h >= from {
+				heights = append(heights, h)
+			}
+		}
+		sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+		for _, h := range heights {
+			old := w.headers[h]
+			block := RollbackBlock{Height: h, Hash: old.Hash, TxIDs: []string{}}
+			if v, ok := w.txs[old.Hash]; ok {
+				block.TxIDs = v.txids
+				delete(w.txs, old.Hash)
+			}
+			result = append(result, block)
+			delete(w.headers, h)
+		}
+	}
+	if !header.Fork {
+		c := *header
+		w.headers[header.Height] = &c
+	}
+	w.prune(header.Height)
+	return result
+}
+
+// 移除窗口外的区块头及交易单
+func (w *ReorgWindow) prune(height uint64) {
+	if height < w.size {
+		return
+	}
+	min := height - w.size + 1
+	for h := range w.headers {
+		if h < min {
+			delete(w.headers, h)
+		}
+	}
+	for k, v := range w.txs {
+		if v.height < min {
+			delete(w.txs, k)
+		}
+	}
+}
+
+// 发送区块回滚消息
+func (o *OpenWScanner) notifyRollback(header *openwallet.BlockHeader, blocks []RollbackBlock) {
+	result := BlockRollback{Symbol: o.Symbol, Height: header.Height, Hash: header.Hash, Blocks: blocks}
+	log2.Warn("检测到链重组", 0, log2.String("symbol", o.Symbol), log2.Uint64("height", header.Height), log2.String("hash", header.Hash), log2.Any("blocks", blocks))
+	ret, sig, err := o.Signer.Sign(result)
+	if err != nil {
+		log2.Warn(err.Error(), 0, log2.Any("content", result))
+		return
+	}
+	if err := o.publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + o.Symbol, Type: EventRollback, Content: ret, Signature: sig}); err != nil {
+		log2.Error("区块回滚数据发送MQ异常", 0, log2.String("symbol", o.Symbol), log2.Any("content", result), log2.AddError(err))
+	}
+}
@@ -0,0 +1,92 @@
+package open_scanner
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+func testHeader(height uint64, hash, prev string) *openwallet.BlockHeader {
+	return &openwallet.BlockHeader{Height: height, Hash: hash, Previousblockhash: prev}
+}
+
+// 高度1-3的区块h1-h3,每个区块一条交易单
+func newTestReorgWindow(size uint64) *ReorgWindow {
+	w := NewReorgWindow(size)
+	for h := uint64(1); h <= 3; h++ {
+		hash := fmt.Sprint("h", h)
+		w.AddTx(h, hash, fmt.Sprint("tx", h))
+		w.AddHeader(testHeader(h, hash, fmt.Sprint("h", h-1)))
+	}
+	return w
+}
+
+// 窗口中的区块hash,按高度排序
+func windowHashes(w *ReorgWindow) []string {
+	heights := make([]uint64, 0, len(w.headers))
+	for h := range w.headers {
+		heights = append(heights, h)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	hashes := make([]string, 0, len(heights))
+	for _, h := range heights {
+		hashes = append(hashes, w.headers[h].Hash)
+	}
+	return hashes
+}
+
+func TestReorgWindowAddHeader(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		header   *openwallet.BlockHeader
+		rollback []RollbackBlock
+		window   []string
+	}{
+		{"next block", testHeader(4, "h4", "h3"), []RollbackBlock{}, []string{"h1", "h2", "h3", "h4"}},
+		{"same block again", testHeader(3, "h3", "h2"), []RollbackBlock{}, []string{"h1", "h2", "h3"}},
+		{"same height new hash", testHeader(2, "x2", "h1"),
+			[]RollbackBlock{{Height: 2, Hash: "h2", TxIDs: []string{"tx2"}}, {Height: 3, Hash: "h3", TxIDs: []string{"tx3"}}}, []string{"h1", "x2"}},
+		{"parent mismatch", testHeader(4, "x4", "x3"),
+			[]RollbackBlock{{Height: 3, Hash: "h3", TxIDs: []string{"tx3"}}}, []string{"h1", "h2", "x4"}},
+		{"fork replay", &openwallet.BlockHeader{Height: 2, Hash: "h2", Fork: true},
+			[]RollbackBlock{{Height: 2, Hash: "h2", TxIDs: []string{"tx2"}}, {Height: 3, Hash: "h3", TxIDs: []string{"tx3"}}}, []string{"h1"}},
+		{"fork of unknown block", &openwallet.BlockHeader{Height: 2, Hash: "x2", Fork: true}, []RollbackBlock{}, []string{"h1", "h2", "h3"}},
+		{"unknown parent", testHeader(6, "h6", "h5"), []RollbackBlock{}, []string{"h1", "h2", "h3", "h6"}},
+	} {
+		w := newTestReorgWindow(10)
+		rollback := w.AddHeader(c.header)
+		if !reflect.DeepEqual(rollback, c.rollback) {
+			t.Fatalf("%s: expected rollback %+v, got %+v", c.name, c.rollback, rollback)
+		}
+		if hashes := windowHashes(w); !reflect.DeepEqual(hashes, c.window) {
+			t.Fatalf("%s: expected window %v, got %v", c.name, c.window, hashes)
+		}
+		// 已回滚区块的交易单不再保留
+		for _, v := range rollback {
+			if _, ok := w.txs[v.Hash]; ok {
+				t.Fatalf("%s: txs of %s left in window", c.name, v.Hash)
+			}
+		}
+	}
+}
+
+func TestReorgWindowPrune(t *testing.T) {
+	w := newTestReorgWindow(2)
+	if hashes := windowHashes(w); !reflect.DeepEqual(hashes, []string{"h2", "h3"}) {
+		t.Fatalf("unexpected window %v", hashes)
+	}
+	if _, ok := w.txs["h1"]; ok || len(w.txs) != 2 {
+		t.Fatalf("unexpected txs %v", w.txs)
+	}
+	// 窗口外的区块不再检测
+	if rollback := w.AddHeader(testHeader(1, "x1", "h0")); len(rollback) != 0 {
+		t.Fatalf("unexpected rollback %+v", rollback)
+	}
+	w.AddTx(2, "h2", "tx2")
+	if len(w.txs["h2"].txids) != 1 {
+		t.Fatal("expected duplicate tx ignored")
+	}
+}
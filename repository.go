@@ -0,0 +1,75 @@
+package open_scanner
+
+import (
+	"github.com/nbit99/open_base/model"
+)
+
+var defaultRepository Repository = new(MongoRepository)
+
+// Repository 钱包数据访问接口(钱包/账户/地址/地址合约/交易记录)
+type Repository interface {
+	// 查询钱包
+	FindWallet(filter *Filter, wallet *model.OwWallet) error
+	// 查询资产账户
+	FindAccount(filter *Filter, account *model.OwAccount) error
+	// 分页查询资产账户,limit<=0时查询全部
+	FindAccountList(filter *Filter, offset, limit int) ([]*model.OwAccount, error)
+	// 查询地址
+	FindAddress(filter *Filter, address *model.OwAddress) error
+	// 分页查询地址,limit<=0时查询全部
+	FindAddressList(filter *Filter, offset, limit int) ([]*model.OwAddress, error)
+	// 更新地址
+	UpdateAddress(address *model.OwAddress) error
+	// 分页查询地址合约,limit<=0时查询全部(跳过查询失败的页)
+	FindAddressTokenList(filter *Filter, offset, limit int) ([]*model.OwAddressToken, error)
+	// 查询临时交易记录
+	FindTradeLogTmpList(filter *Filter) ([]*model.OwTradeLogTmp, error)
+	// 查询交易记录
+	FindTradeLogList(filter *Filter) ([]*model.OwTradeLog, error)
+}
+
+// SetDefaultRepository 设置默认的数据访问实现,NewWrapper未指定时使用
+func SetDefaultRepository(repo Repository) {
+	if repo != nil {
+		defaultRepository = repo
+	}
+}
+
+// GetDefaultRepository 获取默认的数据访问实现
+func GetDefaultRepository() Repository {
+	return defaultRepository
+}
+
+type filterCond struct {
+	key   string
+	value interface{}
+	not   bool
+}
+
+// Filter 仓储查询条件,键为数据库字段名
+type Filter struct {
+	conds []filterCond
+}
+
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+// Eq 等于
+func (f *Filter) Eq(key string, value interface{}) *Filter {
+	f.conds = append(f.conds, filterCond{key: key, value: value})
+	return f
+}
+
+// NotEq 不等于
+func (f *Filter) NotEq(key string, value interface{}) *Filter {
+	f.conds = append(f.conds, filterCond{key: key, value: value, not: true})
+	return f
+}
+
+// Clone 复制查询条件
+func (f *Filter) Clone() *Filter {
+	conds := make([]filterCond, len(f.conds))
+	copy(conds, f.conds)
+	return &Filter{conds: conds}
+}
@@ -0,0 +1,220 @@
+package open_scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_base/model"
+	"sync"
+)
+
+// MemoryRepository 基于内存的数据访问实现,用于单元测试及本地开发
+type MemoryRepository struct {
+	mu            sync.RWMutex
+	id            int64
+	wallets       []*model.OwWallet
+	accounts      []*model.OwAccount
+	addresses     []*model.OwAddress
+	addressTokens []*model.OwAddressToken
+	tradeLogTmps  []*model.OwTradeLogTmp
+	tradeLogs     []*model.OwTradeLog
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+// Save 写入数据,Id为0时自动分配
+func (r *MemoryRepository) Save(datas ...interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range datas {
+		switch data := v.(type) {
+		case *model.OwWallet:
+			data.Id = r.nextID(data.Id)
+			r.wallets = append(r.wallets, data)
+		case *model.OwAccount:
+			data.Id = r.nextID(data.Id)
+			r.accounts = append(r.accounts, data)
+		case *model.OwAddress:
+			data.Id = r.nextID(data.Id)
+			r.addresses = append(r.addresses, data)
+		case *model.OwAddressToken:
+			data.Id = r.nextID(data.Id)
+			r.addressTokens = append(r.addressTokens, data)
+		case *model.OwTradeLogTmp:
+			data.Id = r.nextID(data.Id)
+			r.tradeLogTmps = append(r.tradeLogTmps, data)
+		case *model.OwTradeLog:
+			data.Id = r.nextID(data.Id)
+			r.tradeLogs = append(r.tradeLogs, data)
+		default:
+			return util.Error("MemoryRepository不支持的数据类型: ", fmt.Sprintf("%T", v))
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) FindWallet(filter *Filter, wallet *model.OwWallet) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, v := range r.wallets {
+		if filter.match(v) {
+			*wallet = *v
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) FindAccount(filter *Filter, account *model.OwAccount) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, v := range r.accounts {
+		if filter.match(v) {
+			*account = *v
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) FindAccountList(filter *Filter, offset, limit int) ([]*model.OwAccount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]*model.OwAccount, 0)
+	for _, v := range r.accounts {
+		if filter.match(v) {
+			c := *v
+			result = append(result, &c)
+		}
+	}
+	from, to := pageRange(len(result), offset, limit)
+	return result[from:to], nil
+}
+
+func (r *MemoryRepository) FindAddress(filter *Filter, address *model.OwAddress) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, v := range r.addresses {
+		if filter.match(v) {
+			*address = *v
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) FindAddressList(filter *Filter, offset, limit int) ([]*model.OwAddress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]*model.OwAddress, 0)
+	for _, v := range r.addresses {
+		if filter.match(v) {
+			c := *v
+			result = append(result, &c)
+		}
+	}
+	from, to := pageRange(len(result), offset, limit)
+	return result[from:to], nil
+}
+
+func (r *MemoryRepository) UpdateAddress(address *model.OwAddress) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, v := range r.addresses {
+		if v.Id == address.Id {
+			c := *address
+			r.addresses[i] = &c
+			return nil
+		}
+	}
+	return util.Error("MemoryRepository地址[", address.Id, "]不存在")
+}
+
+func (r *MemoryRepository) FindAddressTokenList(filter *Filter, offset, limit int) ([]*model.OwAddressToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]*model.OwAddressToken, 0)
+	for _, v := range r.addressTokens {
+		if filter.match(v) {
+			c := *v
+			result = append(result, &c)
+		}
+	}
+	from, to := pageRange(len(result), offset, limit)
+	return result[from:to], nil
+}
+
+func (r *MemoryRepository) FindTradeLogTmpList(filter *Filter) ([]*model.OwTradeLogTmp, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]*model.OwTradeLogTmp, 0)
+	for _, v := range r.tradeLogTmps {
+		if filter.match(v) {
+			c := *v
+			result = append(result, &c)
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryRepository) FindTradeLogList(filter *Filter) ([]*model.OwTradeLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]*model.OwTradeLog, 0)
+	for _, v := range r.tradeLogs {
+		if filter.match(v) {
+			c := *v
+			result = append(result, &c)
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryRepository) nextID(id int64) int64 {
+	if id > 0 {
+		if id > r.id {
+			r.id = id
+		}
+		return id
+	}
+	r.id++
+	return r.id
+}
+
+func pageRange(size, offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > size {
+		offset = size
+	}
+	if limit <= 0 || offset+limit > size {
+		return offset, size
+	}
+	return offset, offset + limit
+}
+
+// 按字段名(json标签与bson一致)比较查询条件
+func (f *Filter) match(data interface{}) bool {
+	if f == nil || len(f.conds) == 0 {
+		return true
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return false
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return false
+	}
+	for _, c := range f.conds {
+		v, ok := fields[c.key]
+		equal := ok && fmt.Sprint(v) == fmt.Sprint(c.value)
+		if equal == c.not {
+			return false
+		}
+	}
+	return true
+}
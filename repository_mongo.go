@@ -0,0 +1,159 @@
+package open_scanner
+
+import (
+	"github.com/godaddy-x/jorm/sqlc"
+	"github.com/godaddy-x/jorm/sqld"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_base/model"
+)
+
+const (
+	mongoPageSize = 100
+)
+
+// MongoRepository 基于Mongo的数据访问实现
+type MongoRepository struct {
+}
+
+func (r *MongoRepository) FindWallet(filter *Filter, wallet *model.OwWallet) error {
+	return r.findOne(filter.cnd(model.OwWallet{}), wallet)
+}
+
+func (r *MongoRepository) FindAccount(filter *Filter, account *model.OwAccount) error {
+	return r.findOne(filter.cnd(model.OwAccount{}), account)
+}
+
+func (r *MongoRepository) FindAccountList(filter *Filter, offset, limit int) ([]*model.OwAccount, error) {
+	result := []*model.OwAccount{}
+	if err := r.findList(filter.cnd(model.OwAccount{}), offset, limit, false, func(mongo *sqld.MGOManager, cnd *sqlc.Cnd) error {
+		list := []*model.OwAccount{}
+		if err := mongo.FindList(cnd, &list); err != nil {
+			return err
+		}
+		result = append(result, list...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *MongoRepository) FindAddress(filter *Filter, address *model.OwAddress) error {
+	return r.findOne(filter.cnd(model.OwAddress{}), address)
+}
+
+func (r *MongoRepository) FindAddressList(filter *Filter, offset, limit int) ([]*model.OwAddress, error) {
+	result := []*model.OwAddress{}
+	if err := r.findList(filter.cnd(model.OwAddress{}), offset, limit, false, func(mongo *sqld.MGOManager, cnd *sqlc.Cnd) error {
+		list := []*model.OwAddress{}
+		if err := mongo.FindList(cnd, &list); err != nil {
+			return err
+		}
+		result = append(result, list...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *MongoRepository) UpdateAddress(address *model.OwAddress) error {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return err
+	}
+	defer mongo.Close()
+	return mongo.Update(address)
+}
+
+// limit<=0时按页读取全部,单页查询失败时跳过该页
+func (r *MongoRepository) FindAddressTokenList(filter *Filter, offset, limit int) ([]*model.OwAddressToken, error) {
+	result := []*model.OwAddressToken{}
+	if err := r.findList(filter.cnd(model.OwAddressToken{}), offset, limit, true, func(mongo *sqld.MGOManager, cnd *sqlc.Cnd) error {
+		list := []*model.OwAddressToken{}
+		if err := mongo.FindList(cnd, &list); err != nil {
+			return err
+		}
+		result = append(result, list...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *MongoRepository) FindTradeLogTmpList(filter *Filter) ([]*model.OwTradeLogTmp, error) {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return nil, err
+	}
+	defer mongo.Close()
+	result := []*model.OwTradeLogTmp{}
+	if err := mongo.FindList(filter.cnd(model.OwTradeLogTmp{}), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *MongoRepository) FindTradeLogList(filter *Filter) ([]*model.OwTradeLog, error) {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return nil, err
+	}
+	defer mongo.Close()
+	result := []*model.OwTradeLog{}
+	if err := mongo.FindList(filter.cnd(model.OwTradeLog{}), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *MongoRepository) findOne(cnd *sqlc.Cnd, data interface{}) error {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return err
+	}
+	defer mongo.Close()
+	return mongo.FindOne(cnd, data)
+}
+
+// limit>0时按下标分页查询,否则按页遍历全部数据,skip为true时跳过查询失败的页
+func (r *MongoRepository) findList(cnd *sqlc.Cnd, offset, limit int, skip bool, call func(mongo *sqld.MGOManager, cnd *sqlc.Cnd) error) error {
+	mongo, err := new(sqld.MGOManager).Get()
+	if err != nil {
+		return err
+	}
+	defer mongo.Close()
+	if limit > 0 {
+		return call(mongo, cnd.Offset(int64(offset), int64(limit)))
+	}
+	cnd.Limit(1, mongoPageSize)
+	if _, err := mongo.Count(cnd); err != nil {
+		return util.Error("获取数据列表统计数失败: ", err)
+	}
+	pagin := cnd.Pagination
+	for i := int64(1); i <= pagin.PageCount; i++ {
+		if err := call(mongo, cnd.Limit(i, pagin.PageSize)); err != nil {
+			if skip {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Filter) cnd(data interface{}) *sqlc.Cnd {
+	cnd := sqlc.M(data)
+	if f == nil {
+		return cnd
+	}
+	for _, c := range f.conds {
+		if c.not {
+			cnd.NotEq(c.key, c.value)
+		} else {
+			cnd.Eq(c.key, c.value)
+		}
+	}
+	return cnd
+}
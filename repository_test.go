@@ -0,0 +1,177 @@
+package open_scanner
+
+import (
+	"testing"
+
+	"github.com/nbit99/open_base/model"
+)
+
+func newTestRepository(t *testing.T) *MemoryRepository {
+	repo := NewMemoryRepository()
+	err := repo.Save(
+		&model.OwWallet{AppID: "app1", WalletID: "w1", IsTrust: 1, State: 1},
+		&model.OwAccount{AppID: "app1", WalletID: "w1", AccountID: "acc1", Symbol: "BTC", State: 1},
+		&model.OwAccount{AppID: "app1", WalletID: "w1", AccountID: "acc2", Symbol: "BTC", State: 0},
+		&model.OwAccount{AppID: "app2", WalletID: "w2", AccountID: "acc3", Symbol: "ETH", State: 1},
+		&model.OwAddress{AppID: "app1", AccountID: "acc1", Symbol: "BTC", Address: "addr1", State: 1},
+		&model.OwAddress{AppID: "app1", AccountID: "acc1", Symbol: "BTC", Address: "addr2", State: 1},
+		&model.OwAddress{AppID: "app1", AccountID: "acc1", Symbol: "BTC", Address: "addr3", State: 1},
+		&model.OwAddressToken{AppID: "app1", Symbol: "ETH", Address: "addr1", ContractID: "c1", State: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestMemoryRepositorySave(t *testing.T) {
+	repo := NewMemoryRepository()
+	wallet := &model.OwWallet{WalletID: "w1"}
+	fixed := &model.OwWallet{Id: 10, WalletID: "w2"}
+	next := &model.OwWallet{WalletID: "w3"}
+	if err := repo.Save(wallet, fixed, next); err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Id != 1 || fixed.Id != 10 || next.Id != 11 {
+		t.Fatalf("unexpected ids: %d %d %d", wallet.Id, fixed.Id, next.Id)
+	}
+	if err := repo.Save("unsupported"); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+}
+
+func TestMemoryRepositoryFilterByJsonTag(t *testing.T) {
+	repo := newTestRepository(t)
+
+	wallet := model.OwWallet{}
+	if err := repo.FindWallet(NewFilter().Eq("appID", "app1").Eq("walletID", "w1").Eq("state", 1), &wallet); err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Id == 0 || wallet.WalletID != "w1" {
+		t.Fatalf("wallet not found: %+v", wallet)
+	}
+
+	// 字段名按json标签匹配,Go字段名不匹配
+	wallet = model.OwWallet{}
+	if err := repo.FindWallet(NewFilter().Eq("WalletID", "w1"), &wallet); err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Id != 0 {
+		t.Fatalf("expected no match by struct field name, got %+v", wallet)
+	}
+
+	account := model.OwAccount{}
+	if err := repo.FindAccount(NewFilter().Eq("accountID", "acc2").Eq("state", 1), &account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Id != 0 {
+		t.Fatalf("expected no match for disabled account, got %+v", account)
+	}
+
+	accounts, err := repo.FindAccountList(NewFilter().Eq("appID", "app1"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(accounts))
+	}
+
+	accounts, err = repo.FindAccountList(NewFilter().Eq("appID", "app1").NotEq("state", 0), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].AccountID != "acc1" {
+		t.Fatalf("unexpected NotEq result: %+v", accounts)
+	}
+
+	tokens, err := repo.FindAddressTokenList(NewFilter().Eq("contractID", "c1").Eq("address", "addr1"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 {
+		t.Fatalf("expected 1 address token, got %d", len(tokens))
+	}
+}
+
+func TestMemoryRepositoryPaging(t *testing.T) {
+	repo := newTestRepository(t)
+	filter := NewFilter().Eq("accountID", "acc1")
+	cases := []struct {
+		offset, limit int
+		expect        []string
+	}{
+		{0, 0, []string{"addr1", "addr2", "addr3"}},
+		{0, 2, []string{"addr1", "addr2"}},
+		{2, 2, []string{"addr3"}},
+		{5, 2, []string{}},
+	}
+	for _, c := range cases {
+		list, err := repo.FindAddressList(filter.Clone(), c.offset, c.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != len(c.expect) {
+			t.Fatalf("offset %d limit %d: expected %d addresses, got %d", c.offset, c.limit, len(c.expect), len(list))
+		}
+		for i, v := range list {
+			if v.Address != c.expect[i] {
+				t.Fatalf("offset %d limit %d: expected %s at %d, got %s", c.offset, c.limit, c.expect[i], i, v.Address)
+			}
+		}
+	}
+}
+
+func TestMemoryRepositoryUpdateAddress(t *testing.T) {
+	repo := newTestRepository(t)
+	address := model.OwAddress{}
+	if err := repo.FindAddress(NewFilter().Eq("address", "addr2"), &address); err != nil {
+		t.Fatal(err)
+	}
+	address.Memo = "memo"
+	if err := repo.UpdateAddress(&address); err != nil {
+		t.Fatal(err)
+	}
+	updated := model.OwAddress{}
+	if err := repo.FindAddress(NewFilter().Eq("memo", "memo"), &updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Address != "addr2" {
+		t.Fatalf("expected addr2, got %+v", updated)
+	}
+	if err := repo.UpdateAddress(&model.OwAddress{Id: 999}); err == nil {
+		t.Fatal("expected error for missing address")
+	}
+}
+
+func TestFilterClone(t *testing.T) {
+	filter := NewFilter().Eq("appID", "app1")
+	clone := filter.Clone().Eq("symbol", "BTC")
+	if len(filter.conds) != 1 || len(clone.conds) != 2 {
+		t.Fatalf("clone shares conditions: %d %d", len(filter.conds), len(clone.conds))
+	}
+}
+
+func TestWrapperRepository(t *testing.T) {
+	repo := newTestRepository(t)
+	w := NewWrapper("app1", "w1", "acc1", "BTC", repo)
+	if wallet := w.GetWallet(); wallet == nil || wallet.WalletID != "w1" {
+		t.Fatalf("unexpected wallet: %+v", wallet)
+	}
+	account, err := w.GetAssetsAccountInfo("acc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.AccountID != "acc1" {
+		t.Fatalf("unexpected account: %+v", account)
+	}
+	if _, err := w.GetAssetsAccountInfo("acc2"); err == nil {
+		t.Fatal("expected error for disabled account")
+	}
+	address, err := w.GetAddress("addr1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address.Address != "addr1" || address.AccountID != "acc1" {
+		t.Fatalf("unexpected address: %+v", address)
+	}
+}
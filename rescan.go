@@ -0,0 +1,275 @@
+package open_scanner
+
+import (
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	RescanRunning   = "running"
+	RescanDone      = "done"
+	RescanCancelled = "cancelled"
+	RescanFailed    = "failed"
+
+	defaultRescanJobs     = 2
+	defaultRescanMaxRange = 100000
+	rescanRetry           = 3
+	rescanKeepJobs        = 100
+	rescanMaxFailed       = 100
+)
+
+// 重扫失败的区块重试间隔
+var rescanRetryWait = 3 * time.Second
+
+// RescanJob 区间重扫任务,与实时扫块并行执行,不修改实时扫块高度
+type RescanJob struct {
+	ID        string   `json:"id"`
+	Symbol    string   `json:"symbol"`
+	From      uint64   `json:"from"`
+	To        uint64   `json:"to"`
+	Addresses []string `json:"addresses"` // 按地址重扫时的地址,为空时重扫整个区块
+	Current   uint64   `json:"current"`   // 最近完成的高度,未开始时为0
+	Scanned   uint64   `json:"scanned"`   // 已完成的区块数(含失败)
+	Failed    []uint64 `json:"failed"`    // 重试后仍失败的高度,最多记录rescanMaxFailed个
+	Status    string   `json:"status"`
+	Error     string   `json:"error"`
+	Force     bool     `json:"force"`
+	StartTime int64    `json:"startTime"`
+	EndTime   int64    `json:"endTime"`
+}
+
+// Progress 完成比例0~1
+func (j RescanJob) Progress() float64 {
+	total := j.To - j.From + 1
+	return float64(j.Scanned) / float64(total)
+}
+
+type rescanTask struct {
+	job    RescanJob
+	scan   func(height uint64) error
+	height uint64 // 正在扫描的高度
+	done   chan struct{}
+	once   sync.Once
+}
+
+// RescanManager 币种重扫任务,[币种ini] rescanJobs 同时执行的任务数(默认2), rescanMaxRange 单个任务最大区块数(默认100000)
+type RescanManager struct {
+	mu    sync.Mutex
+	o     *OpenWScanner
+	tasks map[string]*rescanTask
+}
+
+func NewRescanManager(o *OpenWScanner) *RescanManager {
+	return &RescanManager{o: o, tasks: make(map[string]*rescanTask)}
+}
+
+func (o *OpenWScanner) rescanManager() *RescanManager {
+	o.scanMu.Lock()
+	defer o.scanMu.Unlock()
+	if o.rescans == nil {
+		o.rescans = NewRescanManager(o)
+	}
+	return o.rescans
+}
+
+// RescanRange 创建区间重扫任务,to不能超过实时扫块已扫描高度,force为true时区间内已发送的交易单重新发送
+func (o *OpenWScanner) RescanRange(from, to uint64, force bool) (*RescanJob, error) {
+	return o.startRescan(from, to, force, nil, func(height uint64) error {
+		return o.BlockScanner.ScanBlock(height)
+	})
+}
+
+// 校验区间及任务数后启动重扫任务,scan为单个区块的扫描方法
+func (o *OpenWScanner) startRescan(from, to uint64, force bool, addresses []string, scan func(height uint64) error) (*RescanJob, error) {
+	if o.BlockScanner == nil {
+		return nil, util.Error("[", o.Symbol, "]扫块器未启动")
+	}
+	if err := o.CheckLeader(); err != nil {
+		return nil, err
+	}
+	if from == 0 || from > to {
+		return nil, util.Error("[", o.Symbol, "]重扫区间[", from, "-", to, "]无效")
+	}
+	maxJobs, maxRange := int64(defaultRescanJobs), int64(defaultRescanMaxRange)
+	if c := o.currentConfig(); c != nil {
+		maxJobs = c.DefaultInt64("rescanJobs", defaultRescanJobs)
+		maxRange = c.DefaultInt64("rescanMaxRange", defaultRescanMaxRange)
+	}
+	if to-from+1 > uint64(maxRange) {
+		return nil, util.Error("[", o.Symbol, "]重扫区块数超过", maxRange)
+	}
+	// 只能重扫实时扫块已完成的高度,重扫的区块按高度区分,不能与实时扫块重叠
+	if scanned := o.BlockScanner.GetScannedBlockHeight(); to > scanned {
+		return nil, util.Error("[", o.Symbol, "]重扫高度[", to, "]超过已扫描高度[", scanned, "]")
+	}
+	m := o.rescanManager()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running() >= int(maxJobs) {
+		return nil, util.Error("[", o.Symbol, "]重扫任务数已达上限", maxJobs)
+	}
+	task := &rescanTask{
+		job:  RescanJob{ID: util.GetUUID(), Symbol: o.Symbol, From: from, To: to, Addresses: addresses, Status: RescanRunning, Force: force, Failed: []uint64{}, StartTime: util.Time()},
+		scan: scan,
+		done: make(chan struct{}),
+	}
+	m.tasks[task.job.ID] = task
+	m.prune()
+	go m.run(task)
+	log2.Info("创建重扫任务", 0, log2.String("symbol", o.Symbol), log2.String("id", task.job.ID), log2.Uint64("from", from), log2.Uint64("to", to), log2.Int("addresses", len(addresses)), log2.Bool("force", force))
+	job := task.job
+	return &job, nil
+}
+
+// GetRescanJob 查询重扫任务
+func (o *OpenWScanner) GetRescanJob(id string) (*RescanJob, error) {
+	m := o.rescanManager()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task, ok := m.tasks[id]
+	if !ok {
+		return nil, util.Error("[", o.Symbol, "]重扫任务[", id, "]不存在")
+	}
+	job := task.job
+	job.Failed = append([]uint64{}, task.job.Failed...)
+	return &job, nil
+}
+
+// CancelRescanJob 取消重扫任务,当前区块扫描完成后停止
+func (o *OpenWScanner) CancelRescanJob(id string) error {
+	m := o.rescanManager()
+	m.mu.Lock()
+	task, ok := m.tasks[id]
+	m.mu.Unlock()
+	if !ok {
+		return util.Error("[", o.Symbol, "]重扫任务[", id, "]不存在")
+	}
+	task.cancel()
+	return nil
+}
+
+// 重扫中的高度不更新实时扫块的重组窗口/确认数/落后告警
+func (o *OpenWScanner) rescanning(height uint64) bool {
+	o.scanMu.Lock()
+	m := o.rescans
+	o.scanMu.Unlock()
+	return m != nil && m.scanning(height)
+}
+
+func (t *rescanTask) cancel() {
+	t.once.Do(func() {
+		close(t.done)
+	})
+}
+
+// 按地址重扫不触发区块通知,只检查区间重扫任务
+func (m *RescanManager) scanning(height uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.tasks {
+		if v.job.Status == RescanRunning && len(v.job.Addresses) == 0 && v.height == height {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *RescanManager) running() int {
+	count := 0
+	for _, v := range m.tasks {
+		if v.job.Status == RescanRunning {
+			count++
+		}
+	}
+	return count
+}
+
+// 保留最近rescanKeepJobs个已结束的任务
+func (m *RescanManager) prune() {
+	ended := make([]*rescanTask, 0)
+	for _, v := range m.tasks {
+		if v.job.Status != RescanRunning {
+			ended = append(ended, v)
+		}
+	}
+	if len(ended) <= rescanKeepJobs {
+		return
+	}
+	sort.Slice(ended, func(i, j int) bool { return ended[i].job.EndTime < ended[j].job.EndTime })
+	for _, v := range ended[:len(ended)-rescanKeepJobs] {
+		delete(m.tasks, v.job.ID)
+	}
+}
+
+// Stop 取消所有重扫任务
+func (m *RescanManager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.tasks {
+		v.cancel()
+	}
+}
+
+func (m *RescanManager) run(task *rescanTask) {
+	o := m.o
+	job := task.job
+	if job.Force {
+		release := o.ForceRepublish(job.From, job.To, false)
+		defer release()
+	}
+	status := RescanDone
+	for height := job.From; height <= job.To; height++ {
+		select {
+		case <-task.done:
+			status = RescanCancelled
+		default:
+		}
+		if status == RescanCancelled {
+			break
+		}
+		m.mu.Lock()
+		task.height = height
+		m.mu.Unlock()
+		err := m.scan(task, height)
+		m.mu.Lock()
+		task.height = 0
+		task.job.Current = height
+		task.job.Scanned++
+		if err != nil {
+			if len(task.job.Failed) < rescanMaxFailed {
+				task.job.Failed = append(task.job.Failed, height)
+			}
+			task.job.Error = err.Error()
+		}
+		m.mu.Unlock()
+	}
+	m.mu.Lock()
+	if status == RescanDone && len(task.job.Failed) > 0 {
+		status = RescanFailed
+	}
+	task.job.Status = status
+	task.job.EndTime = util.Time()
+	job = task.job
+	m.mu.Unlock()
+	log2.Info("重扫任务结束", 0, log2.String("symbol", o.Symbol), log2.String("id", job.ID), log2.String("status", job.Status), log2.Uint64("current", job.Current), log2.Int("failed", len(job.Failed)))
+}
+
+// 扫描单个区块,失败时重试
+func (m *RescanManager) scan(task *rescanTask, height uint64) error {
+	var err error
+	for i := 0; i < rescanRetry; i++ {
+		if err = task.scan(height); err == nil {
+			return nil
+		}
+		log2.Warn("重扫区块失败", 0, log2.String("symbol", m.o.Symbol), log2.String("id", task.job.ID), log2.Uint64("height", height), log2.AddError(err))
+		select {
+		case <-task.done:
+			return err
+		case <-time.After(rescanRetryWait):
+		}
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package open_scanner
+
+import (
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	"strings"
+)
+
+// BlockTxLister 读取区块中的交易单ID,区块扫描器实现后支持按地址重扫
+type BlockTxLister interface {
+	GetBlockTxIDs(height uint64) ([]string, error)
+}
+
+// RescanAddresses 创建按地址重扫任务,用于补扫后导入地址的历史交易
+// 使用只匹配指定地址的临时扫描对象提取交易单,结果经BlockExtractDataNotify发送,不影响同一高度的实时扫块
+func (o *OpenWScanner) RescanAddresses(addresses []string, from, to uint64, force bool) (*RescanJob, error) {
+	if o.BlockScanner == nil {
+		return nil, util.Error("[", o.Symbol, "]扫块器未启动")
+	}
+	lister, ok := o.BlockScanner.(BlockTxLister)
+	if !ok {
+		return nil, util.Error("[", o.Symbol, "]扫块器不支持按地址重扫")
+	}
+	targetFunc, list, err := o.addressTargets(addresses)
+	if err != nil {
+		return nil, err
+	}
+	return o.startRescan(from, to, force, list, func(height uint64) error {
+		txids, err := lister.GetBlockTxIDs(height)
+		if err != nil {
+			return err
+		}
+		hashes := make(map[string]bool)
+		for _, txid := range txids {
+			if err := o.extractTx(txid, targetFunc, hashes); err != nil {
+				return err
+			}
+		}
+		// 不触发区块通知,批量模式下直接发送重扫的交易单
+		if o.batcher != nil {
+			for hash := range hashes {
+				if err := o.batcher.Flush(hash); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// 临时扫描对象,只匹配指定地址/别名,地址须已导入地址索引
+func (o *OpenWScanner) addressTargets(addresses []string) (openwallet.BlockScanTargetFuncV2, []string, error) {
+	if o.AddressIndex == nil {
+		return nil, nil, util.Error("[", o.Symbol, "]地址索引未初始化")
+	}
+	symbol := strings.ToUpper(o.Symbol)
+	byAddress, byAlias := make(map[string]string), make(map[string]string)
+	list := make([]string, 0, len(addresses))
+	reloaded := false
+	for _, v := range addresses {
+		v = strings.TrimSpace(v)
+		if len(v) == 0 {
+			continue
+		}
+		if _, ok := byAddress[v]; ok {
+			continue
+		}
+		if _, ok := byAlias[v]; ok {
+			continue
+		}
+		found := false
+		for !found {
+			if accountID, err := o.AddressIndex.AccountIDByAddress(v, symbol); err == nil && len(accountID) > 0 {
+				byAddress[v], found = accountID, true
+			} else if accountID, err := o.AddressIndex.AccountIDByAlias(v, symbol); err == nil && len(accountID) > 0 {
+				byAlias[v], found = accountID, true
+			} else if index, ok := o.AddressIndex.(ReloadableIndex); ok && !reloaded {
+				// 新导入的地址可能尚未加载到索引
+				reloaded = true
+				if err := index.Reload(); err != nil {
+					return nil, nil, err
+				}
+			} else {
+				return nil, nil, util.Error("[", o.Symbol, "]地址[", v, "]未导入")
+			}
+		}
+		list = append(list, v)
+	}
+	if len(list) == 0 {
+		return nil, nil, util.Error("[", o.Symbol, "]重扫地址为空")
+	}
+	return func(target openwallet.ScanTargetParam) openwallet.ScanTargetResult {
+		var sourceKey string
+		switch target.ScanTargetType {
+		case openwallet.ScanTargetTypeAccountAddress:
+			sourceKey = byAddress[target.ScanTarget]
+		case openwallet.ScanTargetTypeAccountAlias:
+			sourceKey = byAlias[target.ScanTarget]
+		}
+		return openwallet.ScanTargetResult{SourceKey: sourceKey, Exist: len(sourceKey) > 0}
+	}, list, nil
+}
+
+// 提取交易单并按实时扫块的回调发送,hashes记录交易单所在的区块
+func (o *OpenWScanner) extractTx(txid string, targetFunc openwallet.BlockScanTargetFuncV2, hashes map[string]bool) error {
+	extracts, _, err := o.BlockScanner.ExtractTransactionAndReceiptData(txid, targetFunc)
+	if err != nil {
+		return util.Error("提取交易单[", txid, "]失败: ", err.Error())
+	}
+	for sourceKey, list := range extracts {
+		for _, data := range list {
+			if data.Transaction != nil {
+				hashes[data.Transaction.BlockHash] = true
+			}
+			if err := o.BlockExtractDataNotify(sourceKey, data); err != nil {
+				log2.Error("按地址重扫发送交易单失败", 0, log2.String("symbol", o.Symbol), log2.String("txid", txid), log2.String("sourceKey", sourceKey), log2.AddError(err))
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package open_scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbit99/open_base/model"
+	"github.com/nbit99/open_scanner/event"
+	"github.com/nbit99/openwallet/v2/openwallet"
+)
+
+func rescanTx(height uint64, from, to string) *openwallet.TxExtractData {
+	return &openwallet.TxExtractData{
+		Transaction: &openwallet.Transaction{TxID: from + to, BlockHash: "h100", BlockHeight: height},
+		TxInputs:    []*openwallet.TxInput{{Recharge: openwallet.Recharge{Address: from, Amount: "1"}}},
+		TxOutputs:   []*openwallet.TxOutPut{{Recharge: openwallet.Recharge{Address: to, Amount: "1"}}},
+	}
+}
+
+// 按扫描对象提取交易单,读取区块交易单时等待proceed
+type rescanBlockScanner struct {
+	*testBlockScanner
+	txs     map[uint64][]*openwallet.TxExtractData
+	listing chan uint64
+	proceed chan struct{}
+}
+
+func (s *rescanBlockScanner) GetBlockTxIDs(height uint64) ([]string, error) {
+	s.listing <- height
+	<-s.proceed
+	txids := []string{}
+	for _, v := range s.txs[height] {
+		txids = append(txids, v.Transaction.TxID)
+	}
+	return txids, nil
+}
+
+func (s *rescanBlockScanner) ExtractTransactionAndReceiptData(txid string, scanTargetFunc openwallet.BlockScanTargetFuncV2) (map[string][]*openwallet.TxExtractData, map[string]*openwallet.SmartContractReceipt, error) {
+	result := make(map[string][]*openwallet.TxExtractData)
+	for _, list := range s.txs {
+		for _, v := range list {
+			if v.Transaction.TxID != txid {
+				continue
+			}
+			addresses := []string{v.TxInputs[0].Address, v.TxOutputs[0].Address}
+			for _, a := range addresses {
+				target := scanTargetFunc(openwallet.ScanTargetParam{ScanTarget: a, Symbol: "BTC", ScanTargetType: openwallet.ScanTargetTypeAccountAddress})
+				if target.Exist {
+					result[target.SourceKey] = append(result[target.SourceKey], v)
+				}
+			}
+		}
+	}
+	return result, nil, nil
+}
+
+// 发送的交易单消息所属账户,区块消息为block
+func sentAccounts(t *testing.T, sink *memorySink) []string {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	result := []string{}
+	for _, v := range sink.sent {
+		if v.Type == EventBlock {
+			result = append(result, "block")
+			continue
+		}
+		tx, err := event.DecodeTx(v.Content.(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, tx.AccountID+":"+tx.Content.TxID)
+	}
+	return result
+}
+
+func TestRescanAddressesLiveSameHeight(t *testing.T) {
+	dir, remove := tempDir(t)
+	defer remove()
+	scanner := &rescanBlockScanner{
+		testBlockScanner: &testBlockScanner{Scanning: true, scanned: 100},
+		txs:              map[uint64][]*openwallet.TxExtractData{100: {rescanTx(100, "addr1", "x"), rescanTx(100, "y", "addr2")}},
+		listing:          make(chan uint64),
+		proceed:          make(chan struct{}),
+	}
+	sink := &memorySink{name: SinkMQ}
+	o := &OpenWScanner{Symbol: "BTC", Signer: &Signer{legacy: "secret"}, routes: []*SinkRoute{{Sink: sink}}, BlockScanner: scanner,
+		AddressIndex: &stubAddressIndex{entries: map[string]string{"BTCaddr1": "acc1", "BTCaddr2": "acc2"}},
+		lookups:      NewLookupCache(10, time.Minute, time.Minute),
+		checkpoint:   NewCheckpointer(&FileCheckpointStore{Dir: dir}, "BTC", 100)}
+	for _, v := range []string{"acc1", "acc2"} {
+		o.lookups.Put(accountCachePrefix+v, &model.OwAccount{AppID: "app", WalletID: "w", AccountID: v})
+	}
+	job, err := o.RescanAddresses([]string{"addr2", " addr2 "}, 100, 100, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(job.Addresses) != 1 {
+		t.Fatalf("unexpected addresses %v", job.Addresses)
+	}
+	<-scanner.listing
+
+	// 重扫任务扫描中时,同一高度的实时扫块回调正常发送
+	if err := o.BlockExtractDataNotify("acc1", scanner.txs[100][0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.BlockScanNotify(&openwallet.BlockHeader{Hash: "h100", Height: 100, Symbol: "BTC"}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := sentAccounts(t, sink); len(sent) != 2 || sent[0] != "acc1:addr1x" || sent[1] != "block" {
+		t.Fatalf("unexpected live messages %v", sent)
+	}
+	if cur := o.checkpoint.Current(); cur == nil || cur.Height != 100 {
+		t.Fatalf("live block not recorded: %+v", cur)
+	}
+
+	// 重扫只发送指定地址的交易单,不发送区块消息
+	close(scanner.proceed)
+	job = waitRescanJob(t, o, job.ID)
+	if job.Status != RescanDone {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if sent := sentAccounts(t, sink); len(sent) != 3 || sent[2] != "acc2:yaddr2" {
+		t.Fatalf("unexpected rescan messages %v", sent)
+	}
+}
+
+func TestRescanAddressesUnsupported(t *testing.T) {
+	o := &OpenWScanner{Symbol: "BTC", BlockScanner: &testBlockScanner{scanned: 100}, AddressIndex: &stubAddressIndex{}}
+	if _, err := o.RescanAddresses([]string{"addr1"}, 1, 10, false); err == nil {
+		t.Fatal("expected error without BlockTxLister")
+	}
+	scanner := &rescanBlockScanner{testBlockScanner: &testBlockScanner{scanned: 100}}
+	o.BlockScanner = scanner
+	for _, addresses := range [][]string{{"addr1"}, {" "}} {
+		if _, err := o.RescanAddresses(addresses, 1, 10, false); err == nil {
+			t.Fatalf("expected error for addresses %v", addresses)
+		}
+	}
+}
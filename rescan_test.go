@@ -0,0 +1,136 @@
+package open_scanner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godaddy-x/jorm/util"
+)
+
+// 等待重扫任务结束
+func waitRescanJob(t *testing.T, o *OpenWScanner, id string) *RescanJob {
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		job, err := o.GetRescanJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != RescanRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rescan job %s not finished: %+v", id, job)
+		}
+	}
+}
+
+func TestRescanManagerRun(t *testing.T) {
+	wait := rescanRetryWait
+	rescanRetryWait = time.Millisecond
+	defer func() { rescanRetryWait = wait }()
+	o := &OpenWScanner{Symbol: "BTC", BlockScanner: &testBlockScanner{scanned: 100}}
+	scanned := make(chan uint64, 10)
+	job, err := o.startRescan(10, 14, false, nil, func(height uint64) error {
+		scanned <- height
+		if height == 12 {
+			return util.Error("scan failed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitRescanJob(t, o, job.ID)
+	// 失败的区块重试后记录,其余区块继续扫描
+	if job.Status != RescanFailed || job.Current != 14 || job.Scanned != 5 || !reflect.DeepEqual(job.Failed, []uint64{12}) {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if job.Progress() != 1 || len(scanned) != 4+rescanRetry {
+		t.Fatalf("unexpected progress %f scans %d", job.Progress(), len(scanned))
+	}
+	if o.rescanning(12) {
+		t.Fatal("height still marked as rescanning")
+	}
+}
+
+func TestRescanManagerCancel(t *testing.T) {
+	o := &OpenWScanner{Symbol: "BTC", BlockScanner: &testBlockScanner{scanned: 100}}
+	started := make(chan uint64)
+	proceed := make(chan struct{})
+	job, err := o.startRescan(10, 20, false, nil, func(height uint64) error {
+		started <- height
+		<-proceed
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 扫描中的高度按任务标记
+	if height := <-started; height != 10 || !o.rescanning(10) || o.rescanning(11) {
+		t.Fatalf("unexpected rescanning height %d", height)
+	}
+	if v, _ := o.GetRescanJob(job.ID); v.Status != RescanRunning || v.Progress() != 0 {
+		t.Fatalf("unexpected running job %+v", v)
+	}
+	// 当前区块扫描完成后停止
+	if err := o.CancelRescanJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	close(proceed)
+	job = waitRescanJob(t, o, job.ID)
+	if job.Status != RescanCancelled || job.Current != 10 || job.Scanned != 1 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if job.Progress() != 1.0/11 {
+		t.Fatalf("unexpected progress %f", job.Progress())
+	}
+	if err := o.CancelRescanJob("unknown"); err == nil {
+		t.Fatal("expected error for unknown job")
+	}
+}
+
+func TestRescanManagerLimits(t *testing.T) {
+	o := &OpenWScanner{Symbol: "BTC", BlockScanner: &testBlockScanner{scanned: 100}}
+	scan := func(height uint64) error { return nil }
+	// 不能超过实时扫块已扫描高度
+	for _, v := range [][2]uint64{{0, 10}, {20, 10}, {90, 101}} {
+		if _, err := o.startRescan(v[0], v[1], false, nil, scan); err == nil {
+			t.Fatalf("expected error for range %v", v)
+		}
+	}
+	o.leader = NewLeaderElector(o, true)
+	if _, err := o.startRescan(1, 10, false, nil, scan); err == nil {
+		t.Fatal("expected error on standby")
+	}
+	o.leader = nil
+	// 同时执行的任务数
+	m := o.rescanManager()
+	for i := 0; i < defaultRescanJobs; i++ {
+		m.tasks[util.GetUUID()] = &rescanTask{job: RescanJob{Status: RescanRunning}}
+	}
+	if _, err := o.startRescan(1, 10, false, nil, scan); err == nil {
+		t.Fatal("expected error when running jobs reach the limit")
+	}
+}
+
+func TestRescanManagerPrune(t *testing.T) {
+	m := NewRescanManager(&OpenWScanner{Symbol: "BTC"})
+	m.tasks["running"] = &rescanTask{job: RescanJob{ID: "running", Status: RescanRunning}}
+	for i := 0; i < rescanKeepJobs+5; i++ {
+		id := util.AnyToStr(i)
+		m.tasks[id] = &rescanTask{job: RescanJob{ID: id, Status: RescanDone, EndTime: int64(i)}}
+	}
+	// 只保留最近结束的任务,运行中的任务不删除
+	m.prune()
+	if len(m.tasks) != rescanKeepJobs+1 {
+		t.Fatalf("expected %d jobs, got %d", rescanKeepJobs+1, len(m.tasks))
+	}
+	for _, id := range []string{"running", "5", util.AnyToStr(rescanKeepJobs + 4)} {
+		if _, ok := m.tasks[id]; !ok {
+			t.Fatalf("expected job %s kept", id)
+		}
+	}
+	if _, ok := m.tasks["4"]; ok {
+		t.Fatal("expected oldest job pruned")
+	}
+}
@@ -64,13 +64,38 @@ type GetRawTransactionFeeRateReq struct {
 type RescannerHeightReq struct {
 	Symbol  string
 	Height  int64
-	IsForce int64
+	IsForce int64 // 1: 已发送的交易单强制重新发送
 }
 
 type RescannerOneHeightReq struct {
 	Symbol  string
 	Height  int64
-	IsForce int64
+	IsForce int64 // 1: 已发送的交易单强制重新发送
+}
+
+type RescanRangeReq struct {
+	Symbol  string
+	From    int64
+	To      int64
+	IsForce int64 // 1: 已发送的交易单强制重新发送
+}
+
+type RescanAddressReq struct {
+	Symbol    string
+	Addresses []string // 已导入的地址或账户别名
+	From      int64
+	To        int64
+	IsForce   int64 // 1: 已发送的交易单强制重新发送
+}
+
+type GetRescanJobReq struct {
+	Symbol string
+	JobID  string
+}
+
+type CancelRescanJobReq struct {
+	Symbol string
+	JobID  string
 }
 
 type GetBalanceTypeReq struct {
@@ -82,6 +107,27 @@ type OnOffScannerReq struct {
 	OnOff  int64 // 1.开 2.关
 }
 
+type GetScannerStateReq struct {
+	Symbol string
+}
+
+type GetCheckpointReq struct {
+	Symbol string
+}
+
+type GetDeadLettersReq struct {
+	Symbol string
+	Sink   string // 发送通道名称
+	Offset int
+	Limit  int
+}
+
+type ReplayDeadLettersReq struct {
+	Symbol string
+	Sink   string   // 发送通道名称
+	IDs    []uint64 // 为空时重新发送全部
+}
+
 type VerifyAddressReq struct {
 	Symbol  string
 	Address string
@@ -52,6 +52,32 @@ type RescannerHeightResp struct {
 type RescannerOneHeightResp struct {
 }
 
+type RescanRangeResp struct {
+	JobID string
+}
+
+type RescanAddressResp struct {
+	JobID string
+}
+
+type GetRescanJobResp struct {
+	JobID     string
+	Addresses []string // 按地址重扫时的地址
+	From      int64
+	To        int64
+	Current   int64   // 最近完成的高度
+	Scanned   int64   // 已完成的区块数
+	Progress  float64 // 完成比例0~1
+	Failed    []int64 // 失败的高度
+	Status    string  // running/done/cancelled/failed
+	Error     string
+	StartTime int64
+	EndTime   int64
+}
+
+type CancelRescanJobResp struct {
+}
+
 type GetBalanceTypeResp struct {
 	BalanceType int64
 }
@@ -59,6 +85,47 @@ type GetBalanceTypeResp struct {
 type OnOffScannerResp struct {
 }
 
+type GetScannerStateResp struct {
+	Running       bool   // 是否扫描中
+	Closed        bool   // 是否已关闭
+	Paused        bool   // consul开关是否暂停
+	Reason        string // 开关变更原因
+	UpdateTime    int64  // 开关变更时间
+	ScannedHeight uint64
+	NodeHeight    uint64
+	Lag           uint64
+	Role          string // 开启主节点选举时为leader/standby
+}
+
+type GetCheckpointResp struct {
+	Exist         bool   // 是否已保存检查点
+	Height        uint64 // 检查点区块高度
+	Hash          string // 检查点区块hash
+	Time          uint64 // 检查点区块时间
+	UpdateTime    int64  // 检查点更新时间
+	ScannedHeight uint64 // 适配器已扫描高度
+}
+
+type DeadLetter struct {
+	ID        uint64
+	Type      int64 // 事件类型
+	Queue     string
+	Content   interface{}
+	Signature string
+	Tries     int64  // 已发送次数
+	Ctime     int64  // 写入发件箱时间
+	Dtime     int64  // 移入死信时间
+	Error     string // 最后一次发送错误
+}
+
+type GetDeadLettersResp struct {
+	DeadLetters []*DeadLetter
+}
+
+type ReplayDeadLettersResp struct {
+	Count int // 重新发送的消息数
+}
+
 type VerifyAddressResp struct {
 	Result bool
 }
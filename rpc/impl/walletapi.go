@@ -17,7 +17,8 @@ import (
 type WalletApiService struct {
 }
 
-func (self *WalletApiService) BatchCreateAddress(req *dto.BatchCreateAddressReq, resp *dto.BatchCreateAddressResp) error {
+func (self *WalletApiService) BatchCreateAddress(req *dto.BatchCreateAddressReq, resp *dto.BatchCreateAddressResp) (err error) {
+	defer open_scanner.ObserveRPC("BatchCreateAddress", time.Now(), &err)
 	assetsMgr, err := open_scanner.GetAssetsManager(req.Symbol)
 	if err != nil {
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
@@ -62,7 +63,8 @@ func (self *WalletApiService) BatchCreateAddress(req *dto.BatchCreateAddressReq,
 	return nil
 }
 
-func (self *WalletApiService) PublicKeyToAddress(req *dto.PublicKeyToAddressReq, resp *dto.PublicKeyToAddressResp) error {
+func (self *WalletApiService) PublicKeyToAddress(req *dto.PublicKeyToAddressReq, resp *dto.PublicKeyToAddressResp) (err error) {
+	defer open_scanner.ObserveRPC("PublicKeyToAddress", time.Now(), &err)
 	assetsMgr, err := open_scanner.GetAssetsManager(req.Symbol)
 	if err != nil {
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
@@ -79,7 +81,8 @@ func (self *WalletApiService) PublicKeyToAddress(req *dto.PublicKeyToAddressReq,
 	return nil
 }
 
-func (self *WalletApiService) CreateRawTransaction(req *dto.CreateRawTransactionReq, resp *dto.CreateRawTransactionResp) error {
+func (self *WalletApiService) CreateRawTransaction(req *dto.CreateRawTransactionReq, resp *dto.CreateRawTransactionResp) (err error) {
+	defer open_scanner.ObserveRPC("CreateRawTransaction", time.Now(), &err)
 	assetsMgr, err := open_scanner.GetAssetsManager(req.Symbol)
 	if err != nil {
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
@@ -97,7 +100,8 @@ func (self *WalletApiService) CreateRawTransaction(req *dto.CreateRawTransaction
 	return nil
 }
 
-func (self *WalletApiService) SubmitRawTransaction(req *dto.SubmitRawTransactionReq, resp *dto.SubmitRawTransactionResp) error {
+func (self *WalletApiService) SubmitRawTransaction(req *dto.SubmitRawTransactionReq, resp *dto.SubmitRawTransactionResp) (err error) {
+	defer open_scanner.ObserveRPC("SubmitRawTransaction", time.Now(), &err)
 	assetsMgr, err := open_scanner.GetAssetsManager(req.Symbol)
 	if err != nil {
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
@@ -125,7 +129,8 @@ func (self *WalletApiService) SubmitRawTransaction(req *dto.SubmitRawTransaction
 	return nil
 }
 
-func (self *WalletApiService) CreateSummaryRawTransaction(req *dto.CreateSummaryRawTransactionReq, resp *dto.CreateSummaryRawTransactionReqResp) error {
+func (self *WalletApiService) CreateSummaryRawTransaction(req *dto.CreateSummaryRawTransactionReq, resp *dto.CreateSummaryRawTransactionReqResp) (err error) {
+	defer open_scanner.ObserveRPC("CreateSummaryRawTransaction", time.Now(), &err)
 	assetsMgr, err := open_scanner.GetAssetsManager(req.Symbol)
 	if err != nil {
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
@@ -154,7 +159,8 @@ func (self *WalletApiService) CreateSummaryRawTransaction(req *dto.CreateSummary
 	return nil
 }
 
-func (self *WalletApiService) GetBalanceByAddress(req *dto.GetBalanceByAddressReq, resp *dto.GetBalanceByAddressResp) error {
+func (self *WalletApiService) GetBalanceByAddress(req *dto.GetBalanceByAddressReq, resp *dto.GetBalanceByAddressResp) (err error) {
+	defer open_scanner.ObserveRPC("GetBalanceByAddress", time.Now(), &err)
 	assetsMgr, err := open_scanner.GetAssetsManager(req.Symbol)
 	if err != nil {
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
@@ -199,7 +205,8 @@ func (self *WalletApiService) GetBalanceByAddress(req *dto.GetBalanceByAddressRe
 	return nil
 }
 
-func (self *WalletApiService) GetTokenBalanceByAddress(req *dto.GetTokenBalanceByAddressReq, resp *dto.GetTokenBalanceByAddressResp) error {
+func (self *WalletApiService) GetTokenBalanceByAddress(req *dto.GetTokenBalanceByAddressReq, resp *dto.GetTokenBalanceByAddressResp) (err error) {
+	defer open_scanner.ObserveRPC("GetTokenBalanceByAddress", time.Now(), &err)
 	assetsMgr, err := open_scanner.GetAssetsManager(req.Symbol)
 	if err != nil {
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
@@ -244,7 +251,8 @@ func (self *WalletApiService) GetTokenBalanceByAddress(req *dto.GetTokenBalanceB
 	return nil
 }
 
-func (self *WalletApiService) GetRawTransactionFeeRate(req *dto.GetRawTransactionFeeRateReq, resp *dto.GetRawTransactionFeeRateResp) error {
+func (self *WalletApiService) GetRawTransactionFeeRate(req *dto.GetRawTransactionFeeRateReq, resp *dto.GetRawTransactionFeeRateResp) (err error) {
+	defer open_scanner.ObserveRPC("GetRawTransactionFeeRate", time.Now(), &err)
 	if req.Symbol == "TRX" {
 		return nil
 	}
@@ -261,7 +269,8 @@ func (self *WalletApiService) GetRawTransactionFeeRate(req *dto.GetRawTransactio
 	return nil
 }
 
-func (self *WalletApiService) RescannerHeight(req *dto.RescannerHeightReq, resp *dto.RescannerHeightResp) error {
+func (self *WalletApiService) RescannerHeight(req *dto.RescannerHeightReq, resp *dto.RescannerHeightResp) (err error) {
+	defer open_scanner.ObserveRPC("RescannerHeight", time.Now(), &err)
 	if len(req.Symbol) == 0 {
 		return util.Error("symbol [", req.Symbol, "] is nil")
 	}
@@ -277,6 +286,26 @@ func (self *WalletApiService) RescannerHeight(req *dto.RescannerHeightReq, resp
 	mlog := assetsMgr.GetAssetsLogger()
 
 	fmt.Println(&mlog)
+	o := open_scanner.GetScanner(req.Symbol)
+	if o != nil {
+		// 备用实例不能重扫
+		if err := o.CheckLeader(); err != nil {
+			return err
+		}
+	}
+	if req.IsForce > 0 {
+		// 重扫至当前高度的交易单强制重新发送,扫描完成后自动移除
+		if o != nil {
+			o.ForceRepublish(uint64(req.Height), scanner.GetScannedBlockHeight(), true)
+		}
+	}
+	if o != nil {
+		// 按开关状态恢复扫块
+		if err := o.RescanHeight(uint64(req.Height)); err != nil {
+			return util.Error("设置[", req.Symbol, "][", req.Height, "]重扫高度失败: ", err.Error())
+		}
+		return nil
+	}
 	scanner.Stop()
 	time.Sleep(30 * time.Second)
 	err = scanner.SetRescanBlockHeight(uint64(req.Height))
@@ -287,7 +316,8 @@ func (self *WalletApiService) RescannerHeight(req *dto.RescannerHeightReq, resp
 	return nil
 }
 
-func (self *WalletApiService) RescannerOneHeight(req *dto.RescannerOneHeightReq, resp *dto.RescannerOneHeightResp) error {
+func (self *WalletApiService) RescannerOneHeight(req *dto.RescannerOneHeightReq, resp *dto.RescannerOneHeightResp) (err error) {
+	defer open_scanner.ObserveRPC("RescannerOneHeight", time.Now(), &err)
 	if len(req.Symbol) == 0 {
 		return util.Error("symbol [", req.Symbol, "] is nil")
 	}
@@ -299,11 +329,110 @@ func (self *WalletApiService) RescannerOneHeight(req *dto.RescannerOneHeightReq,
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
 	}
 	scanner := assetsMgr.GetBlockScanner()
+	o := open_scanner.GetScanner(req.Symbol)
+	if o != nil {
+		if err := o.CheckLeader(); err != nil {
+			return err
+		}
+	}
+	if req.IsForce > 0 {
+		if o != nil {
+			release := o.ForceRepublish(uint64(req.Height), uint64(req.Height), false)
+			defer release()
+		}
+	}
 	scanner.ScanBlock(uint64(req.Height))
 	return nil
 }
 
-func (self *WalletApiService) GetBalanceType(req *dto.GetBalanceTypeReq, resp *dto.GetBalanceTypeResp) error {
+func (self *WalletApiService) RescanRange(req *dto.RescanRangeReq, resp *dto.RescanRangeResp) (err error) {
+	defer open_scanner.ObserveRPC("RescanRange", time.Now(), &err)
+	if len(req.Symbol) == 0 {
+		return util.Error("symbol [", req.Symbol, "] is nil")
+	}
+	if req.From <= 0 || req.To < req.From {
+		return util.Error("height [", req.From, "-", req.To, "] is invalid")
+	}
+	o := open_scanner.GetScanner(req.Symbol)
+	if o == nil {
+		return util.Error("scanner [", req.Symbol, "] is nil")
+	}
+	job, err := o.RescanRange(uint64(req.From), uint64(req.To), req.IsForce > 0)
+	if err != nil {
+		return err
+	}
+	resp.JobID = job.ID
+	return nil
+}
+
+func (self *WalletApiService) RescanAddress(req *dto.RescanAddressReq, resp *dto.RescanAddressResp) (err error) {
+	defer open_scanner.ObserveRPC("RescanAddress", time.Now(), &err)
+	if len(req.Symbol) == 0 {
+		return util.Error("symbol [", req.Symbol, "] is nil")
+	}
+	if len(req.Addresses) == 0 {
+		return util.Error("addresses is nil")
+	}
+	if req.From <= 0 || req.To < req.From {
+		return util.Error("height [", req.From, "-", req.To, "] is invalid")
+	}
+	o := open_scanner.GetScanner(req.Symbol)
+	if o == nil {
+		return util.Error("scanner [", req.Symbol, "] is nil")
+	}
+	job, err := o.RescanAddresses(req.Addresses, uint64(req.From), uint64(req.To), req.IsForce > 0)
+	if err != nil {
+		return err
+	}
+	resp.JobID = job.ID
+	return nil
+}
+
+func (self *WalletApiService) GetRescanJob(req *dto.GetRescanJobReq, resp *dto.GetRescanJobResp) (err error) {
+	defer open_scanner.ObserveRPC("GetRescanJob", time.Now(), &err)
+	if len(req.Symbol) == 0 {
+		return util.Error("symbol [", req.Symbol, "] is nil")
+	}
+	o := open_scanner.GetScanner(req.Symbol)
+	if o == nil {
+		return util.Error("scanner [", req.Symbol, "] is nil")
+	}
+	job, err := o.GetRescanJob(req.JobID)
+	if err != nil {
+		return err
+	}
+	resp.JobID = job.ID
+	resp.Addresses = job.Addresses
+	resp.From = int64(job.From)
+	resp.To = int64(job.To)
+	resp.Current = int64(job.Current)
+	resp.Scanned = int64(job.Scanned)
+	resp.Progress = job.Progress()
+	resp.Failed = make([]int64, 0, len(job.Failed))
+	for _, v := range job.Failed {
+		resp.Failed = append(resp.Failed, int64(v))
+	}
+	resp.Status = job.Status
+	resp.Error = job.Error
+	resp.StartTime = job.StartTime
+	resp.EndTime = job.EndTime
+	return nil
+}
+
+func (self *WalletApiService) CancelRescanJob(req *dto.CancelRescanJobReq, resp *dto.CancelRescanJobResp) (err error) {
+	defer open_scanner.ObserveRPC("CancelRescanJob", time.Now(), &err)
+	if len(req.Symbol) == 0 {
+		return util.Error("symbol [", req.Symbol, "] is nil")
+	}
+	o := open_scanner.GetScanner(req.Symbol)
+	if o == nil {
+		return util.Error("scanner [", req.Symbol, "] is nil")
+	}
+	return o.CancelRescanJob(req.JobID)
+}
+
+func (self *WalletApiService) GetBalanceType(req *dto.GetBalanceTypeReq, resp *dto.GetBalanceTypeResp) (err error) {
+	defer open_scanner.ObserveRPC("GetBalanceType", time.Now(), &err)
 	if len(req.Symbol) == 0 {
 		return util.Error("symbol [", req.Symbol, "] is nil")
 	}
@@ -319,7 +448,8 @@ func (self *WalletApiService) GetBalanceType(req *dto.GetBalanceTypeReq, resp *d
 	return nil
 }
 
-func (self *WalletApiService) OnOffScanner(req *dto.OnOffScannerReq, resp *dto.OnOffScannerResp) error {
+func (self *WalletApiService) OnOffScanner(req *dto.OnOffScannerReq, resp *dto.OnOffScannerResp) (err error) {
+	defer open_scanner.ObserveRPC("OnOffScanner", time.Now(), &err)
 	if len(req.Symbol) == 0 {
 		return util.Error("symbol [", req.Symbol, "] is nil")
 	}
@@ -327,6 +457,10 @@ func (self *WalletApiService) OnOffScanner(req *dto.OnOffScannerReq, resp *dto.O
 	if err != nil {
 		return util.Error("assetsMgr [", req.Symbol, "] is nil")
 	}
+	// 已启动开关监听时写入consul,重启后保持
+	if o := open_scanner.GetScanner(req.Symbol); o != nil && o.BlockScanner != nil {
+		return o.SetPause(req.OnOff != 1, "OnOffScanner")
+	}
 	if req.OnOff == 1 {
 		assetsMgr.GetBlockScanner().Run()
 	} else {
@@ -335,7 +469,99 @@ func (self *WalletApiService) OnOffScanner(req *dto.OnOffScannerReq, resp *dto.O
 	return nil
 }
 
-func (self *WalletApiService) VerifyAddress(req *dto.VerifyAddressReq, resp *dto.VerifyAddressResp) error {
+func (self *WalletApiService) GetScannerState(req *dto.GetScannerStateReq, resp *dto.GetScannerStateResp) (err error) {
+	defer open_scanner.ObserveRPC("GetScannerState", time.Now(), &err)
+	if len(req.Symbol) == 0 {
+		return util.Error("symbol [", req.Symbol, "] is nil")
+	}
+	o := open_scanner.GetScanner(req.Symbol)
+	if o == nil {
+		return util.Error("scanner [", req.Symbol, "] is nil")
+	}
+	status := o.Status()
+	resp.Running = status.Running
+	resp.Closed = status.Closed
+	resp.ScannedHeight = status.ScannedHeight
+	resp.NodeHeight = status.NodeHeight
+	resp.Lag = status.Lag
+	resp.Role = status.Role
+	if status.Control != nil {
+		resp.Paused = status.Control.Pause
+		resp.Reason = status.Control.Reason
+		resp.UpdateTime = status.Control.UpdateTime
+	}
+	return nil
+}
+
+func (self *WalletApiService) GetCheckpoint(req *dto.GetCheckpointReq, resp *dto.GetCheckpointResp) (err error) {
+	defer open_scanner.ObserveRPC("GetCheckpoint", time.Now(), &err)
+	if len(req.Symbol) == 0 {
+		return util.Error("symbol [", req.Symbol, "] is nil")
+	}
+	o := open_scanner.GetScanner(req.Symbol)
+	if o == nil || o.BlockScanner == nil {
+		return util.Error("scanner [", req.Symbol, "] is nil")
+	}
+	checkpoint, err := o.Checkpoint()
+	if err != nil {
+		return err
+	}
+	resp.ScannedHeight = o.BlockScanner.GetScannedBlockHeight()
+	if checkpoint != nil {
+		resp.Exist = true
+		resp.Height = checkpoint.Height
+		resp.Hash = checkpoint.Hash
+		resp.Time = checkpoint.Time
+		resp.UpdateTime = checkpoint.Utime
+	}
+	return nil
+}
+
+func (self *WalletApiService) GetDeadLetters(req *dto.GetDeadLettersReq, resp *dto.GetDeadLettersResp) (err error) {
+	defer open_scanner.ObserveRPC("GetDeadLetters", time.Now(), &err)
+	if len(req.Symbol) == 0 {
+		return util.Error("symbol [", req.Symbol, "] is nil")
+	}
+	o := open_scanner.GetScanner(req.Symbol)
+	if o == nil {
+		return util.Error("scanner [", req.Symbol, "] is nil")
+	}
+	list, err := o.DeadLetters(req.Sink, req.Offset, req.Limit)
+	if err != nil {
+		return err
+	}
+	resp.DeadLetters = make([]*dto.DeadLetter, 0, len(list))
+	for _, v := range list {
+		resp.DeadLetters = append(resp.DeadLetters, &dto.DeadLetter{
+			ID:        v.ID,
+			Type:      v.Data.Type,
+			Queue:     v.Data.Queue,
+			Content:   v.Data.Content,
+			Signature: v.Data.Signature,
+			Tries:     v.Tries,
+			Ctime:     v.Ctime,
+			Dtime:     v.Dtime,
+			Error:     v.Error,
+		})
+	}
+	return nil
+}
+
+func (self *WalletApiService) ReplayDeadLetters(req *dto.ReplayDeadLettersReq, resp *dto.ReplayDeadLettersResp) (err error) {
+	defer open_scanner.ObserveRPC("ReplayDeadLetters", time.Now(), &err)
+	if len(req.Symbol) == 0 {
+		return util.Error("symbol [", req.Symbol, "] is nil")
+	}
+	o := open_scanner.GetScanner(req.Symbol)
+	if o == nil {
+		return util.Error("scanner [", req.Symbol, "] is nil")
+	}
+	resp.Count, err = o.ReplayDeadLetters(req.Sink, req.IDs...)
+	return err
+}
+
+func (self *WalletApiService) VerifyAddress(req *dto.VerifyAddressReq, resp *dto.VerifyAddressResp) (err error) {
+	defer open_scanner.ObserveRPC("VerifyAddress", time.Now(), &err)
 	if len(req.Symbol) == 0 {
 		return util.Error("symbol [", req.Symbol, "] is nil")
 	}
@@ -373,7 +599,8 @@ func getABI(symbol, contractID string) (string, error) {
 	return contract.ABI, nil
 }
 
-func (self *WalletApiService) CallSmartContractABI(req *dto.CallSmartContractABIReq, resp *dto.CallSmartContractABIResp) error {
+func (self *WalletApiService) CallSmartContractABI(req *dto.CallSmartContractABIReq, resp *dto.CallSmartContractABIResp) (err error) {
+	defer open_scanner.ObserveRPC("CallSmartContractABI", time.Now(), &err)
 	if len(req.Rawtx.Coin.ContractID) > 0 {
 		abi, err := getABI(req.Symbol, req.Rawtx.Coin.Contract.ContractID)
 		if err != nil {
@@ -398,7 +625,8 @@ func (self *WalletApiService) CallSmartContractABI(req *dto.CallSmartContractABI
 	return nil
 }
 
-func (self *WalletApiService) CreateSmartContractTrade(req *dto.CreateSmartContractTradeReq, resp *dto.CreateSmartContractTradeResp) error {
+func (self *WalletApiService) CreateSmartContractTrade(req *dto.CreateSmartContractTradeReq, resp *dto.CreateSmartContractTradeResp) (err error) {
+	defer open_scanner.ObserveRPC("CreateSmartContractTrade", time.Now(), &err)
 	if len(req.Rawtx.Coin.ContractID) > 0 {
 		abi, err := getABI(req.Symbol, req.Rawtx.Coin.Contract.ContractID)
 		if err != nil {
@@ -423,7 +651,8 @@ func (self *WalletApiService) CreateSmartContractTrade(req *dto.CreateSmartContr
 	return nil
 }
 
-func (self *WalletApiService) SubmitSmartContractTrade(req *dto.SubmitSmartContractTradeReq, resp *dto.SubmitSmartContractTradeResp) error {
+func (self *WalletApiService) SubmitSmartContractTrade(req *dto.SubmitSmartContractTradeReq, resp *dto.SubmitSmartContractTradeResp) (err error) {
+	defer open_scanner.ObserveRPC("SubmitSmartContractTrade", time.Now(), &err)
 	if len(req.Rawtx.Coin.ContractID) > 0 {
 		abi, err := getABI(req.Symbol, req.Rawtx.Coin.ContractID)
 		if err != nil {
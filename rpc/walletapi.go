@@ -25,10 +25,26 @@ type WalletApiService interface {
 	RescannerHeight(req *dto.RescannerHeightReq, resp *dto.RescannerHeightResp) error
 	// 设置重扫单个高度
 	RescannerOneHeight(req *dto.RescannerOneHeightReq, resp *dto.RescannerOneHeightResp) error
+	// 创建区间重扫任务
+	RescanRange(req *dto.RescanRangeReq, resp *dto.RescanRangeResp) error
+	// 创建按地址重扫任务
+	RescanAddress(req *dto.RescanAddressReq, resp *dto.RescanAddressResp) error
+	// 查询重扫任务
+	GetRescanJob(req *dto.GetRescanJobReq, resp *dto.GetRescanJobResp) error
+	// 取消重扫任务
+	CancelRescanJob(req *dto.CancelRescanJobReq, resp *dto.CancelRescanJobResp) error
 	// 获取余额模型类型
 	GetBalanceType(req *dto.GetBalanceTypeReq, resp *dto.GetBalanceTypeResp) error
 	// 开启/暂停扫块器
 	OnOffScanner(req *dto.OnOffScannerReq, resp *dto.OnOffScannerResp) error
+	// 获取扫块器状态
+	GetScannerState(req *dto.GetScannerStateReq, resp *dto.GetScannerStateResp) error
+	// 获取扫描检查点
+	GetCheckpoint(req *dto.GetCheckpointReq, resp *dto.GetCheckpointResp) error
+	// 查询发送通道的死信消息
+	GetDeadLetters(req *dto.GetDeadLettersReq, resp *dto.GetDeadLettersResp) error
+	// 重新发送死信消息
+	ReplayDeadLetters(req *dto.ReplayDeadLettersReq, resp *dto.ReplayDeadLettersResp) error
 	// 校验地址
 	VerifyAddress(req *dto.VerifyAddressReq, resp *dto.VerifyAddressResp) error
 	// 调用智能合约ABI方法
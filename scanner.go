@@ -17,13 +17,15 @@ import (
 	"github.com/godaddy-x/jorm/sqlc"
 	"github.com/godaddy-x/jorm/sqld"
 	"github.com/godaddy-x/jorm/util"
-	"github.com/nbit99/open_base/major"
 	"github.com/nbit99/open_base/model"
+	"github.com/nbit99/open_scanner/event"
 	"github.com/nbit99/open_scanner/rpc"
 	"github.com/nbit99/open_scanner/rpc/dto"
 	"github.com/shopspring/decimal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -35,8 +37,10 @@ const (
 var Pause = flag.Int64("p", 0, "")
 
 func InitWallet(adapter openwallet.AssetsAdapter, walletapi service.WalletApiService, server *OpenWScanner) {
-	major.InitDB()
-	major.InitLog("scanner_" + strings.ToLower(server.Symbol))
+	if err := server.provider().InitEnv(server.Symbol); err != nil {
+		panic(err)
+	}
+	StartMetrics(*MetricsAddr)
 	server.Adapter = adapter
 	server.Walletapi = walletapi
 	server.Pause = *Pause
@@ -54,12 +58,39 @@ type OpenWScanner struct {
 	Pause        int64
 	DbPath       string
 	DbName       string
+	Repository   Repository      // 钱包数据访问实现,为空时使用默认实现
+	AddressIndex AddressIndex    // 扫块地址索引,为空时按币种配置创建
+	Notifier     Notifier        // 扫块消息发送实现,为空时使用AMQP
+	Signer       *Signer         // 消息签名,为空时读取consul签名配置
+	Provider     ConfigProvider  // 配置来源,为空时按-config参数选择consul或本地文件
+	Checkpoints  CheckpointStore // 扫描检查点存储,为空时按币种配置创建
+	outbox       *Outbox
+	routes       []*SinkRoute
+	reorg        *ReorgWindow
+	confirm      *ConfirmTracker
+	dedupe       *DedupeLedger
+	lagMonitor   *LagMonitor
+	control      *ControlWatcher
+	leader       *LeaderElector
+	scanMu       sync.Mutex
+	scanStarted  bool
+	rescans      *RescanManager
+	checkpoint   *Checkpointer
+	batcher      *TxBatcher
+	lookups      *LookupCache
+	indexRefresh *IndexRefresher
+	blockStop    bool // 币种BlockStop不为0,扫块器开关未配置时暂停
+	reload       *ConfigWatcher
+	callbacks    callbackTracker
+	dai          openwallet.BlockchainDAI
+	conf         atomic.Value    // *coinConfig,热加载时整体替换
+	restartKeys  map[string]bool // 启动时读取的配置项,修改后需重启生效
 }
 
 func (o *OpenWScanner) StartWallet() {
 	symbol := o.Symbol
 	adapter := o.Adapter
-	if err := major.LoadConsulSymbol(symbol); err != nil {
+	if err := o.provider().LoadSymbol(symbol); err != nil {
 		panic(err)
 	}
 	assets.RegAssets(symbol, adapter)
@@ -68,16 +99,25 @@ func (o *OpenWScanner) StartWallet() {
 		log.Error(symbol, err.Error())
 		return
 	}
+	registerScanner(o)
 	assetsMgr, err := GetAssetsManager(symbol)
 	if err != nil {
 		log.Error(symbol, "is not support")
 		return
 	}
-	if c, err := o.LoadConfig(symbol); err != nil {
+	c, err := o.LoadConfig(symbol)
+	if err != nil {
+		return
+	}
+	assetsMgr.LoadAssetsConfig(c) //读取对应配置(留下疑问，暂时一个币种只能支持一个机器)
+	// 记录初始化读取的配置项,热加载时据此判断是否需重启
+	rec := newKeyRecorder(c)
+	if err := o.initAddressIndex(rec); err != nil {
+		log.Error(symbol, " 地址索引初始化失败: ", err.Error())
 		return
-	} else {
-		assetsMgr.LoadAssetsConfig(c) //读取对应配置(留下疑问，暂时一个币种只能支持一个机器)
 	}
+	o.initReorgWindow(rec)
+	o.initLookupCache(rec)
 	// 加载费率缓存
 	if err := o.CacheFreerate(symbol); err != nil {
 		log.Error("cache [", symbol, "] freerate error: ", err.Error())
@@ -86,7 +126,12 @@ func (o *OpenWScanner) StartWallet() {
 	log.Notice(symbol, " Wallet Manager Load Successfully.")
 	if o.Pause == 0 {
 		//设置日志信息
-		logPath := major.InitNetwork().ScanLogPath
+		network, err := o.provider().Network()
+		if err != nil {
+			log.Error(symbol, " 读取网络配置失败: ", err.Error())
+			return
+		}
+		logPath := network.ScanLogPath
 		loggerSetting := assetsMgr.GetAssetsLogger()
 		logDir := filepath.Join(logPath)
 		file.MkdirAll(logDir)
@@ -110,83 +155,77 @@ func (o *OpenWScanner) StartWallet() {
 				return
 			}
 			scanner.SetBlockchainDAI(dai)
+			o.dai = dai
 		}
 		o.BlockScanner = scanner
 		//加载地址时，暂停区块扫描
 		scanner.Pause()
 		//扫块读取是否我们的地址,GetSourceKeyByAddress 获取地址对应的数据源标识
-		scanner.SetBlockScanTargetFunc(scanTargetFunc(symbol))
-		scanner.SetBlockScanTargetFuncV2(scanTargetFuncV2)
-		if o.InitHeight > 0 {
-			scanner.SetRescanBlockHeight(uint64(o.InitHeight))
-		}
+		scanner.SetBlockScanTargetFunc(scanTargetFunc(symbol, o.AddressIndex))
+		scanner.SetBlockScanTargetFuncV2(scanTargetFuncV2(o.AddressIndex, o.lookups))
+		// 按InitHeight/检查点/适配器确定扫描高度,ReHeight只重扫单个区块,不影响扫描高度
+		o.initCheckpointer(rec)
+		o.reconcileCheckpoint()
 		if o.ReHeight > 0 {
 			scanner.ScanBlock(uint64(o.ReHeight))
 		}
+		// 区块/交易单消息先写入发件箱再发送到各通道
+		if err := o.initSigner(); err != nil {
+			log.Error(symbol, " 消息签名初始化失败: ", err.Error())
+			return
+		}
+		if err := o.initNotifier(); err != nil {
+			log.Error(symbol, " 消息发送初始化失败: ", err.Error())
+			return
+		}
+		if err := o.initOutbox(rec); err != nil {
+			log.Error(symbol, " 发件箱初始化失败: ", err.Error())
+			return
+		}
+		o.initConfirmTracker(rec, coin.Confirm)
+		o.initDedupeLedger(rec)
+		o.initTxBatcher(rec)
 		// 设置walletapi接口实现类
-		scanner.SetBlockScanWalletDAI(NewWrapper("", "", "", symbol))
+		scanner.SetBlockScanWalletDAI(NewWrapper("", "", "", symbol, o.Repository))
 		//添加观测者到区块扫描器
 		scanner.AddObserver(o)
-		if coin.BlockStop == 0 {
+		o.startLagMonitor(rec)
+		o.blockStop = coin.BlockStop != 0
+		if o.startLeaderElector(rec, coin.BlockStop == 0) {
+			log.Info(symbol, " 扫块启动成功(等待选举)...")
+		} else if coin.BlockStop == 0 {
 			log.Info(symbol, " 扫块启动成功(运行中)...")
-			scanner.Run()
+			o.resumeScanner()
 		} else {
 			log.Info(symbol, " 扫块启动成功(暂停中)...")
 		}
-		//o.CheckBlockScannerState()
-	}
-}
-
-func (o *OpenWScanner) CheckBlockScannerState() error {
-	go func() {
-		pause := false
-		for {
-			time.Sleep(20 * time.Second)
-			mongo, err := new(sqld.MGOManager).Get()
-			if err != nil {
-				log.Error("检测币种开关状态获取mongo失败")
-				continue
-			}
-			defer mongo.Close()
-			conf := model.OwSetting{}
-			if err := mongo.FindOne(sqlc.M(model.OwSetting{}).Eq("platform", "scanner"), &conf); err != nil {
-				log.Error("检测币种开关状态获取数据失败失败")
-				continue
-			}
-			if conf.Id == 0 {
-				continue
-			}
-			if conf.IsOff > 0 {
-				if !pause {
-					o.BlockScanner.Pause()
-					pause = true
-					log.Warn(util.AddStr("[", o.Symbol, "]扫块器已暂停"))
-				}
-			} else {
-				if pause {
-					o.BlockScanner.Restart()
-					pause = false
-					log.Warn(util.AddStr("[", o.Symbol, "]扫块器已启动"))
-				}
+		// 监听consul中的扫块器开关
+		if o.provider().UseConsul() {
+			if err := o.CheckBlockScannerState(); err != nil {
+				log.Error(symbol, " 扫块器开关监听启动失败: ", err.Error())
 			}
 		}
-	}()
-	return nil
+	}
+	// 监听币种配置变更
+	o.restartKeys = rec.Keys()
+	if o.provider().UseConsul() {
+		o.startConfigWatcher()
+	}
 }
 
 // 扫块器回调函数
-func scanTargetFunc(symbol string) func(target openwallet.ScanTarget) (string, bool) {
+func scanTargetFunc(symbol string, index AddressIndex) func(target openwallet.ScanTarget) (string, bool) {
 	return func(target openwallet.ScanTarget) (string, bool) {
 		//如果余额模型是地址，查找地址表
 		if target.BalanceModelType == openwallet.BalanceModelTypeAddress {
-			if accountID, err := getAccountIDByAddress(target.Address, strings.ToUpper(symbol)); err != nil || accountID == "" {
+			if accountID, err := index.AccountIDByAddress(target.Address, strings.ToUpper(symbol)); err != nil || accountID == "" {
 				return "", false
 			} else {
 				return accountID, true
 			}
 		} else {
 			//如果余额模型是账户，用别名操作账户的别名
-			if accountID, err := getAccountIDByAlias(target.Alias, strings.ToUpper(symbol)); err != nil || accountID == "" {
+			if accountID, err := index.AccountIDByAlias(target.Alias, strings.ToUpper(symbol)); err != nil || accountID == "" {
 				return "", false
 			} else {
 				return accountID, true
@@ -196,49 +235,51 @@ func scanTargetFunc(symbol string) func(target openwallet.ScanTarget) (string, b
 }
 
 // 扫块器回调函数V2 0: 账户地址，1：账户别名，2：合约地址，3：合约别名，4：地址公钥
-func scanTargetFuncV2(target openwallet.ScanTargetParam) openwallet.ScanTargetResult {
-	if target.ScanTargetType == 0 { // 地址模型
-		if accountID, err := getAccountIDByAddress(target.ScanTarget, strings.ToUpper(target.Symbol)); err != nil || accountID == "" {
-			return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
-		} else {
-			return openwallet.ScanTargetResult{SourceKey: accountID, Exist: true}
-		}
-	} else if target.ScanTargetType == 1 { // 账户模型
-		if accountID, err := getAccountIDByAlias(target.ScanTarget, strings.ToUpper(target.Symbol)); err != nil || accountID == "" {
-			return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
-		} else {
-			return openwallet.ScanTargetResult{SourceKey: accountID, Exist: true}
-		}
-	} else if target.ScanTargetType == 2 || target.ScanTargetType == 3 {
-		mongo, err := new(sqld.MGOManager).Get()
-		if err != nil {
-			log2.Error("扫块器回调查询合约 - 获取mongo失败", 0, log2.AddError(err))
-			return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
-		}
-		defer mongo.Close()
-		contract := model.OwContract{}
-		if err := mongo.FindOne(sqlc.M(model.OwContract{}).Eq("symbol", target.Symbol).Eq("address", target.ScanTarget).Eq("state", 1), &contract); err != nil {
-			log2.Error("扫块器回调查询合约 - 获取数据失败", 0, log2.AddError(err))
-			return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
-		}
-		if contract.Id == 0 {
-			return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
-		}
-		smart := &openwallet.SmartContract{
-			ContractID: contract.ContractID,
-			Symbol:     contract.Symbol,
-			Address:    contract.Address,
-			Token:      contract.Token,
-			Protocol:   contract.Protocol,
-			Name:       contract.Name,
-			Decimals:   uint64(contract.Decimals),
-		}
-		smart.SetABI(contract.ABI)
-		return openwallet.ScanTargetResult{SourceKey: contract.ContractID, Exist: true, TargetInfo: smart}
-	} else if target.ScanTargetType == 4 {
+func scanTargetFuncV2(index AddressIndex, lookups *LookupCache) openwallet.BlockScanTargetFuncV2 {
+	return func(target openwallet.ScanTargetParam) openwallet.ScanTargetResult {
+		if target.ScanTargetType == 0 { // 地址模型
+			start := time.Now()
+			accountID, err := index.AccountIDByAddress(target.ScanTarget, strings.ToUpper(target.Symbol))
+			observeLookup(strings.ToUpper(target.Symbol), indexStore(index), "address", start)
+			if err != nil || accountID == "" {
+				return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
+			} else {
+				return openwallet.ScanTargetResult{SourceKey: accountID, Exist: true}
+			}
+		} else if target.ScanTargetType == 1 { // 账户模型
+			start := time.Now()
+			accountID, err := index.AccountIDByAlias(target.ScanTarget, strings.ToUpper(target.Symbol))
+			observeLookup(strings.ToUpper(target.Symbol), indexStore(index), "alias", start)
+			if err != nil || accountID == "" {
+				return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
+			} else {
+				return openwallet.ScanTargetResult{SourceKey: accountID, Exist: true}
+			}
+		} else if target.ScanTargetType == 2 || target.ScanTargetType == 3 {
+			contract, err := findContractByAddress(lookups, target.Symbol, target.ScanTarget)
+			if err != nil {
+				log2.Error("扫块器回调查询合约 - 获取数据失败", 0, log2.AddError(err))
+				return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
+			}
+			if contract == nil {
+				return openwallet.ScanTargetResult{SourceKey: "", Exist: false}
+			}
+			smart := &openwallet.SmartContract{
+				ContractID: contract.ContractID,
+				Symbol:     contract.Symbol,
+				Address:    contract.Address,
+				Token:      contract.Token,
+				Protocol:   contract.Protocol,
+				Name:       contract.Name,
+				Decimals:   uint64(contract.Decimals),
+			}
+			smart.SetABI(contract.ABI)
+			return openwallet.ScanTargetResult{SourceKey: contract.ContractID, Exist: true, TargetInfo: smart}
+		} else if target.ScanTargetType == 4 {
 
+		}
+		return openwallet.ScanTargetResult{}
 	}
-	return openwallet.ScanTargetResult{}
 }
 
 func (o *OpenWScanner) CacheFreerate(symbol string) error {
@@ -266,6 +307,10 @@ func (o *OpenWScanner) CacheFreerate(symbol string) error {
 }
 
 func (o *OpenWScanner) AddRegistration() {
+	if !o.provider().UseConsul() {
+		o.serveLocal(o.Symbol + "钱包RPC服务启动成功(本地配置)")
+		return
+	}
 	// 注册服务
 	consulx, err := new(consul.ConsulManager).Client(o.Symbol)
 	if err != nil {
@@ -276,172 +321,233 @@ func (o *OpenWScanner) AddRegistration() {
 	consulx.ClearTagService(tag)
 	// 注册RPC服务
 	consulx.AddRegistration(tag, o.Walletapi)
-	// 启动RPC服务
-	consulx.StartListenAndServe()
-	major.ForeverWait(o.Symbol + "钱包RPC服务启动成功")
+	// 注册健康检查及消息Schema
+	registerHealthHandlers()
+	registerSchemaHandler()
+	if err := registerReadyCheck(consulx, tag, o.Symbol, consulx.Config.CheckPort); err != nil {
+		log.Error(o.Symbol, " 注册就绪检查失败: ", err.Error())
+	}
+	// 启动RPC服务,收到退出信号后停止扫块并注销服务
+	go consulx.StartListenAndServe()
+	o.waitShutdown(consulx, tag, o.Symbol+"钱包RPC服务启动成功")
 }
 
 //BlockScanNotify 新区块扫描完成通知
 func (o *OpenWScanner) BlockScanNotify(header *openwallet.BlockHeader) error {
-	ret, sig, err := major.GenMQDataSig(header)
+	defer o.callbacks.enter()()
+	// 区间重扫的历史区块只发送消息
+	live := !o.rescanning(header.Height)
+	// 检测链重组,回滚消息先于新区块发送
+	if o.reorg != nil && live {
+		if blocks := o.reorg.AddHeader(header); len(blocks) > 0 {
+			o.notifyRollback(header, blocks)
+			if o.confirm != nil {
+				if err := o.confirm.Rollback(blocks); err != nil {
+					log2.Error("删除回滚区块待确认交易单失败", 0, log2.String("symbol", o.Symbol), log2.AddError(err))
+				}
+			}
+		}
+	}
+	// 批量模式下区块的交易单先于区块消息发送,发送失败时不发送区块消息,由适配器重扫
+	if o.batcher != nil {
+		if err := o.batcher.Flush(header.Hash); err != nil {
+			return err
+		}
+	}
+	// 交易单发送后再记录检查点,避免重扫前重启时跳过该区块
+	if !header.Fork && live {
+		observeBlock(o.Symbol, header.Height)
+		if o.lagMonitor != nil {
+			o.lagMonitor.Notify(header.Height)
+		}
+		if o.checkpoint != nil {
+			o.checkpoint.Notify(header)
+		}
+	}
+	ret, sig, err := o.Signer.Sign(event.NewBlock(header))
 	if err != nil {
 		log2.Warn(err.Error(), 0, log2.Any("header", header))
 		return nil
 	}
-	client, err := new(rabbitmq.PublishManager).Client()
-	if err != nil {
-		log.Error(err.Error())
-	}
-	if err := client.Publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + o.Symbol, Type: 1, Content: ret, Signature: sig}); err != nil {
+	if err := o.publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + o.Symbol, Type: EventBlock, Content: ret, Signature: sig}); err != nil {
 		log2.Error("区块数据发送MQ异常", 0, log2.String("symbol", o.Symbol), log2.String("exchange", exchange), log2.String("queue", queue+o.Symbol), log2.Any("content", header), log2.AddError(err))
 	}
+	if o.confirm != nil && !header.Fork && live {
+		o.notifyConfirmed(header.Height)
+	}
+	if o.dedupe != nil && !header.Fork && live {
+		o.dedupe.Advance(header.Height)
+	}
 	return nil
 }
 
 //BlockExtractDataNotify 区块提取结果通知
 func (o *OpenWScanner) BlockExtractDataNotify(sourceKey string, data *openwallet.TxExtractData) error {
+	defer o.callbacks.enter()()
 	//jv, _ := util.ObjectToJson(data)
 	//fmt.Println("test------", sourceKey, jv)
-	mongo, err := new(sqld.MGOManager).Get()
+	// 批量模式下缓存到区块扫描完成后合并发送
+	if o.batcher != nil && data.Transaction != nil {
+		o.batcher.Add(sourceKey, data)
+		return nil
+	}
+	account, err := findAccount(o.lookups, sourceKey)
 	if err != nil {
-		return errors.New("mongo create err")
+		return err
 	}
-	defer mongo.Close()
-	unusual := false
-	account := model.OwAccount{}
-	contract := model.OwContract{}
-	if err := mongo.FindOne(sqlc.M(model.OwAccount{}).Eq("accountID", sourceKey).Eq("state", 1), &account); err != nil {
+	var owner *txOwner
+	if account != nil {
+		owner = accountOwner(account)
+	} else if contract, err := findContract(o.lookups, sourceKey); err != nil {
 		return err
+	} else if contract != nil {
+		owner = contractOwner(contract)
+	} else {
+		return util.Error("Wrapper Account or Contract[", sourceKey, "] Not Exist")
 	}
-	if account.Id == 0 {
-		if err := mongo.FindOne(sqlc.M(model.OwContract{}).Eq("contractID", sourceKey).Eq("state", 1), &contract); err != nil {
-			return err
-		}
-		if contract.Id > 0 {
-			unusual = true
-		}
+	if data.Transaction == nil {
+		o.observeOwner(owner)
+		return nil
 	}
-	if account.Id == 0 && contract.Id == 0 {
-		return util.Error("Wrapper Account or Contract[", sourceKey, "] Not Exist")
+	result, err := o.newTxEvent(sourceKey, data, owner)
+	if err != nil || result == nil {
+		return err
 	}
-	if data.Transaction != nil {
-		amount := decimal.NewFromFloat(0)
-		if data.TxInputs != nil {
-			for _, v := range data.TxInputs {
-				if v.Amount == "" {
-					continue
-				}
-				inputAmount, err := decimal.NewFromString(v.Amount)
-				if err != nil {
-					return util.Error("input get amount error!")
-				}
-				amount = amount.Sub(inputAmount)
+	ret, sig, err := o.Signer.Sign(result)
+	if err != nil {
+		log2.Warn(err.Error(), 0, log2.Any("content", result))
+		return nil
+	}
+	err = o.publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + o.Symbol, Type: EventTx, Content: ret, Signature: sig})
+	if err != nil {
+		log2.Error("发送MQ数据失败", 0, log2.String("appid", owner.AppID), log2.String("exchange", exchange), log2.String("queue", queue+o.Symbol), log2.Any("content", result), log2.AddError(err))
+	}
+	o.txPublished(sourceKey, owner, result, err == nil)
+	return nil
+}
+
+// 交易单所属的资产账户或合约,合约交易单的appID/walletID/accountID均为合约ID
+type txOwner struct {
+	AppID      string
+	WalletID   string
+	AccountID  string
+	ContractID string
+}
+
+func accountOwner(account *model.OwAccount) *txOwner {
+	return &txOwner{AppID: account.AppID, WalletID: account.WalletID, AccountID: account.AccountID}
+}
+
+func contractOwner(contract *model.OwContract) *txOwner {
+	return &txOwner{AppID: contract.ContractID, WalletID: contract.ContractID, AccountID: contract.ContractID, ContractID: contract.ContractID}
+}
+
+func (o *OpenWScanner) observeOwner(owner *txOwner) {
+	if len(owner.ContractID) > 0 {
+		observeExtract(o.Symbol, "contract")
+	} else {
+		observeExtract(o.Symbol, "account")
+	}
+}
+
+// 计算账户净变动数量并生成交易单消息,记录到重组窗口
+// 已发送的交易单按去重模式处理,suppress模式下返回nil
+func (o *OpenWScanner) newTxEvent(sourceKey string, data *openwallet.TxExtractData, owner *txOwner) (*event.Tx, error) {
+	o.observeOwner(owner)
+	amount := decimal.NewFromFloat(0)
+	if data.TxInputs != nil {
+		for _, v := range data.TxInputs {
+			if v.Amount == "" {
+				continue
 			}
-		}
-		if data.TxOutputs != nil {
-			for _, v := range data.TxOutputs {
-				if v.Amount == "" {
-					continue
-				}
-				outputAmount, err := decimal.NewFromString(v.Amount)
-				if err != nil {
-					return util.Error("output get amount error!")
-				}
-				amount = amount.Add(outputAmount)
+			inputAmount, err := decimal.NewFromString(v.Amount)
+			if err != nil {
+				return nil, util.Error("input get amount error!")
 			}
+			amount = amount.Sub(inputAmount)
 		}
-		data.Transaction.Amount = amount.String()
-		var result map[string]interface{}
-		if unusual { // 智能合约交易单
-			//trade_sec := model.OwTrade{}
-			//if err := mongo.FindOne(sqlc.M(model.OwTrade{}).Eq("txID", data.Transaction.TxID).Eq("reqtype", 2), &trade_sec); err != nil {
-			//	return util.Error("查询广播交易记录失败: [", sourceKey, "-", data.Transaction.TxID, "],", err.Error())
-			//}
-			//if trade_sec.Id == 0 {
-			//	return util.Error("查询广播交易记录无效: [", sourceKey, "-", data.Transaction.TxID, "],", err.Error())
-			//}
-			result = map[string]interface{}{
-				"appID":      contract.ContractID,
-				"walletID":   contract.ContractID,
-				"accountID":  contract.ContractID,
-				"dataType":   2,
-				"content":    data.Transaction,
-				"inputs":     data.TxInputs,
-				"outputs":    data.TxOutputs,
-				"contractID": contract.ContractID,
+	}
+	if data.TxOutputs != nil {
+		for _, v := range data.TxOutputs {
+			if v.Amount == "" {
+				continue
 			}
-		} else { // 普通交易单
-			result = map[string]interface{}{
-				"appID":      account.AppID,
-				"walletID":   account.WalletID,
-				"accountID":  account.AccountID,
-				"dataType":   2,
-				"content":    data.Transaction,
-				"inputs":     data.TxInputs,
-				"outputs":    data.TxOutputs,
-				"contractID": "",
+			outputAmount, err := decimal.NewFromString(v.Amount)
+			if err != nil {
+				return nil, util.Error("output get amount error!")
 			}
+			amount = amount.Add(outputAmount)
 		}
-		ret, sig, err := major.GenMQDataSig(result)
-		if err != nil {
-			log2.Warn(err.Error(), 0, log2.Any("content", result))
-			return nil
-		}
-		client, err := new(rabbitmq.PublishManager).Client()
-		if err != nil {
-			log2.Error("获取mq连接失败", 0, log2.AddError(err))
+	}
+	data.Transaction.Amount = amount.String()
+	result := &event.Tx{SchemaVersion: event.SchemaVersion, DataType: event.DataTypeTx, Content: data.Transaction, Inputs: data.TxInputs, Outputs: data.TxOutputs}
+	result.AppID = owner.AppID
+	result.WalletID = owner.WalletID
+	result.AccountID = owner.AccountID
+	result.ContractID = owner.ContractID
+	if o.reorg != nil {
+		o.reorg.AddTx(data.Transaction.BlockHeight, data.Transaction.BlockHash, data.Transaction.TxID)
+	}
+	// 已发送的交易单按去重模式处理,强制重新发送区间内不去重
+	if o.dedupe != nil && !o.dedupe.Forced(data.Transaction.BlockHeight) {
+		if delivered, err := o.dedupe.Delivered(o.Symbol, data.Transaction.TxID, sourceKey, data.Transaction.BlockHash); err != nil {
+			log2.Error("读取交易单发送记录失败", 0, log2.String("symbol", o.Symbol), log2.String("txid", data.Transaction.TxID), log2.AddError(err))
+		} else if delivered {
+			if o.dedupe.Mode() == DedupeSuppress {
+				log2.Info("交易单已发送,忽略", 0, log2.String("symbol", o.Symbol), log2.String("txid", data.Transaction.TxID), log2.String("sourceKey", sourceKey))
+				return nil, nil
+			}
+			result.Redelivery = true
 		}
-		if err := client.Publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + o.Symbol, Type: 2, Content: ret, Signature: sig}); err != nil {
-			log2.Error("发送MQ数据失败", 0, log2.String("appid", account.AppID), log2.String("exchange", exchange), log2.String("queue", queue+o.Symbol), log2.Any("content", result))
+	}
+	return result, nil
+}
+
+// 交易单发送后记录发送记录及待确认交易单,重新发送的交易单不再记录
+func (o *OpenWScanner) txPublished(sourceKey string, owner *txOwner, result *event.Tx, published bool) {
+	tx := result.Content
+	if o.dedupe != nil && published && !result.Redelivery {
+		if err := o.dedupe.Mark(o.Symbol, tx.TxID, sourceKey, tx.BlockHash, tx.BlockHeight); err != nil {
+			log2.Error("记录交易单发送失败", 0, log2.String("symbol", o.Symbol), log2.String("txid", tx.TxID), log2.AddError(err))
 		}
 	}
-	return nil
+	// 发送失败(未写入发件箱)的交易单不跟踪,重新提取后再记录
+	if !published || result.Redelivery || o.confirm == nil {
+		return
+	}
+	pending := PendingTx{AppID: owner.AppID, WalletID: owner.WalletID, AccountID: owner.AccountID, ContractID: owner.ContractID, TxID: tx.TxID, BlockHash: tx.BlockHash, BlockHeight: tx.BlockHeight}
+	if err := o.confirm.Track(pending); err != nil {
+		log2.Error("记录待确认交易单失败", 0, log2.String("symbol", o.Symbol), log2.String("txid", pending.TxID), log2.AddError(err))
+	}
 }
 
 // 提取智能合约交易单
 func (o *OpenWScanner) BlockExtractSmartContractDataNotify(sourceKey string, data *openwallet.SmartContractReceipt) error {
-	mongo, err := new(sqld.MGOManager).Get()
+	defer o.callbacks.enter()()
+	contract, err := findContract(o.lookups, sourceKey)
 	if err != nil {
-		return errors.New("mongo create err")
-	}
-	defer mongo.Close()
-	contract := model.OwContract{}
-	if err := mongo.FindOne(sqlc.M(model.OwContract{}).Eq("contractID", sourceKey).Eq("state", 1), &contract); err != nil {
 		return err
 	}
-	if contract.Id == 0 {
+	if contract == nil {
 		return util.Error("Wrapper Contract [", sourceKey, "] Not Exist")
 	}
+	observeExtract(o.Symbol, "receipt")
 	//info, _ := util.ObjectToJson(data)
 	//fmt.Println("BlockExtractSmartContractDataNotify------", info)
-	ret, sig, err := major.GenMQDataSig(data)
+	ret, sig, err := o.Signer.Sign(event.NewReceipt(data))
 	if err != nil {
 		log2.Warn(err.Error(), 0, log2.Any("content", data))
 		return nil
 	}
-	client, err := new(rabbitmq.PublishManager).Client()
-	if err != nil {
-		log2.Error("获取mq连接失败", 0, log2.AddError(err))
+	if err := o.publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + "receipt", Type: EventReceipt, Content: ret, Signature: sig}); err != nil {
+		log2.Error("发送MQ数据失败", 0, log2.String("exchange", exchange), log2.String("queue", queue+"receipt"), log2.Any("content", data), log2.AddError(err))
 	}
-	if err := client.Publish(rabbitmq.MsgData{Exchange: exchange, Queue: queue + "receipt", Type: 3, Content: ret, Signature: sig}); err != nil {
-		log2.Error("发送MQ数据失败", 0, log2.String("exchange", exchange), log2.String("queue", queue+"receipt"), log2.Any("content", data))
+	if o.reorg != nil {
+		o.reorg.AddTx(data.BlockHeight, data.BlockHash, data.TxID)
 	}
 	return nil
 }
 
-func genDataSig(result interface{}) (string, string, error) {
-	str, err := util.ObjectToJson(result)
-	if err != nil || len(str) == 0 {
-		return "", "", util.Error("区块/交易单数据转换JSON失败")
-	}
-	ret := util.Base64URLEncode(str)
-	if len(str) == 0 {
-		return "", "", util.Error("区块/交易单数据base64编码失败")
-	}
-	sig := util.MD5(ret, major.InitNetwork().MQSecretKey)
-	return ret, sig, nil
-}
-
 func ValidConfigSymbol(symbol string) (*model.OwSymbol, error) {
 	mongo, err := new(sqld.MGOManager).Get()
 	if err != nil {
@@ -459,67 +565,17 @@ func ValidConfigSymbol(symbol string) (*model.OwSymbol, error) {
 }
 
 func (o *OpenWScanner) LoadConfig(symbol string) (config.Configer, error) {
-	consulx, err := new(consul.ConsulManager).Client()
-	if err != nil {
-		panic(err)
-	}
-	result, err := consulx.GetKV(util.AddStr("coin/", symbol, ".ini"))
+	c, result, err := o.provider().CoinConfig(symbol)
 	if err != nil {
 		log.Error(symbol, " load error: ", err.Error())
 		return nil, err
 	}
-	c, err := config.NewConfigData("ini", result)
-	if err != nil {
-		log.Error("load ini config err: ", err.Error())
-		return nil, err
-	}
 	o.DbPath = c.String("dataDir")
 	o.DbName = symbol + ".db"
+	o.setConfig(c, result)
 	return c, nil
 }
 
-func getAccountIDByAddress(address, symbol string) (string, error) {
-	client, err := new(cache.RedisManager).Client()
-	if err != nil {
-		log2.Error(util.AddStr("[", symbol, "]通过地址[", address, "]获取redis失败: ", err), 0)
-		return "", nil
-	}
-	obj := major.CacheValue{}
-	if _, err := client.Get(util.AddStr(symbol, address), &obj); err != nil {
-		log2.Error(util.AddStr("[", symbol, "]通过地址[", address, "]读取账号ID失败: ", err), 0)
-		return "", nil
-	}
-	if len(obj.V) == 0 {
-		if symbol == model.ETH {
-			if _, err := client.Get(util.AddStr(model.TRUE, address), &obj); err != nil {
-				log2.Error(util.AddStr("[", model.TRUE, "]通过地址[", address, "]读取账号ID失败: ", err), 0)
-				return "", nil
-			}
-		}
-		if len(obj.V) == 0 {
-			return "", nil
-		}
-	}
-	return obj.V, nil
-}
-
-func getAccountIDByAlias(alias, symbol string) (string, error) {
-	client, err := new(cache.RedisManager).Client()
-	if err != nil {
-		log2.Error(util.AddStr("[", symbol, "]通过别名[", alias, "]获取redis失败: ", err), 0)
-		return "", nil
-	}
-	obj := major.CacheValue{}
-	if _, err := client.Get(util.AddStr(symbol, alias), &obj); err != nil {
-		log2.Error(util.AddStr("[", symbol, "]通过别名[", alias, "]读取账号ID失败: ", err), 0)
-		return "", nil
-	}
-	if len(obj.V) == 0 {
-		return "", nil
-	}
-	return obj.V, nil
-}
-
 // GetAssetsController 获取资产控制器 -
 func GetAssetsManager(symbol string) (openwallet.AssetsAdapter, error) {
 	adapter := assets.GetAssets(symbol)
@@ -533,3 +589,17 @@ func GetAssetsManager(symbol string) (openwallet.AssetsAdapter, error) {
 	}
 	return manager, nil
 }
+
+var scanners sync.Map
+
+func registerScanner(o *OpenWScanner) {
+	scanners.Store(strings.ToUpper(o.Symbol), o)
+}
+
+// GetScanner 获取已启动的币种扫块服务,未启动时返回nil
+func GetScanner(symbol string) *OpenWScanner {
+	if v, ok := scanners.Load(strings.ToUpper(symbol)); ok {
+		return v.(*OpenWScanner)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package open_scanner
+
+import (
+	"github.com/nbit99/open_scanner/event"
+	"net/http"
+	"sync"
+)
+
+const schemaPath = "/schema/"
+
+var schemaOnce sync.Once
+
+// 注册/schema/<name>.json发布消息的JSON Schema,与健康检查使用同一端口(CheckPort)
+func registerSchemaHandler() {
+	schemaOnce.Do(func() {
+		http.Handle(schemaPath, event.SchemaHandler(schemaPath))
+	})
+}
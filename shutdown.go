@@ -0,0 +1,189 @@
+package open_scanner
+
+import (
+	"fmt"
+	"github.com/godaddy-x/jorm/consul"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/nbit99/openwallet/v2/log"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultShutdownTimeout = 30
+	shutdownQuiet          = 2 * time.Second
+	shutdownPoll           = 200 * time.Millisecond
+)
+
+// 扫块回调计数,暂停扫块后等待正在执行的回调完成
+type callbackTracker struct {
+	mu      sync.Mutex
+	running int64
+	last    time.Time
+}
+
+// 回调开始,返回结束函数,用法: defer o.callbacks.enter()()
+func (t *callbackTracker) enter() func() {
+	t.mu.Lock()
+	t.running++
+	t.last = time.Now()
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		t.running--
+		t.last = time.Now()
+		t.mu.Unlock()
+	}
+}
+
+// 暂停后扫描任务仍可能继续执行当前批次,无回调执行且静默quiet后视为完成
+func (t *callbackTracker) idle(quiet time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running == 0 && time.Since(t.last) >= quiet
+}
+
+// 等待服务退出信号(SIGINT/SIGTERM),收到后执行Shutdown
+func (o *OpenWScanner) waitShutdown(consulx *consul.ConsulManager, tag string, msg string) {
+	stop := waitSignal(msg)
+	defer stop()
+	o.Shutdown(consulx, tag)
+}
+
+// 等待退出信号,返回后再次收到信号时直接退出,停止服务后调用返回的函数
+func waitSignal(msg string) func() {
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	fmt.Println("启动信息: ", msg)
+	sig := <-c
+	log.Warn("收到退出信号: ", sig.String())
+	go func() {
+		<-c
+		log.Error("再次收到退出信号,强制退出")
+		os.Exit(1)
+	}()
+	return func() {
+		signal.Stop(c)
+	}
+}
+
+// Shutdown 停止扫块服务,顺序: 暂停扫块 -> 等待回调及发件箱消息发送完成 -> 释放主节点 -> 注销consul服务 -> 关闭区块数据库
+// 等待时间读取币种ini(shutdownTimeout,秒),超时后未发送的消息保留在发件箱,下次启动继续发送
+func (o *OpenWScanner) Shutdown(consulx *consul.ConsulManager, tag string) {
+	timeout := time.Duration(defaultShutdownTimeout) * time.Second
+	if c := o.currentConfig(); c != nil {
+		timeout = time.Duration(c.DefaultInt64("shutdownTimeout", defaultShutdownTimeout)) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	// 1. 暂停扫块
+	if o.control != nil {
+		o.control.Stop()
+	}
+	if o.reload != nil {
+		o.reload.Stop()
+	}
+	if o.lagMonitor != nil {
+		o.lagMonitor.Stop()
+	}
+	if o.rescans != nil {
+		o.rescans.Stop()
+	}
+	o.indexRefresh.Stop()
+	if o.BlockScanner != nil {
+		if err := o.BlockScanner.Pause(); err != nil {
+			log.Error(o.Symbol, " 暂停扫块失败: ", err.Error())
+		}
+	}
+	// 2. 等待回调及消息发送
+	if !waitUntil(deadline, func() bool { return o.callbacks.idle(shutdownQuiet) }) {
+		log.Warn(o.Symbol, " 等待扫块回调完成超时")
+	}
+	if o.batcher != nil {
+		o.batcher.Stop()
+	}
+	if o.checkpoint != nil {
+		o.checkpoint.Flush()
+	}
+	if o.outbox != nil {
+		if !waitUntil(deadline, o.outboxDrained) {
+			pending, _ := o.outbox.Pending()
+			log2.Warn("等待发件箱消息发送超时", 0, log2.String("symbol", o.Symbol), log2.Any("pending", pending))
+		}
+		if err := o.outbox.Close(); err != nil {
+			log.Error(o.Symbol, " 关闭发件箱失败: ", err.Error())
+		}
+	}
+	if o.Notifier != nil {
+		if err := o.Notifier.Close(); err != nil {
+			log.Error(o.Symbol, " 关闭消息发送失败: ", err.Error())
+		}
+	}
+	// 写入最终检查点并释放主节点锁
+	if o.leader != nil {
+		o.leader.Release()
+	}
+	// 3. 注销consul服务
+	if consulx != nil {
+		if err := deregisterTag(consulx, tag); err != nil {
+			log.Error(o.Symbol, " 注销consul服务失败: ", err.Error())
+		}
+	}
+	// 4. 停止扫块并关闭区块数据库
+	if o.BlockScanner != nil {
+		o.BlockScanner.Stop()
+	}
+	// BlockchainLocal以非保持打开方式创建,仅在读写时打开文件,扫块停止后即已释放
+	if closer, ok := o.dai.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Error(o.Symbol, " 关闭区块数据库失败: ", err.Error())
+		}
+	}
+	log.Notice(o.Symbol, " 扫块服务已停止")
+}
+
+func (o *OpenWScanner) outboxDrained() bool {
+	pending, err := o.outbox.Pending()
+	if err != nil {
+		return false
+	}
+	for _, v := range pending {
+		if v > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func waitUntil(deadline time.Time, done func() bool) bool {
+	for !done() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(shutdownPoll)
+	}
+	return true
+}
+
+// 注销标签下的服务,与ClearTagService相同但返回错误而不是panic
+func deregisterTag(consulx *consul.ConsulManager, tag string) error {
+	services, err := consulx.Consulx.Agent().Services()
+	if err != nil {
+		return err
+	}
+	for _, v := range services {
+		for _, t := range v.Tags {
+			if t != tag {
+				continue
+			}
+			if err := consulx.Consulx.Agent().ServiceDeregister(v.ID); err != nil {
+				return err
+			}
+			log2.Info("注销服务成功", 0, log2.String("service", v.ID))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package open_scanner
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/godaddy-x/jorm/amqp"
+	log2 "github.com/godaddy-x/jorm/log"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_base/major"
+	"github.com/nbit99/open_scanner/event"
+	"golang.org/x/crypto/ed25519"
+	"strings"
+)
+
+const (
+	SignVersion    = "v2"
+	SignAlgHS256   = "HS256"
+	SignAlgEd25519 = "Ed25519"
+
+	signConfigNode = "rpc/mqsign"
+	minHS256Secret = 32 // HS256共享密钥最小长度(字节)
+
+	SignVersionHeader = "X-Sign-Version"
+	SignKeyIDHeader   = "X-Sign-Kid"
+	SignatureHeader   = "X-Sign" // protobuf消息的签名,签名内容为消息体的base64url编码
+)
+
+// SignKey 消息签名密钥
+// HS256: Secret为共享密钥; Ed25519: Secret为私钥(hex,签名方配置),PublicKey为公钥(hex,验签方配置)
+type SignKey struct {
+	ID        string `json:"id"`
+	Alg       string `json:"alg"`
+	Secret    string `json:"secret"`
+	PublicKey string `json:"publicKey"`
+	Expire    int64  `json:"expire"` // 验签截止时间(毫秒),0不过期,用于密钥轮换
+}
+
+// SignConfig consul中的签名配置(rpc/mqsign,AES加密)
+type SignConfig struct {
+	Active       string    `json:"active"`       // 签名使用的密钥ID
+	Keys         []SignKey `json:"keys"`         // 验签可用的密钥,轮换期间同时包含新旧密钥
+	LegacyExpire int64     `json:"legacyExpire"` // 接受旧版MD5签名的截止时间(毫秒),0不接受
+}
+
+// Signer 消息签名,签名格式: v2:<kid>:<hex签名>
+// 未配置密钥时使用旧版MD5签名,保证未升级的消费者可以继续验签
+type Signer struct {
+	key    *SignKey
+	secret []byte
+	legacy string
+}
+
+// Verifier 消息验签,接受所有未过期的密钥
+type Verifier struct {
+	keys         map[string]*SignKey
+	legacy       string
+	legacyExpire int64
+}
+
+// LoadSignConfig 读取consul中的签名配置,未配置时返回nil
+func LoadSignConfig() (*SignConfig, error) {
+	dc := major.InitDc()
+	pair, _, err := dc.Consulx.KV().Get(signConfigNode, nil)
+	if err != nil {
+		return nil, util.Error("读取签名配置失败: ", err.Error())
+	}
+	if pair == nil || len(pair.Value) == 0 {
+		return nil, nil
+	}
+	conf := &SignConfig{}
+	if err := major.ReadNodeAesData(dc, signConfigNode, conf); err != nil {
+		return nil, util.Error("读取签名配置失败: ", err.Error())
+	}
+	return conf, nil
+}
+
+// NewSigner 按签名配置创建,conf为nil时使用旧版MD5签名
+func NewSigner(conf *SignConfig, legacySecret string) (*Signer, error) {
+	if conf == nil {
+		return &Signer{legacy: legacySecret}, nil
+	}
+	for i := range conf.Keys {
+		key := &conf.Keys[i]
+		if key.ID != conf.Active {
+			continue
+		}
+		if err := checkSignKey(key); err != nil {
+			return nil, err
+		}
+		s := &Signer{key: key}
+		switch key.Alg {
+		case SignAlgHS256:
+			s.secret = []byte(key.Secret)
+		case SignAlgEd25519:
+			b, err := hex.DecodeString(key.Secret)
+			if err != nil || len(b) != ed25519.PrivateKeySize {
+				return nil, util.Error("签名密钥[", key.ID, "]私钥无效")
+			}
+			s.secret = b
+		}
+		return s, nil
+	}
+	return nil, util.Error("签名密钥[", conf.Active, "]未找到")
+}
+
+// 未指定签名实现时读取consul配置
+func (o *OpenWScanner) initSigner() error {
+	if o.Signer != nil {
+		return nil
+	}
+	conf, err := o.provider().SignConfig()
+	if err != nil {
+		return err
+	}
+	if conf == nil {
+		log2.Warn("签名密钥未配置,使用旧版MD5签名", 0, log2.String("symbol", o.Symbol))
+	}
+	network, err := o.provider().Network()
+	if err != nil {
+		return err
+	}
+	signer, err := NewSigner(conf, network.MQSecretKey)
+	if err != nil {
+		return err
+	}
+	o.Signer = signer
+	return nil
+}
+
+func checkSignKey(key *SignKey) error {
+	if len(key.ID) == 0 || strings.Contains(key.ID, ":") {
+		return util.Error("签名密钥ID[", key.ID, "]无效")
+	}
+	if key.Alg != SignAlgHS256 && key.Alg != SignAlgEd25519 {
+		return util.Error("签名密钥[", key.ID, "]算法[", key.Alg, "]不支持")
+	}
+	if key.Alg == SignAlgHS256 && len(key.Secret) < minHS256Secret {
+		return util.Error("签名密钥[", key.ID, "]共享密钥长度不能小于", minHS256Secret)
+	}
+	return nil
+}
+
+// KeyID 当前签名密钥ID,旧版签名返回空
+func (s *Signer) KeyID() string {
+	if s == nil || s.key == nil {
+		return ""
+	}
+	return s.key.ID
+}
+
+// Sign 数据转换JSON并base64编码后签名,返回编码内容及签名
+func (s *Signer) Sign(result interface{}) (string, string, error) {
+	if s == nil {
+		return "", "", util.Error("消息签名未初始化")
+	}
+	str, err := util.ObjectToJson(result)
+	if err != nil || len(str) == 0 {
+		return "", "", util.Error("区块/交易单数据转换JSON失败")
+	}
+	ret := util.Base64URLEncode(str)
+	if len(ret) == 0 {
+		return "", "", util.Error("区块/交易单数据base64编码失败")
+	}
+	return ret, s.sign(ret), nil
+}
+
+// SignBytes protobuf编码的数据base64编码后签名,签名方式与Sign相同
+func (s *Signer) SignBytes(b []byte) (string, string, error) {
+	if s == nil {
+		return "", "", util.Error("消息签名未初始化")
+	}
+	if len(b) == 0 {
+		return "", "", util.Error("区块/交易单数据为空")
+	}
+	ret := base64.URLEncoding.EncodeToString(b)
+	return ret, s.sign(ret), nil
+}
+
+func (s *Signer) sign(content string) string {
+	if s.key == nil {
+		return util.MD5(content, s.legacy)
+	}
+	var sig []byte
+	switch s.key.Alg {
+	case SignAlgEd25519:
+		sig = ed25519.Sign(ed25519.PrivateKey(s.secret), []byte(content))
+	default:
+		sig = hmacSHA256(s.secret, content)
+	}
+	return util.AddStr(SignVersion, ":", s.key.ID, ":", hex.EncodeToString(sig))
+}
+
+// NewVerifier 按签名配置创建验签,legacySecret为旧版MD5签名密钥
+func NewVerifier(conf *SignConfig, legacySecret string) (*Verifier, error) {
+	v := &Verifier{keys: make(map[string]*SignKey), legacy: legacySecret}
+	if conf == nil {
+		// 未配置时只接受旧版签名
+		v.legacyExpire = -1
+		return v, nil
+	}
+	v.legacyExpire = conf.LegacyExpire
+	for i := range conf.Keys {
+		key := conf.Keys[i]
+		if err := checkSignKey(&key); err != nil {
+			return nil, err
+		}
+		if key.Alg == SignAlgEd25519 {
+			b, err := hex.DecodeString(key.PublicKey)
+			if err != nil || len(b) != ed25519.PublicKeySize {
+				return nil, util.Error("签名密钥[", key.ID, "]公钥无效")
+			}
+		}
+		v.keys[key.ID] = &key
+	}
+	return v, nil
+}
+
+// Verify 校验签名,密钥ID不存在或已过期时失败
+func (v *Verifier) Verify(content, signature string) error {
+	if len(signature) == 0 {
+		return util.Error("消息内容无签名")
+	}
+	parts := strings.SplitN(signature, ":", 3)
+	if len(parts) != 3 || parts[0] != SignVersion {
+		if v.legacyExpire == 0 || (v.legacyExpire > 0 && util.Time() > v.legacyExpire) {
+			return util.Error("旧版签名已停止使用")
+		}
+		if signature != util.MD5(content, v.legacy) {
+			return util.Error("消息内容签名校验失败")
+		}
+		return nil
+	}
+	key, ok := v.keys[parts[1]]
+	if !ok {
+		return util.Error("签名密钥[", parts[1], "]不存在")
+	}
+	if key.Expire > 0 && util.Time() > key.Expire {
+		return util.Error("签名密钥[", key.ID, "]已过期")
+	}
+	sig, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return util.Error("消息签名格式错误")
+	}
+	switch key.Alg {
+	case SignAlgEd25519:
+		pub, _ := hex.DecodeString(key.PublicKey)
+		if !ed25519.Verify(ed25519.PublicKey(pub), []byte(content), sig) {
+			return util.Error("消息内容签名校验失败")
+		}
+	default:
+		if !hmac.Equal(sig, hmacSHA256([]byte(key.Secret), content)) {
+			return util.Error("消息内容签名校验失败")
+		}
+	}
+	return nil
+}
+
+// Decode 校验MQ消息签名并解析内容,替代major.CheckMQDataSig
+func (v *Verifier) Decode(message rabbitmq.MsgData, data interface{}) error {
+	content, ok := message.Content.(string)
+	if !ok {
+		return util.Error("消息内容非string类型")
+	}
+	if err := v.Verify(content, message.Signature); err != nil {
+		return err
+	}
+	str := util.Base64URLDecode(content)
+	if len(str) == 0 {
+		return util.Error("消息内容为空")
+	}
+	if err := util.JsonToObject(str, data); err != nil {
+		return util.Error("消息内容JSON转换失败: ", err)
+	}
+	return nil
+}
+
+// DecodeEvent 校验消息签名并按内容类型解析消息,返回*event.Block/*event.Tx/*event.Receipt/*event.TxBatch
+func (v *Verifier) DecodeEvent(message rabbitmq.MsgData) (interface{}, error) {
+	content, ok := message.Content.(string)
+	if !ok {
+		return nil, util.Error("消息内容非string类型")
+	}
+	if err := v.Verify(content, message.Signature); err != nil {
+		return nil, err
+	}
+	if message.Kind == ContentTypeProtobuf {
+		return event.DecodeProto(message.Type, content)
+	}
+	return event.Decode(message.Type, content)
+}
+
+// VerifyBytes 校验protobuf消息体签名,signature取自SignatureHeader
+func (v *Verifier) VerifyBytes(body []byte, signature string) error {
+	return v.Verify(base64.URLEncoding.EncodeToString(body), signature)
+}
+
+// SignHeaders 从签名中解析版本及密钥ID,用于MQ/HTTP消息头
+func SignHeaders(signature string) map[string]string {
+	parts := strings.SplitN(signature, ":", 3)
+	if len(parts) != 3 || parts[0] != SignVersion {
+		return map[string]string{SignVersionHeader: "v1"}
+	}
+	return map[string]string{SignVersionHeader: parts[0], SignKeyIDHeader: parts[1]}
+}
+
+func hmacSHA256(key []byte, content string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(content))
+	return mac.Sum(nil)
+}
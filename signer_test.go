@@ -0,0 +1,177 @@
+package open_scanner
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/godaddy-x/jorm/amqp"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_scanner/event"
+	"github.com/nbit99/openwallet/v2/openwallet"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	testSecret1 = "0123456789abcdef0123456789abcdef"
+	testSecret2 = "fedcba9876543210fedcba9876543210"
+)
+
+func mustSigner(t *testing.T, conf *SignConfig, legacy string) *Signer {
+	s, err := NewSigner(conf, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func mustVerifier(t *testing.T, conf *SignConfig, legacy string) *Verifier {
+	v, err := NewVerifier(conf, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestSignerRoundTrip(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []SignKey{
+		{ID: "k1", Alg: SignAlgHS256, Secret: testSecret1},
+		{ID: "k2", Alg: SignAlgEd25519, Secret: hex.EncodeToString(priv), PublicKey: hex.EncodeToString(pub)},
+	}
+	// 验签方只配置Ed25519公钥
+	verifier := mustVerifier(t, &SignConfig{Keys: []SignKey{keys[0], {ID: "k2", Alg: SignAlgEd25519, PublicKey: hex.EncodeToString(pub)}}}, "")
+	for _, active := range []string{"k1", "k2"} {
+		signer := mustSigner(t, &SignConfig{Active: active, Keys: keys}, "")
+		if signer.KeyID() != active {
+			t.Fatalf("expected key %s, got %s", active, signer.KeyID())
+		}
+		block := event.NewBlock(&openwallet.BlockHeader{Hash: "h100", Height: 100})
+		content, sig, err := signer.Sign(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(sig, SignVersion+":"+active+":") {
+			t.Fatalf("unexpected signature %s", sig)
+		}
+		v, err := verifier.DecodeEvent(rabbitmq.MsgData{Type: EventBlock, Content: content, Signature: sig})
+		if err != nil {
+			t.Fatalf("%s: %v", active, err)
+		}
+		if v.(*event.Block).Hash != "h100" {
+			t.Fatalf("%s: unexpected block %+v", active, v)
+		}
+		// 内容被修改
+		if err := verifier.Verify(content+"x", sig); err == nil {
+			t.Fatalf("%s: expected error for modified content", active)
+		}
+		body := []byte{1, 2, 3}
+		content, sig, err = signer.SignBytes(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := verifier.VerifyBytes(body, sig); err != nil {
+			t.Fatalf("%s: %v", active, err)
+		}
+		if err := verifier.VerifyBytes([]byte{1, 2}, sig); err == nil {
+			t.Fatalf("%s: expected error for modified body", active)
+		}
+	}
+	// 共享密钥不一致
+	other := mustVerifier(t, &SignConfig{Keys: []SignKey{{ID: "k1", Alg: SignAlgHS256, Secret: testSecret2}}}, "")
+	content, sig, _ := mustSigner(t, &SignConfig{Active: "k1", Keys: keys}, "").Sign("data")
+	if err := other.Verify(content, sig); err == nil {
+		t.Fatal("expected error for different secret")
+	}
+	if _, _, err := (*Signer)(nil).Sign("data"); err == nil {
+		t.Fatal("expected error for nil signer")
+	}
+}
+
+func TestSignerRotation(t *testing.T) {
+	old := SignKey{ID: "k1", Alg: SignAlgHS256, Secret: testSecret1}
+	next := SignKey{ID: "k2", Alg: SignAlgHS256, Secret: testSecret2}
+	oldContent, oldSig, _ := mustSigner(t, &SignConfig{Active: "k1", Keys: []SignKey{old}}, "").Sign("data")
+	newContent, newSig, _ := mustSigner(t, &SignConfig{Active: "k2", Keys: []SignKey{old, next}}, "").Sign("data")
+
+	// 轮换期间新旧密钥均可验签
+	old.Expire = util.Time() + 60000
+	verifier := mustVerifier(t, &SignConfig{Keys: []SignKey{old, next}}, "")
+	if err := verifier.Verify(oldContent, oldSig); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.Verify(newContent, newSig); err != nil {
+		t.Fatal(err)
+	}
+	// 旧密钥过期后拒绝
+	old.Expire = util.Time() - 1
+	verifier = mustVerifier(t, &SignConfig{Keys: []SignKey{old, next}}, "")
+	if err := verifier.Verify(oldContent, oldSig); err == nil {
+		t.Fatal("expected error for expired key")
+	}
+	if err := verifier.Verify(newContent, newSig); err != nil {
+		t.Fatal(err)
+	}
+	// 已移除的密钥
+	verifier = mustVerifier(t, &SignConfig{Keys: []SignKey{next}}, "")
+	if err := verifier.Verify(oldContent, oldSig); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+	if _, err := NewSigner(&SignConfig{Active: "k3", Keys: []SignKey{old, next}}, ""); err == nil {
+		t.Fatal("expected error for missing active key")
+	}
+}
+
+func TestSignerLegacyWindow(t *testing.T) {
+	content, sig, err := mustSigner(t, nil, "legacy").Sign("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未配置签名时只接受旧版签名
+	if err := mustVerifier(t, nil, "legacy").Verify(content, sig); err != nil {
+		t.Fatal(err)
+	}
+	if err := mustVerifier(t, nil, "other").Verify(content, sig); err == nil {
+		t.Fatal("expected error for different legacy secret")
+	}
+	keys := []SignKey{{ID: "k1", Alg: SignAlgHS256, Secret: testSecret1}}
+	for _, v := range []struct {
+		expire int64
+		ok     bool
+	}{{util.Time() + 60000, true}, {util.Time() - 1, false}, {0, false}} {
+		verifier := mustVerifier(t, &SignConfig{Keys: keys, LegacyExpire: v.expire}, "legacy")
+		if err := verifier.Verify(content, sig); (err == nil) != v.ok {
+			t.Fatalf("legacyExpire %d: unexpected result %v", v.expire, err)
+		}
+	}
+	if err := mustVerifier(t, nil, "legacy").Verify(content, ""); err == nil {
+		t.Fatal("expected error for empty signature")
+	}
+}
+
+func TestCheckSignKey(t *testing.T) {
+	for _, key := range []SignKey{
+		{ID: "", Alg: SignAlgHS256, Secret: testSecret1},
+		{ID: "k:1", Alg: SignAlgHS256, Secret: testSecret1},
+		{ID: "k1", Alg: "RS256", Secret: testSecret1},
+		{ID: "k1", Alg: SignAlgHS256},
+		{ID: "k1", Alg: SignAlgHS256, Secret: "short"},
+	} {
+		if err := checkSignKey(&key); err == nil {
+			t.Fatalf("expected error for key %+v", key)
+		}
+		if _, err := NewSigner(&SignConfig{Active: key.ID, Keys: []SignKey{key}}, ""); err == nil {
+			t.Fatalf("expected signer error for key %+v", key)
+		}
+		if _, err := NewVerifier(&SignConfig{Keys: []SignKey{key}}, ""); err == nil {
+			t.Fatalf("expected verifier error for key %+v", key)
+		}
+	}
+	if _, err := NewSigner(&SignConfig{Active: "k1", Keys: []SignKey{{ID: "k1", Alg: SignAlgEd25519, Secret: "00"}}}, ""); err == nil {
+		t.Fatal("expected error for invalid ed25519 private key")
+	}
+}
@@ -0,0 +1,310 @@
+package open_scanner
+
+import (
+	"encoding/base64"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	"github.com/godaddy-x/jorm/util"
+	"github.com/nbit99/open_scanner/event"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventBlock     = event.TypeBlock     // 区块
+	EventTx        = event.TypeTx        // 交易单
+	EventReceipt   = event.TypeReceipt   // 智能合约交易回执
+	EventRollback  = event.TypeRollback  // 区块回滚
+	EventConfirmed = event.TypeConfirmed // 交易单达到确认数
+	EventAlert     = event.TypeAlert     // 扫块延迟告警
+	EventRecovery  = event.TypeRecovery  // 扫块延迟恢复
+	EventTxBatch   = event.TypeTxBatch   // 交易单批量消息
+
+	SinkMQ      = "mq"
+	SinkWebhook = "webhook"
+	SinkKafka   = "kafka"
+	SinkFile    = "file"
+	SinkAlert   = "alert" // alertWebhook对应的发送通道名称
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+
+	EventTypeHeader = "X-Scanner-Event"
+)
+
+var eventNames = map[string]int64{
+	"block":     EventBlock,
+	"tx":        EventTx,
+	"receipt":   EventReceipt,
+	"rollback":  EventRollback,
+	"confirmed": EventConfirmed,
+	"alert":     EventAlert,
+	"recovery":  EventRecovery,
+	"txbatch":   EventTxBatch,
+}
+
+var contentTypes = map[string]string{
+	"json":              ContentTypeJSON,
+	ContentTypeJSON:     ContentTypeJSON,
+	"protobuf":          ContentTypeProtobuf,
+	ContentTypeProtobuf: ContentTypeProtobuf,
+}
+
+// alert通道未单独配置的项读取alertWebhook/alertSecret,默认只订阅告警/恢复消息
+var alertSinkDefaults = map[string]func(c config.Configer) string{
+	"type":   func(config.Configer) string { return SinkWebhook },
+	"events": func(config.Configer) string { return "alert,recovery" },
+	"url":    func(c config.Configer) string { return c.String("alertWebhook") },
+	"secret": func(c config.Configer) string { return c.String("alertSecret") },
+}
+
+// 支持protobuf编码的事件类型,其余事件始终为JSON
+var protoEvents = map[int64]bool{
+	EventBlock:   true,
+	EventTx:      true,
+	EventReceipt: true,
+	EventTxBatch: true,
+}
+
+// Sink 扫块事件发送通道,返回nil表示对端已接收
+type Sink interface {
+	Name() string
+	Send(data rabbitmq.MsgData) error
+}
+
+// SinkFactory 按币种配置创建发送通道,name为ini中的节点名称
+type SinkFactory func(name string, o *OpenWScanner, c config.Configer) (Sink, error)
+
+// RetryPolicy 发送失败重试策略
+type RetryPolicy struct {
+	MaxTries   int64 // 最大发送次数,超过后移入死信节点,0不限制
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// SinkPayload 非MQ通道发送的事件内容
+type SinkPayload struct {
+	Symbol    string      `json:"symbol"`
+	Type      int64       `json:"type"`
+	Content   interface{} `json:"content"`
+	Signature string      `json:"signature"`
+	// 内容编码类型,protobuf时content为protobuf数据的base64url编码
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// SinkRoute 发送通道及其重试策略/订阅的事件类型/内容编码
+type SinkRoute struct {
+	Sink        Sink
+	Policy      RetryPolicy
+	Events      map[int64]bool // 为空时订阅全部事件
+	ContentType string         // ContentTypeJSON/ContentTypeProtobuf
+	signer      *Signer
+}
+
+func (r *SinkRoute) accept(data rabbitmq.MsgData) bool {
+	return len(r.Events) == 0 || r.Events[data.Type]
+}
+
+// 按通道内容类型编码后发送,用于未写入发件箱的消息;发件箱中的消息写入时已编码
+func (r *SinkRoute) send(data rabbitmq.MsgData) error {
+	data, err := r.encode(data)
+	if err != nil {
+		return err
+	}
+	return r.Sink.Send(data)
+}
+
+// protobuf通道将JSON内容转换为protobuf并重新签名,Kind标记内容类型,已编码的消息不重复编码
+func (r *SinkRoute) encode(data rabbitmq.MsgData) (rabbitmq.MsgData, error) {
+	if r.ContentType != ContentTypeProtobuf || !protoEvents[data.Type] || data.Kind == ContentTypeProtobuf {
+		return data, nil
+	}
+	content, ok := data.Content.(string)
+	if !ok {
+		return data, util.Error("消息内容非string类型")
+	}
+	v, err := event.Decode(data.Type, content)
+	if err != nil {
+		return data, err
+	}
+	b, err := event.MarshalProto(v)
+	if err != nil {
+		return data, err
+	}
+	ret, sig, err := r.signer.SignBytes(b)
+	if err != nil {
+		return data, err
+	}
+	data.Content, data.Signature, data.Kind = ret, sig, ContentTypeProtobuf
+	return data, nil
+}
+
+var (
+	sinkMu        sync.RWMutex
+	sinkFactories = map[string]SinkFactory{}
+)
+
+func init() {
+	RegisterSink(SinkMQ, newMQSink)
+	RegisterSink(SinkWebhook, newWebhookSink)
+	RegisterSink(SinkKafka, newKafkaSink)
+	RegisterSink(SinkFile, newFileSink)
+}
+
+// RegisterSink 注册发送通道类型
+func RegisterSink(kind string, factory SinkFactory) {
+	sinkMu.Lock()
+	sinkFactories[strings.ToLower(kind)] = factory
+	sinkMu.Unlock()
+}
+
+// NewSinkRoutes 按币种配置创建发送通道,未配置时只发送MQ
+// 配置alertWebhook时添加名称为alert的webhook通道,[alert]中可配置重试策略等
+// sinks = mq,hook1
+// [hook1]
+// type = webhook (默认与节点名称相同)
+// events = block,tx,receipt
+// maxTries = 0
+// minBackoff = 1
+// maxBackoff = 60
+// contentType = application/json|application/x-protobuf (默认json,protobuf只作用于区块/交易单/合约回执/交易单批量消息)
+func NewSinkRoutes(o *OpenWScanner, c config.Configer) ([]*SinkRoute, error) {
+	names, err := sinkNames(c)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]*SinkRoute, 0, len(names))
+	for _, name := range names {
+		route, factory, err := sinkRoute(c, name)
+		if err != nil {
+			return nil, err
+		}
+		sink, err := factory(name, o, c)
+		if err != nil {
+			return nil, util.Error("发送通道[", name, "]创建失败: ", err.Error())
+		}
+		route.Sink, route.signer = sink, o.Signer
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// 配置的发送通道名称(小写),配置alertWebhook时追加alert
+func sinkNames(c config.Configer) ([]string, error) {
+	names := splitConfig(c.DefaultString("sinks", SinkMQ))
+	if len(c.String("alertWebhook")) > 0 && !containsFold(names, SinkAlert) {
+		names = append(names, SinkAlert)
+	}
+	exist := make(map[string]bool)
+	for i, name := range names {
+		name = strings.ToLower(name)
+		if exist[name] {
+			return nil, util.Error("发送通道[", name, "]重复配置")
+		}
+		exist[name] = true
+		names[i] = name
+	}
+	return names, nil
+}
+
+// 读取发送通道类型/订阅事件/内容类型/重试策略,不创建通道
+func sinkRoute(c config.Configer, name string) (*SinkRoute, SinkFactory, error) {
+	kind := strings.ToLower(sinkString(c, name, "type", name))
+	sinkMu.RLock()
+	factory, ok := sinkFactories[kind]
+	sinkMu.RUnlock()
+	if !ok {
+		return nil, nil, util.Error("发送通道[", name, "]类型[", kind, "]不支持")
+	}
+	events := make(map[int64]bool)
+	for _, v := range splitConfig(sinkString(c, name, "events", "")) {
+		t, ok := eventNames[strings.ToLower(v)]
+		if !ok {
+			return nil, nil, util.Error("发送通道[", name, "]事件类型[", v, "]不支持")
+		}
+		events[t] = true
+	}
+	value := sinkString(c, name, "contentType", ContentTypeJSON)
+	contentType, ok := contentTypes[strings.ToLower(value)]
+	if !ok {
+		return nil, nil, util.Error("发送通道[", name, "]内容类型[", value, "]不支持")
+	}
+	policy := make(map[string]int64)
+	for key, def := range map[string]int64{"maxTries": 0, "minBackoff": 1, "maxBackoff": 60} {
+		v, err := configInt(c, name+"::"+key, def, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		policy[key] = v
+	}
+	return &SinkRoute{
+		Events:      events,
+		ContentType: contentType,
+		Policy: RetryPolicy{
+			MaxTries:   policy["maxTries"],
+			MinBackoff: time.Duration(policy["minBackoff"]) * time.Second,
+			MaxBackoff: time.Duration(policy["maxBackoff"]) * time.Second,
+		},
+	}, factory, nil
+}
+
+func sinkString(c config.Configer, name, key, def string) string {
+	if v, ok := alertSinkDefaults[key]; ok && name == SinkAlert {
+		def = v(c)
+	}
+	return c.DefaultString(name+"::"+key, def)
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitConfig(value string) []string {
+	result := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func sinkPayload(symbol string, data rabbitmq.MsgData) SinkPayload {
+	return SinkPayload{Symbol: symbol, Type: data.Type, Content: data.Content, Signature: data.Signature, ContentType: data.Kind}
+}
+
+// protobuf消息内容解码为二进制数据
+func protoContent(data rabbitmq.MsgData) ([]byte, error) {
+	content, ok := data.Content.(string)
+	if !ok {
+		return nil, util.Error("消息内容非string类型")
+	}
+	return base64.URLEncoding.DecodeString(content)
+}
+
+// MQSink 通过Notifier发送到tx.exchange
+type MQSink struct {
+	name     string
+	notifier Notifier
+}
+
+func newMQSink(name string, o *OpenWScanner, c config.Configer) (Sink, error) {
+	if o.Notifier == nil {
+		return nil, util.Error("消息发送未初始化")
+	}
+	return &MQSink{name: name, notifier: o.Notifier}, nil
+}
+
+func (s *MQSink) Name() string {
+	return s.name
+}
+
+func (s *MQSink) Send(data rabbitmq.MsgData) error {
+	return s.notifier.Publish(data)
+}
@@ -0,0 +1,53 @@
+package open_scanner
+
+import (
+	"encoding/json"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	"github.com/nbit99/openwallet/v2/common/file"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileSink 以JSONL格式追加写入本地文件,用于事件回放
+// [file]
+// path = /data/btc_events.jsonl (默认DbPath/<symbol>_events.jsonl)
+type FileSink struct {
+	mu     sync.Mutex
+	name   string
+	symbol string
+	path   string
+}
+
+func newFileSink(name string, o *OpenWScanner, c config.Configer) (Sink, error) {
+	path := sinkString(c, name, "path", "")
+	if len(path) == 0 {
+		path = filepath.Join(o.DbPath, o.Symbol+"_events.jsonl")
+	}
+	file.MkdirAll(filepath.Dir(path))
+	return &FileSink{name: name, symbol: o.Symbol, path: path}, nil
+}
+
+func (s *FileSink) Name() string {
+	return s.name
+}
+
+// Send 每条事件一行,写入后同步到磁盘
+func (s *FileSink) Send(data rabbitmq.MsgData) error {
+	line, err := json.Marshal(sinkPayload(s.symbol, data))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
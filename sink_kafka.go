@@ -0,0 +1,72 @@
+package open_scanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	"github.com/godaddy-x/jorm/util"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+)
+
+// KafkaSink 通过Kafka REST Proxy(v2)写入主题,消息key为币种
+// [kafka]
+// url = http://127.0.0.1:8082
+// topic = scanner.btc
+// timeout = 10
+type KafkaSink struct {
+	name   string
+	symbol string
+	url    string
+	client *http.Client
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string      `json:"key"`
+	Value SinkPayload `json:"value"`
+}
+
+func newKafkaSink(name string, o *OpenWScanner, c config.Configer) (Sink, error) {
+	url := sinkString(c, name, "url", "")
+	if len(url) == 0 {
+		return nil, util.Error("kafka rest proxy地址未配置")
+	}
+	topic := sinkString(c, name, "topic", "")
+	if len(topic) == 0 {
+		topic = "scanner." + strings.ToLower(o.Symbol)
+	}
+	timeout := c.DefaultInt64(name+"::timeout", 10)
+	return &KafkaSink{
+		name:   name,
+		symbol: o.Symbol,
+		url:    util.AddStr(strings.TrimRight(url, "/"), "/topics/", topic),
+		client: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}, nil
+}
+
+func (s *KafkaSink) Name() string {
+	return s.name
+}
+
+func (s *KafkaSink) Send(data rabbitmq.MsgData) error {
+	body, err := json.Marshal(kafkaRecords{Records: []kafkaRecord{{Key: s.symbol, Value: sinkPayload(s.symbol, data)}}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	return doSinkRequest(s.client, req)
+}
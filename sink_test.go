@@ -0,0 +1,66 @@
+package open_scanner
+
+import (
+	"testing"
+
+	"github.com/astaxie/beego/config"
+)
+
+func newTestConfig(t *testing.T, ini string) config.Configer {
+	c, err := config.NewConfigData("ini", []byte(ini))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNewSinkRoutesAlertWebhook(t *testing.T) {
+	c := newTestConfig(t, `
+sinks = hook
+alertWebhook = http://127.0.0.1/alert
+alertSecret = s1
+[hook]
+type = webhook
+url = http://127.0.0.1/hook
+events = block,tx
+[alert]
+maxTries = 5
+`)
+	routes, err := NewSinkRoutes(&OpenWScanner{Symbol: "BTC"}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	alert := routes[1]
+	sink, ok := alert.Sink.(*WebhookSink)
+	if !ok || sink.Name() != SinkAlert || sink.url != "http://127.0.0.1/alert" || string(sink.secret) != "s1" {
+		t.Fatalf("unexpected alert sink: %+v", alert.Sink)
+	}
+	if len(alert.Events) != 2 || !alert.Events[EventAlert] || !alert.Events[EventRecovery] {
+		t.Fatalf("unexpected alert events: %v", alert.Events)
+	}
+	if alert.Policy.MaxTries != 5 {
+		t.Fatalf("unexpected alert policy: %+v", alert.Policy)
+	}
+	if hook := routes[0]; hook.Events[EventAlert] || !hook.Events[EventTx] {
+		t.Fatalf("unexpected hook events: %v", hook.Events)
+	}
+}
+
+func TestNewSinkRoutesWithoutAlertWebhook(t *testing.T) {
+	c := newTestConfig(t, `
+sinks = hook
+[hook]
+type = webhook
+url = http://127.0.0.1/hook
+`)
+	routes, err := NewSinkRoutes(&OpenWScanner{Symbol: "BTC"}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || len(routes[0].Events) != 0 {
+		t.Fatalf("unexpected routes: %+v", routes)
+	}
+}
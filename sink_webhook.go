@@ -0,0 +1,106 @@
+package open_scanner
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/astaxie/beego/config"
+	"github.com/godaddy-x/jorm/amqp"
+	"github.com/godaddy-x/jorm/util"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookSignatureHeader = "X-Scanner-Signature"
+	webhookSymbolHeader    = "X-Scanner-Symbol"
+)
+
+// WebhookSink 以HTTP POST发送事件,配置secret时附带HMAC-SHA256签名
+// [hook1]
+// type = webhook
+// url = https://example.com/notify
+// secret = xxx
+// timeout = 10
+// contentType = application/x-protobuf 时区块/交易单/合约回执/交易单批量消息以protobuf二进制作为请求体,消息签名在X-Sign中
+type WebhookSink struct {
+	name   string
+	symbol string
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func newWebhookSink(name string, o *OpenWScanner, c config.Configer) (Sink, error) {
+	url := sinkString(c, name, "url", "")
+	if len(url) == 0 {
+		return nil, util.Error("webhook地址未配置")
+	}
+	timeout := c.DefaultInt64(name+"::timeout", 10)
+	return &WebhookSink{
+		name:   name,
+		symbol: o.Symbol,
+		url:    url,
+		secret: []byte(sinkString(c, name, "secret", "")),
+		client: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}, nil
+}
+
+func (s *WebhookSink) Name() string {
+	return s.name
+}
+
+func (s *WebhookSink) Send(data rabbitmq.MsgData) error {
+	body, err := sinkBody(s.symbol, data)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if data.Kind == ContentTypeProtobuf {
+		req.Header.Set("Content-Type", ContentTypeProtobuf)
+		req.Header.Set(SignatureHeader, data.Signature)
+	} else {
+		req.Header.Set("Content-Type", ContentTypeJSON)
+	}
+	req.Header.Set(webhookSymbolHeader, s.symbol)
+	req.Header.Set(EventTypeHeader, util.AnyToStr(data.Type))
+	for k, v := range SignHeaders(data.Signature) {
+		req.Header.Set(k, v)
+	}
+	if len(s.secret) > 0 {
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	return doSinkRequest(s.client, req)
+}
+
+// protobuf消息的请求体为解码后的二进制数据,其余为SinkPayload JSON
+func sinkBody(symbol string, data rabbitmq.MsgData) ([]byte, error) {
+	if data.Kind == ContentTypeProtobuf {
+		return protoContent(data)
+	}
+	return json.Marshal(sinkPayload(symbol, data))
+}
+
+// 发送HTTP请求,非2xx状态视为失败
+func doSinkRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return util.Error("[", req.URL.String(), "]响应状态[", resp.StatusCode, "]: ", string(msg))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
@@ -1,18 +1,158 @@
 package tradeutil
 
 import (
+	redigo "github.com/garyburd/redigo/redis"
 	"github.com/godaddy-x/jorm/cache/redis"
 	"github.com/godaddy-x/jorm/exception"
+	"github.com/godaddy-x/jorm/log"
 	"github.com/godaddy-x/jorm/sqlc"
 	"github.com/godaddy-x/jorm/sqld"
 	"github.com/godaddy-x/jorm/util"
 	"github.com/nbit99/open_base/model"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	cache_expire = 3600
 )
 
+// 缓存失效通知的数据类型
+const (
+	CacheApp      = "app"
+	CacheSymbol   = "coin"
+	CacheContract = "contract"
+	CacheAccount  = "account"
+	// 新增/删除地址或账户别名,id为AddressKey(symbol, address)
+	CacheAddress = "address"
+	// 订阅中断期间可能丢失通知,重新订阅后通知清空全部缓存
+	CacheAll = "*"
+)
+
+const (
+	invalidateChannel    = "cache.tx.invalidate"
+	invalidateMaxBackoff = time.Minute
+)
+
+// InvalidateHook 缓存失效回调,kind为数据类型,id为appid/coin/contractID/accountID/AddressKey
+type InvalidateHook func(kind, id string)
+
+var (
+	hookMu          sync.RWMutex
+	invalidateHooks []InvalidateHook
+	subscribeOnce   sync.Once
+	// 进程标识,忽略本进程发布的通知
+	instanceID = util.GetUUID()
+)
+
+// OnInvalidate 注册缓存失效回调,Set*/Del*更新Redis缓存后通知,用于同步清理进程内缓存
+func OnInvalidate(hook InvalidateHook) {
+	hookMu.Lock()
+	invalidateHooks = append(invalidateHooks, hook)
+	hookMu.Unlock()
+}
+
+// Invalidate 通知缓存失效,执行本进程的回调并通过Redis发布到其他进程
+// 数据在其他服务更新时由调用方主动通知,Redis未初始化时只通知本进程
+func Invalidate(kind, id string) {
+	notifyInvalidate(kind, id)
+	client, err := new(cache.RedisManager).Client()
+	if err != nil {
+		return
+	}
+	conn := client.Pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PUBLISH", invalidateChannel, util.AddStr(instanceID, "|", kind, "|", id)); err != nil {
+		log.Error("发布缓存失效通知失败", 0, log.String("kind", kind), log.String("id", id), log.AddError(err))
+	}
+}
+
+func notifyInvalidate(kind, id string) {
+	hookMu.RLock()
+	hooks := invalidateHooks
+	hookMu.RUnlock()
+	for _, hook := range hooks {
+		hook(kind, id)
+	}
+}
+
+// SubscribeInvalidate 订阅其他进程发布的缓存失效通知并执行回调,进程内只订阅一次
+// 连接断开后按退避时间重新订阅,重新订阅成功后通知CacheAll清空缓存
+func SubscribeInvalidate() {
+	subscribeOnce.Do(func() {
+		go func() {
+			backoff := time.Second
+			for subscribed := false; ; {
+				err := subscribeInvalidate(subscribed, func() {
+					subscribed, backoff = true, time.Second
+				})
+				log.Warn("缓存失效通知订阅中断,稍后重新订阅", 0, log.String("channel", invalidateChannel), log.AddError(err))
+				time.Sleep(backoff)
+				if backoff *= 2; backoff > invalidateMaxBackoff {
+					backoff = invalidateMaxBackoff
+				}
+			}
+		}()
+	})
+}
+
+// 订阅直到连接出错,resubscribe为true时订阅成功后清空缓存
+func subscribeInvalidate(resubscribe bool, ready func()) error {
+	client, err := new(cache.RedisManager).Client()
+	if err != nil {
+		return err
+	}
+	conn := redigo.PubSubConn{Conn: client.Pool.Get()}
+	defer conn.Close()
+	if err := conn.Subscribe(invalidateChannel); err != nil {
+		return err
+	}
+	for {
+		switch v := conn.Receive().(type) {
+		case redigo.Subscription:
+			ready()
+			if resubscribe {
+				notifyInvalidate(CacheAll, "")
+			}
+		case redigo.Message:
+			if kind, id, ok := parseInvalidate(string(v.Data)); ok {
+				notifyInvalidate(kind, id)
+			}
+		case error:
+			return v
+		}
+	}
+}
+
+// AddressKey 地址变更通知的id: 币种:地址
+func AddressKey(symbol, address string) string {
+	return util.AddStr(symbol, ":", address)
+}
+
+// SplitAddressKey 解析地址变更通知的id
+func SplitAddressKey(id string) (string, string, bool) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// NotifyAddress 导入/删除地址或设置账户别名后调用,通知扫块服务更新本地地址索引
+func NotifyAddress(symbol, address string) {
+	Invalidate(CacheAddress, AddressKey(symbol, address))
+}
+
+// 解析其他进程发布的通知: 进程标识|kind|id
+func parseInvalidate(msg string) (string, string, bool) {
+	parts := strings.SplitN(msg, "|", 3)
+	if len(parts) != 3 || parts[0] == instanceID {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
 func GetObj(key string, value interface{}, expire int, call func(mongo *sqld.MGOManager) (interface{}, error)) error {
 	client, err := new(cache.RedisManager).Client()
 	if err != nil {
@@ -80,6 +220,7 @@ func GetApp(appid string) (*model.OwApp, error) {
 }
 
 func SetApp(appid string) error {
+	defer Invalidate(CacheApp, appid)
 	mongo, err := new(sqld.MGOManager).Get()
 	if err != nil {
 		return err
@@ -112,6 +253,7 @@ func GetSymbol(coin string) (*model.OwSymbol, error) {
 }
 
 func SetSymbol(coin string) error {
+	defer Invalidate(CacheSymbol, coin)
 	mongo, err := new(sqld.MGOManager).Get()
 	if err != nil {
 		return err
@@ -149,6 +291,7 @@ func GetContract(contractID string, dst interface{}) (*model.OwContract, error)
 }
 
 func SetContract(contractID string) error {
+	defer Invalidate(CacheContract, contractID)
 	mongo, err := new(sqld.MGOManager).Get()
 	if err != nil {
 		return err
@@ -180,6 +323,16 @@ func GetAccount(walletID, accountID string) (*model.OwAccount, error) {
 	return &account, nil
 }
 
+// DelAccount 删除账号缓存,账号变更后调用
+func DelAccount(walletID, accountID string) error {
+	defer Invalidate(CacheAccount, accountID)
+	client, err := new(cache.RedisManager).Client()
+	if err != nil {
+		return err
+	}
+	return client.Del(util.AddStr("cache.tx.account.", util.MD5(walletID, accountID)))
+}
+
 func GetAccountByAppID(appID, accountID string) (*model.OwAccount, error) {
 	account := model.OwAccount{}
 	if err := GetObj(util.AddStr("cache.tx.account.", util.MD5(appID, accountID)), &account, 0, func(mongo *sqld.MGOManager) (interface{}, error) {
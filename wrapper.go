@@ -3,8 +3,6 @@ package open_scanner
 import (
 	"errors"
 	"fmt"
-	"github.com/godaddy-x/jorm/sqlc"
-	"github.com/godaddy-x/jorm/sqld"
 	"github.com/godaddy-x/jorm/util"
 	"github.com/nbit99/open_base/model"
 	"github.com/nbit99/openwallet/v2/common"
@@ -26,6 +24,7 @@ type RpcWrapper struct {
 	AccountID string
 	Symbol    string
 	key       *hdkeystore.HDKey
+	repo      Repository
 }
 
 func (w *RpcWrapper) GetWallet() *openwallet.Wallet {
@@ -37,14 +36,8 @@ func (w *RpcWrapper) GetWallet() *openwallet.Wallet {
 		log.Error("Wrapper WalletID is nil")
 		return nil
 	}
-	mongo, err := new(sqld.MGOManager).Get()
-	if err != nil {
-		log.Error("Wrapper Get Mongo faild")
-		return nil
-	}
-	defer mongo.Close()
 	query := model.OwWallet{}
-	if err := mongo.FindOne(sqlc.M(model.OwWallet{}).Eq("appID", w.AppID).Eq("walletID", w.WalletID).Eq("state", 1), &query); err != nil {
+	if err := w.repo.FindWallet(NewFilter().Eq("appID", w.AppID).Eq("walletID", w.WalletID).Eq("state", 1), &query); err != nil {
 		log.Error(util.AddStr("Wrapper Find Wallet [", w.WalletID, "] faild"))
 		return nil
 	}
@@ -75,13 +68,8 @@ func (w *RpcWrapper) GetWalletByID(walletID string) (*openwallet.Wallet, error)
 	if w.WalletID != walletID {
 		return nil, util.Error("Wrapper WalletID Not Match")
 	}
-	mongo, err := new(sqld.MGOManager).Get()
-	if err != nil {
-		return nil, err
-	}
-	defer mongo.Close()
 	query := model.OwWallet{}
-	if err := mongo.FindOne(sqlc.M(model.OwWallet{}).Eq("appID", w.AppID).Eq("walletID", walletID).Eq("state", 1), &query); err != nil {
+	if err := w.repo.FindWallet(NewFilter().Eq("appID", w.AppID).Eq("walletID", walletID).Eq("state", 1), &query); err != nil {
 		return nil, err
 	}
 	if query.Id == 0 {
@@ -113,13 +101,8 @@ func (w *RpcWrapper) GetAssetsAccountInfo(accountID string) (*openwallet.AssetsA
 	//if w.AccountID != accountID {
 	//	return nil, util.Error("Wrapper AccountID Not Match")
 	//}
-	mongo, err := new(sqld.MGOManager).Get()
-	if err != nil {
-		return nil, err
-	}
-	defer mongo.Close()
 	query := model.OwAccount{}
-	if err := mongo.FindOne(sqlc.M(model.OwAccount{}).Eq("appID", w.AppID).Eq("accountID", accountID).Eq("symbol", strings.ToUpper(w.Symbol)).Eq("state", 1), &query); err != nil {
+	if err := w.repo.FindAccount(NewFilter().Eq("appID", w.AppID).Eq("accountID", accountID).Eq("symbol", strings.ToUpper(w.Symbol)).Eq("state", 1), &query); err != nil {
 		return nil, err
 	}
 	if query.Id == 0 {
@@ -135,11 +118,9 @@ func (w *RpcWrapper) GetAssetsAccountList(offset, limit int, cols ...interface{}
 	if len(w.Symbol) == 0 {
 		return nil, util.Error("Wrapper Symbol is nil")
 	}
-	sql := sqlc.M(model.OwAccount{}).Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("state", 1)
-	if limit > 0 {
-		sql.Offset(int64(offset), int64(limit))
-	} else {
-		sql.Offset(int64(offset), int64(50))
+	filter := NewFilter().Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("state", 1)
+	if limit <= 0 {
+		limit = 50
 	}
 	if cols != nil && len(cols) > 0 {
 		for k := 0; k < len(cols); k += 2 {
@@ -147,18 +128,13 @@ func (w *RpcWrapper) GetAssetsAccountList(offset, limit int, cols ...interface{}
 			if !ok || len(key) == 0 {
 				continue
 			}
-			sql.Eq(util.LowerFirst(key), cols[k+1])
+			filter.Eq(util.LowerFirst(key), cols[k+1])
 		}
 	}
-	mongo, err := new(sqld.MGOManager).Get()
+	query, err := w.repo.FindAccountList(filter, offset, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer mongo.Close()
-	query := []*model.OwAccount{}
-	if err := mongo.FindList(sql, &query); err != nil {
-		return nil, err
-	}
 	assetAccounts := make([]*openwallet.AssetsAccount, 0)
 	for e := range query {
 		account := query[e]
@@ -177,20 +153,15 @@ func (w *RpcWrapper) GetAssetsAccountByAddress(address string) (*openwallet.Asse
 	if len(address) == 0 {
 		return nil, util.Error("Wrapper Address is nil")
 	}
-	mongo, err := new(sqld.MGOManager).Get()
-	if err != nil {
-		return nil, err
-	}
-	defer mongo.Close()
 	query := model.OwAddress{}
-	if err := mongo.FindOne(sqlc.M(model.OwAddress{}).Eq("appID", w.AppID).Eq("accountID", w.AppID).Eq("symbol", strings.ToUpper(w.Symbol)).Eq("address", address).Eq("state", 1), &query); err != nil {
+	if err := w.repo.FindAddress(NewFilter().Eq("appID", w.AppID).Eq("accountID", w.AppID).Eq("symbol", strings.ToUpper(w.Symbol)).Eq("address", address).Eq("state", 1), &query); err != nil {
 		return nil, err
 	}
 	if query.Id == 0 {
 		return nil, util.Error("Wrapper Address [", address, "] Not Exist")
 	}
 	account := model.OwAccount{}
-	if err := mongo.FindOne(sqlc.M(model.OwAccount{}).Eq("appID", w.AppID).Eq("accountID", query.AccountID).Eq("symbol", query.Symbol).Eq("state", 1), &account); err != nil {
+	if err := w.repo.FindAccount(NewFilter().Eq("appID", w.AppID).Eq("accountID", query.AccountID).Eq("symbol", query.Symbol).Eq("state", 1), &account); err != nil {
 		return nil, err
 	}
 	if account.Id == 0 {
@@ -209,18 +180,13 @@ func (w *RpcWrapper) GetAddress(address string) (*openwallet.Address, error) {
 	if len(address) == 0 {
 		return nil, util.Error("Wrapper Address is nil")
 	}
-	mongo, err := new(sqld.MGOManager).Get()
-	if err != nil {
-		return nil, err
-	}
-	defer mongo.Close()
 	query := model.OwAddress{}
-	if err := mongo.FindOne(sqlc.M(model.OwAddress{}).Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("address", address).Eq("state", 1), &query); err != nil {
+	if err := w.repo.FindAddress(NewFilter().Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("address", address).Eq("state", 1), &query); err != nil {
 		return nil, err
 	}
 	if query.Id == 0 {
 		if w.Symbol == model.ETH { // 如是ETH链则二次判定是否存在TRUE链公用地址
-			if err := mongo.FindOne(sqlc.M(model.OwAddress{}).Eq("appID", w.AppID).Eq("symbol", model.TRUE).Eq("address", address).Eq("state", 1), &query); err != nil {
+			if err := w.repo.FindAddress(NewFilter().Eq("appID", w.AppID).Eq("symbol", model.TRUE).Eq("address", address).Eq("state", 1), &query); err != nil {
 				return nil, err
 			}
 		}
@@ -232,43 +198,23 @@ func (w *RpcWrapper) GetAddress(address string) (*openwallet.Address, error) {
 }
 
 //获取ow_address_token表中所有该合约的有余额的地址，返回map
-func(w RpcWrapper) getAllTokenAddressMap(query *sqlc.Cnd) (map[string]bool, error) {
-	query.Limit(1, 100)
-	mongo, err := new(sqld.MGOManager).Get()
+func (w *RpcWrapper) getAllTokenAddressMap(filter *Filter) (map[string]bool, error) {
+	addressList, err := w.repo.FindAddressTokenList(filter, 0, 0)
 	if err != nil {
 		return nil, err
 	}
-	defer mongo.Close()
-
-	if _, err := mongo.Count(query); err != nil {
-		return nil, util.Error("获取账号地址列表统计数失败")
-	}
-	pagin := query.Pagination
 	addrMap := make(map[string]bool, 0)
-	for i := int64(1); i <= pagin.PageCount; i++ {
-		addressList := []*model.OwAddressToken{}
-		if err := mongo.FindList(query.Limit(i, pagin.PageSize), &addressList); err != nil {
-			continue
-		}
-
-		for _, addr := range addressList {
-			if _, exist := addrMap[addr.Address]; !exist {
-				addrMap[addr.Address] = true
-			}
+	for _, addr := range addressList {
+		if _, exist := addrMap[addr.Address]; !exist {
+			addrMap[addr.Address] = true
 		}
 	}
 	return addrMap, nil
 }
 
-
-func(w RpcWrapper) getTokenAddressesByQuery(query *sqlc.Cnd) ([]string, error) {
-	mongo, err := new(sqld.MGOManager).Get()
+func (w *RpcWrapper) getTokenAddressesByQuery(filter *Filter, offset, limit int) ([]string, error) {
+	addressList, err := w.repo.FindAddressTokenList(filter, offset, limit)
 	if err != nil {
-		return nil, err
-	}
-	defer mongo.Close()
-	addressList := []*model.OwAddressToken{}
-	if err := mongo.FindList(query, &addressList); err != nil {
 		return nil, util.Error("获取账号地址列表统计数失败")
 	}
 
@@ -279,15 +225,9 @@ func(w RpcWrapper) getTokenAddressesByQuery(query *sqlc.Cnd) ([]string, error) {
 	return addressArray, nil
 }
 
-func(w RpcWrapper) getAddressesByQuery(query *sqlc.Cnd) ([]string, error) {
-	mongo, err := new(sqld.MGOManager).Get()
+func (w *RpcWrapper) getAddressesByQuery(filter *Filter, offset, limit int) ([]string, error) {
+	addressList, err := w.repo.FindAddressList(filter, offset, limit)
 	if err != nil {
-		return nil, err
-	}
-	defer mongo.Close()
-
-	addressList := []*model.OwAddress{}
-	if err := mongo.FindList(query, &addressList); err != nil {
 		return nil, util.Error("获取账号地址列表统计数失败")
 	}
 
@@ -308,16 +248,13 @@ func (w *RpcWrapper) GetAddressListContainsBalance(offset, limit int, cols ...in
 	}
 
 	getTokenAddress := false
-	sql      := sqlc.M(model.OwAddress{}).Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("state", 1).NotEq("balance", "0")
-	sqlToken := sqlc.M(model.OwAddressToken{}).Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("state", 1).NotEq("balance", "0")
+	filter      := NewFilter().Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("state", 1).NotEq("balance", "0")
+	filterToken := NewFilter().Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("state", 1).NotEq("balance", "0")
 
 	if limit <= 0 {
 		limit = 100
 	}
 
-	sql.Offset(int64(offset), int64(limit))
-	sqlToken.Offset(int64(offset), int64(limit))
-
 	if cols != nil && len(cols) > 0 {
 		for k := 0; k < len(cols); k += 2 {
 			key, ok := cols[k].(string)
@@ -326,19 +263,19 @@ func (w *RpcWrapper) GetAddressListContainsBalance(offset, limit int, cols ...in
 			}
 			if key == "ContractID" {
 				getTokenAddress = true
-				sqlToken.Eq(util.LowerFirst(key), cols[k+1])
+				filterToken.Eq(util.LowerFirst(key), cols[k+1])
 				continue
 			}
-			sql.Eq(util.LowerFirst(key), cols[k+1])
-			sqlToken.Eq(util.LowerFirst(key), cols[k+1])
+			filter.Eq(util.LowerFirst(key), cols[k+1])
+			filterToken.Eq(util.LowerFirst(key), cols[k+1])
 
 		}
 	}
 
 	if getTokenAddress {
-		return w.getTokenAddressesByQuery(sqlToken)
+		return w.getTokenAddressesByQuery(filterToken, offset, limit)
 	} else {
-		return w.getAddressesByQuery(sql)
+		return w.getAddressesByQuery(filter, offset, limit)
 	}
 	return nil, fmt.Errorf("something error not match mode")
 }
@@ -353,8 +290,8 @@ func (w *RpcWrapper) GetAddressList(offset, limit int, cols ...interface{}) ([]*
 
 	getTokenAddress := false
 	filterBalance := true// 过滤掉余额为0的地址 默认true
-	sql := sqlc.M(model.OwAddress{}).Eq("appID", w.AppID).Eq("state", 1)
-	sqlToken := sqlc.M(model.OwAddressToken{}).Eq("appID", w.AppID).Eq("symbol", w.Symbol).NotEq("balance", "0").Eq("state", 1)
+	filter := NewFilter().Eq("appID", w.AppID).Eq("state", 1)
+	filterToken := NewFilter().Eq("appID", w.AppID).Eq("symbol", w.Symbol).NotEq("balance", "0").Eq("state", 1)
 
 	if cols != nil && len(cols) > 0 {
 		for k := 0; k < len(cols); k += 2 {
@@ -364,7 +301,7 @@ func (w *RpcWrapper) GetAddressList(offset, limit int, cols ...interface{}) ([]*
 			}
 			if key == "ContractID" {
 				getTokenAddress = true
-				sqlToken.Eq(util.LowerFirst(key), cols[k+1])
+				filterToken.Eq(util.LowerFirst(key), cols[k+1])
 				continue
 			}
 
@@ -373,8 +310,8 @@ func (w *RpcWrapper) GetAddressList(offset, limit int, cols ...interface{}) ([]*
 			}
 
 			if key != FilterBalanceKey {//非sql条件
-				sql.Eq(util.LowerFirst(key), cols[k+1])
-				sqlToken.Eq(util.LowerFirst(key), cols[k+1])
+				filter.Eq(util.LowerFirst(key), cols[k+1])
+				filterToken.Eq(util.LowerFirst(key), cols[k+1])
 			}
 		}
 	}
@@ -382,13 +319,13 @@ func (w *RpcWrapper) GetAddressList(offset, limit int, cols ...interface{}) ([]*
 	if getTokenAddress {
 		//var ret []*openwallet.Address
 		ret := make([]*openwallet.Address, 0)
-		result, err := w.getAddressListBySymbol(w.Symbol, offset, limit, sql)
+		result, err := w.getAddressListBySymbol(w.Symbol, offset, limit, filter)
 		if err != nil {
 			return nil, err
 		}
 
 		//获取ow_address_token表中所有该合约的有余额的地址，返回map
-		addressTokenMap, err := w.getAllTokenAddressMap(sqlToken)
+		addressTokenMap, err := w.getAllTokenAddressMap(filterToken)
 		if err != nil {
 			return nil, err
 		}
@@ -400,12 +337,12 @@ func (w *RpcWrapper) GetAddressList(offset, limit int, cols ...interface{}) ([]*
 		log.Info("token address symbol:" + w.Symbol + "," + ",address token map size:", len(addressTokenMap), ",result size:", len(result), ",ret size:", len(ret))
 		return ret, nil
 	} else {
-		result, err := w.getAddressListBySymbol(w.Symbol, offset, limit, sql)
+		result, err := w.getAddressListBySymbol(w.Symbol, offset, limit, filter)
 		if err != nil {
 			return nil, err
 		}
 		if len(result) == 0 && w.Symbol == model.ETH { // 如是ETH链则二次判定是否存在TRUE链公用地址
-			result, err = w.getAddressListBySymbol(model.TRUE, offset, limit, sql)
+			result, err = w.getAddressListBySymbol(model.TRUE, offset, limit, filter)
 			if err != nil {
 				return nil, err
 			}
@@ -426,22 +363,14 @@ func (w *RpcWrapper) GetAddressList(offset, limit int, cols ...interface{}) ([]*
 	return nil, fmt.Errorf("something error not match mode")
 }
 
-func (w *RpcWrapper) getAddressListBySymbol(symbol string, offset, limit int, sql *sqlc.Cnd) ([]*openwallet.Address, error) {
-	if limit > 0 {
-		sql.Offset(int64(offset), int64(limit))
-	} else {
-		sql.Offset(int64(offset), int64(50))
+func (w *RpcWrapper) getAddressListBySymbol(symbol string, offset, limit int, filter *Filter) ([]*openwallet.Address, error) {
+	if limit <= 0 {
+		limit = 50
 	}
-	sql.Eq("symbol", symbol)
-	mongo, err := new(sqld.MGOManager).Get()
+	query, err := w.repo.FindAddressList(filter.Clone().Eq("symbol", symbol), offset, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer mongo.Close()
-	query := []*model.OwAddress{}
-	if err := mongo.FindList(sql, &query); err != nil {
-		return nil, err
-	}
 	addresses := make([]*openwallet.Address, 0)
 	for _, v := range query {
 		addresses = append(addresses, v.ToAddress())
@@ -472,23 +401,18 @@ func (w *RpcWrapper) HDKey(password ...string) (*hdkeystore.HDKey, error) {
 		return nil, errors.New("Wrapper AppID is nil")
 	}
 
-	mongo, err := new(sqld.MGOManager).Get()
-	if err != nil {
-		return nil, err
-	}
-	defer mongo.Close()
 	query := model.OwWallet{}
-	if err := mongo.FindOne(sqlc.M(model.OwWallet{}).Eq("appID", w.AppID).Eq("walletID", w.WalletID).Eq("state", 1), &query); err != nil {
+	if err := w.repo.FindWallet(NewFilter().Eq("appID", w.AppID).Eq("walletID", w.WalletID).Eq("state", 1), &query); err != nil {
 		log.Error(util.AddStr("Wrapper Find Wallet [", w.WalletID, "] faild"))
 		return nil, err
 	}
 	if query.Id == 0 {
 		log.Error(util.AddStr("Wrapper Wallet [", w.WalletID, "] Not Exist"))
-		return nil, err
+		return nil, nil
 	}
 	if query.IsTrust == 0 {
 		log.Error(util.AddStr("Wrapper Wallet [", w.WalletID, "] Is Not Trust"))
-		return nil, err
+		return nil, nil
 	}
 	key, err := hdkeystore.DecryptHDKey([]byte(query.Keystore), query.Password)
 	if err != nil {
@@ -508,13 +432,8 @@ func (w *RpcWrapper) SetAddressExtParam(address string, key string, val interfac
 	if len(address) == 0 {
 		return util.Error("Wrapper Address is nil")
 	}
-	mongo, err := new(sqld.MGOManager).Get()
-	if err != nil {
-		return err
-	}
-	defer mongo.Close()
 	query := model.OwAddress{}
-	if err := mongo.FindOne(sqlc.M(model.OwAddress{}).Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("address", address).Eq("state", 1), &query); err != nil {
+	if err := w.repo.FindAddress(NewFilter().Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("address", address).Eq("state", 1), &query); err != nil {
 		return err
 	}
 	if query.Id == 0 {
@@ -525,7 +444,7 @@ func (w *RpcWrapper) SetAddressExtParam(address string, key string, val interfac
 		query.ExtParam = make(map[string]interface{}, 0)
 	}
 	query.ExtParam[key] = val
-	if err := mongo.Update(&query); err != nil {
+	if err := w.repo.UpdateAddress(&query); err != nil {
 		return err
 	}
 	return nil
@@ -542,13 +461,8 @@ func (w *RpcWrapper) GetAddressExtParam(address string, key string) (interface{}
 	if len(address) == 0 {
 		return nil, util.Error("Wrapper Address is nil")
 	}
-	mongo, err := new(sqld.MGOManager).Get()
-	if err != nil {
-		return nil, err
-	}
-	defer mongo.Close()
 	query := model.OwAddress{}
-	if err := mongo.FindOne(sqlc.M(model.OwAddress{}).Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("address", address).Eq("state", 1), &query); err != nil {
+	if err := w.repo.FindAddress(NewFilter().Eq("appID", w.AppID).Eq("symbol", w.Symbol).Eq("address", address).Eq("state", 1), &query); err != nil {
 		return nil, err
 	}
 	if query.Id == 0 {
@@ -565,15 +479,10 @@ func (w *RpcWrapper) GetTransactionByTxID(txid, symbol string) ([]*openwallet.Tr
 	if len(w.Symbol) == 0 {
 		return nil, util.Error("Wrapper Symbol is nil")
 	}
-	mongo, err := new(sqld.MGOManager).Get()
+	tradelogTmp, err := w.repo.FindTradeLogTmpList(NewFilter().Eq("txid", txid).Eq("symbol", symbol))
 	if err != nil {
 		return nil, err
 	}
-	defer mongo.Close()
-	tradelogTmp := []*model.OwTradeLogTmp{}
-	if err := mongo.FindList(sqlc.M(model.OwTradeLogTmp{}).Eq("txid", txid).Eq("symbol", symbol), &tradelogTmp); err != nil {
-		return nil, err
-	}
 	if len(tradelogTmp) > 0 {
 		txList := make([]*openwallet.Transaction, 0, len(tradelogTmp))
 		for _, v := range tradelogTmp {
@@ -614,8 +523,8 @@ func (w *RpcWrapper) GetTransactionByTxID(txid, symbol string) ([]*openwallet.Tr
 		}
 		return txList, nil
 	}
-	tradelog := []*model.OwTradeLog{}
-	if err := mongo.FindList(sqlc.M(model.OwTradeLog{}).Eq("txid", txid).Eq("symbol", symbol), &tradelog); err != nil {
+	tradelog, err := w.repo.FindTradeLogList(NewFilter().Eq("txid", txid).Eq("symbol", symbol))
+	if err != nil {
 		return nil, err
 	}
 	if len(tradelog) > 0 {
@@ -661,12 +570,17 @@ func (w *RpcWrapper) GetTransactionByTxID(txid, symbol string) ([]*openwallet.Tr
 	return make([]*openwallet.Transaction, 0), nil
 }
 
-func NewWrapper(appID, walletID string, accountID string, symbol string) *RpcWrapper {
+// NewWrapper 创建钱包数据访问包装器,repo未指定时使用默认实现
+func NewWrapper(appID, walletID string, accountID string, symbol string, repo ...Repository) *RpcWrapper {
 	wrapper := RpcWrapper{
 		AppID:     appID,
 		WalletID:  walletID,
 		AccountID: accountID,
 		Symbol:    symbol,
+		repo:      defaultRepository,
+	}
+	if len(repo) > 0 && repo[0] != nil {
+		wrapper.repo = repo[0]
 	}
 	return &wrapper
 }